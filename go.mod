@@ -3,6 +3,7 @@ module github.com/zintus/flowerss-bot
 go 1.25
 
 require (
+	github.com/PuerkitoBio/goquery v1.8.0
 	github.com/go-sql-driver/mysql v1.6.0
 	github.com/golang/mock v1.6.0
 	github.com/grokify/html-strip-tags-go v0.0.0-20200923094847-079d207a09f1
@@ -12,8 +13,8 @@ require (
 	github.com/spf13/viper v1.13.0
 	github.com/stretchr/testify v1.8.1
 	github.com/yuin/goldmark v1.7.16
-	go.uber.org/atomic v1.9.0
 	go.uber.org/zap v1.23.0
+	golang.org/x/net v0.4.0
 	google.golang.org/protobuf v1.28.1
 	gopkg.in/telebot.v3 v3.3.8
 	gorm.io/driver/mysql v1.3.6
@@ -22,7 +23,6 @@ require (
 )
 
 require (
-	github.com/PuerkitoBio/goquery v1.8.0 // indirect
 	github.com/andybalholm/cascadia v1.3.1 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/fsnotify/fsnotify v1.5.4 // indirect
@@ -43,8 +43,8 @@ require (
 	github.com/spf13/jwalterweatherman v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/subosito/gotenv v1.4.1 // indirect
+	go.uber.org/atomic v1.9.0 // indirect
 	go.uber.org/multierr v1.6.0 // indirect
-	golang.org/x/net v0.4.0 // indirect
 	golang.org/x/sys v0.3.0 // indirect
 	golang.org/x/text v0.5.0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
@@ -2,6 +2,7 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"strings"
 	"time"
 
@@ -20,9 +21,15 @@ import (
 	"github.com/zintus/flowerss-bot/internal/model"
 )
 
+// errFiltered 文章未通过订阅的过滤规则
+var errFiltered = errors.New("filtered by subscription rules")
+
 type Bot struct {
 	core *core.Core
 	tb   *tb.Bot // telebot.Bot instance
+
+	outboxKick chan struct{}
+	outboxDone chan struct{}
 }
 
 // logCommand wraps a command handler and logs each dispatch.
@@ -42,10 +49,15 @@ func logCommand(cmd string, handler func(tb.Context) error) func(tb.Context) err
 
 func NewBot(core *core.Core) *Bot {
 	log.Infof("init telegram bot, token %s, endpoint %s", config.BotToken, config.TelegramEndpoint)
+	poller, err := newPoller()
+	if err != nil {
+		log.Error(err)
+		return nil
+	}
 	settings := tb.Settings{
 		URL:    config.TelegramEndpoint,
 		Token:  config.BotToken,
-		Poller: &tb.LongPoller{Timeout: 10 * time.Second},
+		Poller: poller,
 		Client: core.HttpClient().Client(),
 	}
 
@@ -55,10 +67,10 @@ func NewBot(core *core.Core) *Bot {
 	}
 
 	b := &Bot{
-		core: core,
+		core:       core,
+		outboxKick: make(chan struct{}, 1),
 	}
 
-	var err error
 	b.tb, err = tb.NewBot(settings)
 	if err != nil {
 		log.Error(err)
@@ -76,11 +88,18 @@ func (b *Bot) registerCommands(appCore *core.Core) error {
 		handler.NewAddSubscription(appCore),
 		handler.NewRemoveSubscription(b.tb, appCore),
 		handler.NewListSubscription(appCore),
+		handler.NewCheck(appCore),
 		handler.NewRemoveAllSubscription(),
 		handler.NewOnDocument(b.tb, appCore),
 		handler.NewSet(b.tb, appCore),
 		handler.NewSetFeedTag(appCore),
 		handler.NewSetUpdateInterval(appCore),
+		handler.NewFilter(appCore),
+		handler.NewSetHTTP(appCore),
+		handler.NewDigest(appCore),
+		handler.NewQuiet(appCore),
+		handler.NewLatest(appCore),
+		handler.NewSearch(appCore),
 		handler.NewExport(appCore),
 		handler.NewImport(),
 		handler.NewPauseAll(appCore),
@@ -88,6 +107,7 @@ func (b *Bot) registerCommands(appCore *core.Core) error {
 		handler.NewHelp(),
 		handler.NewVersion(),
 		handler.NewLanguageHandler(appCore), // Added here
+		handler.NewAdmin(b.tb, appCore),
 	}
 
 	for _, h := range commandHandlers {
@@ -101,8 +121,15 @@ func (b *Bot) registerCommands(appCore *core.Core) error {
 		handler.NewRemoveSubscriptionItemButton(appCore),
 		handler.NewNotificationSwitchButton(b.tb, appCore),
 		handler.NewSetSubscriptionTagButton(b.tb),
+		handler.NewSetFilterButton(b.tb),
+		handler.NewSearchPageButton(b.tb, appCore),
 		handler.NewTelegraphSwitchButton(b.tb, appCore),
+		handler.NewMediaSwitchButton(b.tb, appCore),
 		handler.NewSubscriptionSwitchButton(b.tb, appCore),
+		handler.NewCheckRetryButton(appCore),
+		handler.NewCheckUnsubscribeButton(appCore),
+		handler.NewFeedChoiceButton(appCore),
+		handler.NewSetHTTPButton(b.tb, appCore),
 	}
 
 	for _, h := range ButtonHandlers {
@@ -123,7 +150,9 @@ func (b *Bot) registerCommands(appCore *core.Core) error {
 	return nil
 }
 
-func (b *Bot) Run() error {
+// Run 启动 bot 并阻塞到 ctx 结束，返回前停止接收更新，outbox 在发送完进行中的推送后退出，
+// 使用 Wait 等待 outbox 退出
+func (b *Bot) Run(ctx context.Context) error {
 	if config.RunMode == config.TestMode {
 		return nil
 	}
@@ -131,132 +160,358 @@ func (b *Bot) Run() error {
 	if err := b.registerCommands(b.core); err != nil {
 		return err
 	}
+	if p, ok := b.tb.Poller.(*webhookPoller); ok {
+		if err := p.register(b.tb); err != nil {
+			return err
+		}
+	} else if err := b.tb.RemoveWebhook(); err != nil {
+		// 之前使用过 webhook 时，需要删除后才能长轮询
+		return err
+	}
+
 	log.Infof("bot start %s", config.AppVersionInfo("en"))
+	b.outboxDone = make(chan struct{})
+	go b.runOutbox(ctx)
+	go func() {
+		<-ctx.Done()
+		log.Info("stopping bot poller")
+		b.tb.Stop()
+	}()
 	b.tb.Start()
 	return nil
 }
 
+// Wait 等待 outbox 退出，ctx 结束时不再等待并返回 ctx 的错误
+func (b *Bot) Wait(ctx context.Context) error {
+	if b.outboxDone == nil {
+		return nil
+	}
+	select {
+	case <-b.outboxDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SourceUpdate 新内容已写入 outbox，唤醒发送
 func (b *Bot) SourceUpdate(
-	source *model.Source, newContents []*model.Content, subscribes []*model.Subscribe,
+	ctx context.Context, source *model.Source, newContents []*model.Content, subscribes []*model.Subscribe,
 ) {
-	b.BroadcastNews(source, subscribes, newContents)
+	b.kickOutbox()
 }
 
-func (b *Bot) SourceUpdateError(source *model.Source) {
-	b.BroadcastSourceError(source)
+func (b *Bot) SourceUpdateError(ctx context.Context, source *model.Source) {
+	b.BroadcastSourceError(ctx, source)
 }
 
-// BroadcastNews send new contents message to subscriber
-func (b *Bot) BroadcastNews(source *model.Source, subs []*model.Subscribe, contents []*model.Content) {
-	zap.S().Infow(
-		"broadcast news",
-		"fetcher id", source.ID,
-		"fetcher title", source.Title,
-		"subscriber count", len(subs),
-		"new contents", len(contents),
-	)
+// SourceMoved 通知原订阅者订阅源已迁移到新链接
+func (b *Bot) SourceMoved(ctx context.Context, move *core.SourceMove) {
+	var u tb.User
+	for _, sub := range move.Subscriptions {
+		user, errUser := b.core.GetUser(ctx, sub.UserID)
+		langCode := "en" // Default
+		if errUser == nil && user != nil && user.LanguageCode != "" {
+			langCode = user.LanguageCode
+		}
 
-	for _, content := range contents {
-		previewText := preview.TrimDescription(content.Description, config.PreviewText)
+		localizedMessage := i18n.Localize(
+			langCode, "bot_broadcast_source_moved_format", move.Source.Title, move.OldLink, move.Source.DisplayLink(),
+		)
+		u.ID = sub.UserID
+		_ = util.BotSendWithRetry(
+			b.tb, &u, localizedMessage, &tb.SendOptions{DisableWebPagePreview: true},
+		)
+	}
+}
 
-		for _, sub := range subs {
-			user, errUser := b.core.GetUser(context.Background(), sub.UserID)
-			langCode := "en" // Default
-			if errUser == nil && user != nil && user.LanguageCode != "" {
-				langCode = user.LanguageCode
-			}
+// BroadcastNews send due deliveries in the outbox to subscribers, and record the result of each delivery,
+// stops taking new deliveries once ctx is done
+func (b *Bot) BroadcastNews(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := b.core.GetDueDeliveries(ctx, time.Now(), outboxBatchSize)
+		if err != nil {
+			log.Errorf("get due deliveries failed, %v", err)
+			return
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+		zap.S().Infow("broadcast news", "deliveries", len(deliveries))
 
-			tpldata := &config.TplData{
-				SourceTitle:     source.Title,
-				ContentTitle:    content.Title,
-				RawLink:         content.RawLink,
-				PreviewText:     previewText,
-				TelegraphURL:    content.TelegraphURL,
-				Tags:            sub.Tag,
-				EnableTelegraph: sub.EnableTelegraph == 1 && content.TelegraphURL != "",
-				LangCode:        langCode, // Added
-			}
+		// 记录状态失败时退出，避免同一批记录被反复发送
+		if !b.sendDeliveries(ctx, deliveries) || len(deliveries) < outboxBatchSize {
+			return
+		}
+	}
+}
 
-			u := &tb.User{
-				ID: sub.UserID,
-			}
-			o := &tb.SendOptions{
-				DisableWebPagePreview: config.DisableWebPagePreview,
-				ParseMode:             config.MessageMode,
-				DisableNotification:   sub.EnableNotification != 1,
-			}
-			msg, err := tpldata.Render(config.MessageMode)
+// sendDeliveries 发送推送并记录结果，摘要推送按用户合并发送，有记录保存失败时返回 false，
+// ctx 结束后不再开始新的发送，未发送的推送留在 outbox 中，已开始的发送及其记录保存不会被中断
+func (b *Bot) sendDeliveries(ctx context.Context, deliveries []*model.Delivery) bool {
+	stop := ctx.Done()
+	ctx = context.WithoutCancel(ctx)
+	hashIDs := make([]string, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		hashIDs = append(hashIDs, delivery.ContentHashID)
+	}
+	contentList, err := b.core.GetContentsByHashIDs(ctx, hashIDs)
+	if err != nil {
+		log.Errorf("get delivery contents failed, %v", err)
+		return false
+	}
+	contents := make(map[string]*model.Content, len(contentList))
+	for _, content := range contentList {
+		contents[content.HashID] = content
+	}
+
+	now := time.Now()
+	sources := map[uint]*model.Source{}
+	quietHours := map[int64]*core.QuietHours{}
+	digests := map[int64][]*deliveryItem{}
+	var digestUsers []int64
+	ok := true
+	for _, delivery := range deliveries {
+		if isDone(stop) {
+			return ok
+		}
+		quiet, cached := quietHours[delivery.UserID]
+		if !cached {
+			quiet, err = b.core.GetUserQuietHours(ctx, delivery.UserID)
 			if err != nil {
-				zap.S().Errorw(
-					"broadcast news error, tpldata.Render err",
-					"error", err.Error(),
-				)
-				return
+				log.Errorf("get quiet hours of user %d failed, %v", delivery.UserID, err)
 			}
-			attachData := &session.Attachment{
-				UserId:   sub.UserID,
-				SourceId: uint32(sub.SourceID),
-			}
-			data := session.Marshal(attachData)
-			unsubBtn := tb.InlineButton{
-				Unique: handler.RemoveSubscriptionItemButtonUnique,
-				Text:   i18n.Localize(langCode, "btn_unsubscribe"),
-				Data:   data,
+			quietHours[delivery.UserID] = quiet
+		}
+		until, inQuiet := quiet.Until(now)
+		if inQuiet && quiet.Mode == core.QuietHold {
+			if err := b.core.HoldDelivery(ctx, delivery, until); err != nil {
+				log.Errorf("hold delivery %d failed, %v", delivery.ID, err)
+				ok = false
 			}
-			markup := &tb.ReplyMarkup{InlineKeyboard: [][]tb.InlineButton{{unsubBtn}}}
-			if err := util.BotSendWithRetry(b.tb, u, msg, o, markup); err != nil {
-
-				if strings.Contains(err.Error(), "Forbidden") {
-					zap.S().Errorw(
-						"broadcast news error, bot stopped by user",
-						"error", err.Error(),
-						"user id", sub.UserID,
-						"source id", sub.SourceID,
-						"title", source.Title,
-						"link", source.Link,
-					)
-					if unsubErr := b.core.Unsubscribe(context.Background(), sub.UserID, sub.SourceID); unsubErr != nil {
-						zap.S().Errorw(
-							"failed to unsubscribe user",
-							"error", unsubErr.Error(),
-							"user id", sub.UserID,
-							"source id", sub.SourceID,
-						)
-					}
-				}
-
-				/*
-					Telegram return error if markdown message has incomplete format.
-					Print the msg to warn the user
-					api error: Bad Request: can't parse entities: Can't find end of the entity starting at byte offset 894
-				*/
-				if strings.Contains(err.Error(), "parse entities") {
-					zap.S().Errorw(
-						"broadcast news error, markdown error",
-						"markdown msg", msg,
-						"error", err.Error(),
-					)
-				}
+			continue
+		}
+
+		item, permanent, err := b.resolveDelivery(ctx, delivery, sources, contents)
+		if err == nil {
+			item.silent = inQuiet
+		}
+		if err == nil && delivery.Digest {
+			if _, exist := digests[delivery.UserID]; !exist {
+				digestUsers = append(digestUsers, delivery.UserID)
 			}
+			digests[delivery.UserID] = append(digests[delivery.UserID], item)
+			continue
+		}
+		if err == nil {
+			permanent, err = b.sendContent(ctx, item.source, item.sub, item.content, item.silent)
 		}
+		ok = b.markDelivery(ctx, delivery, permanent, err) && ok
+	}
+
+	for _, userID := range digestUsers {
+		if isDone(stop) {
+			return ok
+		}
+		ok = b.sendDigest(ctx, userID, digests[userID]) && ok
+	}
+	return ok
+}
+
+// isDone done 是否已关闭，不阻塞
+func isDone(done <-chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	default:
+		return false
+	}
+}
+
+// markDelivery 按发送结果更新推送记录，保存失败时返回 false
+func (b *Bot) markDelivery(ctx context.Context, delivery *model.Delivery, permanent bool, sendErr error) bool {
+	var err error
+	switch {
+	case sendErr == nil:
+		err = b.core.MarkDeliverySent(ctx, delivery, time.Now())
+	case errors.Is(sendErr, errFiltered):
+		err = b.core.MarkDeliveryFiltered(ctx, delivery)
+	case permanent:
+		err = b.core.FailDelivery(ctx, delivery, sendErr)
+	default:
+		err = b.core.RetryDelivery(ctx, delivery, sendErr, time.Now())
+	}
+	if err != nil {
+		log.Errorf("update delivery %d failed, %v", delivery.ID, err)
+		return false
+	}
+	return true
+}
+
+// deliveryItem 一条推送及其发送所需的数据
+type deliveryItem struct {
+	delivery *model.Delivery
+	source   *model.Source
+	sub      *model.Subscribe
+	content  *model.Content
+	silent   bool // 处于免打扰时间，不发出通知
+}
+
+// resolveDelivery 获取推送对应的订阅源、订阅和文章，并检查过滤规则，permanent 表示错误无法通过重试恢复
+func (b *Bot) resolveDelivery(
+	ctx context.Context, delivery *model.Delivery, sources map[uint]*model.Source, contents map[string]*model.Content,
+) (item *deliveryItem, permanent bool, err error) {
+	content, ok := contents[delivery.ContentHashID]
+	if !ok {
+		return nil, true, core.ErrContentNotExist
+	}
+
+	source, ok := sources[delivery.SourceID]
+	if !ok {
+		source, err = b.core.GetSource(ctx, delivery.SourceID)
+		if err != nil {
+			return nil, errors.Is(err, core.ErrSourceNotExist), err
+		}
+		sources[delivery.SourceID] = source
+	}
+
+	sub, err := b.core.GetSubscription(ctx, delivery.UserID, delivery.SourceID)
+	if err != nil {
+		return nil, errors.Is(err, core.ErrSubscriptionNotExist), err
+	}
+	if !core.ContentMatchesFilter(sub, content) {
+		return nil, true, errFiltered
+	}
+	return &deliveryItem{delivery: delivery, source: source, sub: sub, content: content}, false, nil
+}
+
+// sendContent send a content message to the subscriber, silent sends it without notification
+func (b *Bot) sendContent(
+	ctx context.Context, source *model.Source, sub *model.Subscribe, content *model.Content, silent bool,
+) (permanent bool, err error) {
+	previewText := preview.TrimDescription(content.Description, config.PreviewText)
+
+	user, errUser := b.core.GetUser(ctx, sub.UserID)
+	langCode := "en" // Default
+	if errUser == nil && user != nil && user.LanguageCode != "" {
+		langCode = user.LanguageCode
+	}
+
+	tpldata := &config.TplData{
+		SourceTitle:     source.Title,
+		ContentTitle:    content.Title,
+		RawLink:         content.RawLink,
+		PreviewText:     previewText,
+		TelegraphURL:    content.TelegraphURL,
+		Tags:            sub.Tag,
+		EnableTelegraph: sub.EnableTelegraph == 1 && content.TelegraphURL != "",
+		LangCode:        langCode, // Added
+	}
+
+	u := &tb.User{
+		ID: sub.UserID,
+	}
+	o := &tb.SendOptions{
+		DisableWebPagePreview: config.DisableWebPagePreview,
+		ParseMode:             config.MessageMode,
+		DisableNotification:   silent || sub.EnableNotification != 1,
+	}
+	msg, err := tpldata.Render(config.MessageMode)
+	if err != nil {
+		zap.S().Errorw(
+			"broadcast news error, tpldata.Render err",
+			"error", err.Error(),
+		)
+		return true, err
+	}
+	attachData := &session.Attachment{
+		UserId:   sub.UserID,
+		SourceId: uint32(sub.SourceID),
+	}
+	data := session.Marshal(attachData)
+	unsubBtn := tb.InlineButton{
+		Unique: handler.RemoveSubscriptionItemButtonUnique,
+		Text:   i18n.Localize(langCode, "btn_unsubscribe"),
+		Data:   data,
+	}
+	markup := &tb.ReplyMarkup{InlineKeyboard: [][]tb.InlineButton{{unsubBtn}}}
+
+	err = errNoMedia
+	if sub.EnableMedia == 1 {
+		err = b.sendMedia(u, content, msg, o, markup)
+	}
+	if err != nil && !strings.Contains(err.Error(), "Forbidden") {
+		if !errors.Is(err, errNoMedia) {
+			// 附件无法被 telegram 获取或格式不支持时只发送链接
+			zap.S().Warnw(
+				"send media failed, falling back to text message",
+				"error", err.Error(),
+				"user id", sub.UserID,
+				"link", content.RawLink,
+			)
+		}
+		err = util.BotSendWithRetry(b.tb, u, msg, o, markup)
+	}
+	if err != nil {
+
+		if strings.Contains(err.Error(), "Forbidden") {
+			b.unsubscribeBlocked(ctx, source, sub, err)
+			return true, err
+		}
+
+		/*
+			Telegram return error if markdown message has incomplete format.
+			Print the msg to warn the user
+			api error: Bad Request: can't parse entities: Can't find end of the entity starting at byte offset 894
+		*/
+		if strings.Contains(err.Error(), "parse entities") {
+			zap.S().Errorw(
+				"broadcast news error, markdown error",
+				"markdown msg", msg,
+				"error", err.Error(),
+			)
+			return true, err
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// unsubscribeBlocked 用户停用了 bot，取消其订阅
+func (b *Bot) unsubscribeBlocked(ctx context.Context, source *model.Source, sub *model.Subscribe, sendErr error) {
+	zap.S().Errorw(
+		"broadcast news error, bot stopped by user",
+		"error", sendErr.Error(),
+		"user id", sub.UserID,
+		"source id", sub.SourceID,
+		"title", source.Title,
+		"link", source.DisplayLink(),
+	)
+	if unsubErr := b.core.Unsubscribe(ctx, sub.UserID, sub.SourceID); unsubErr != nil {
+		zap.S().Errorw(
+			"failed to unsubscribe user",
+			"error", unsubErr.Error(),
+			"user id", sub.UserID,
+			"source id", sub.SourceID,
+		)
 	}
 }
 
 // BroadcastSourceError send fetcher update error message to subscribers
-func (b *Bot) BroadcastSourceError(source *model.Source) {
-	subs, err := b.core.GetSourceAllSubscriptions(context.Background(), source.ID)
+func (b *Bot) BroadcastSourceError(ctx context.Context, source *model.Source) {
+	subs, err := b.core.GetSourceAllSubscriptions(ctx, source.ID)
 	if err != nil {
 		log.Errorf("get subscriptions failed, %v", err)
 	}
 	var u tb.User
 	for _, sub := range subs {
-		user, errUser := b.core.GetUser(context.Background(), sub.UserID)
+		user, errUser := b.core.GetUser(ctx, sub.UserID)
 		langCode := "en" // Default
 		if errUser == nil && user != nil && user.LanguageCode != "" {
 			langCode = user.LanguageCode
 		}
 
-		localizedMessage := i18n.Localize(langCode, "bot_broadcast_source_error_format", source.Title, source.Link, config.ErrorThreshold)
+		localizedMessage := i18n.Localize(langCode, "bot_broadcast_source_error_format", source.Title, source.DisplayLink(), config.ErrorThreshold)
 		u.ID = sub.UserID
 		_ = util.BotSendWithRetry(
 			b.tb, &u, localizedMessage, &tb.SendOptions{
@@ -0,0 +1,128 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+// digestMessageLimit telegram 单条消息的最大长度
+const digestMessageLimit = 4096
+
+// digestPage 一条摘要消息及其包含的推送
+type digestPage struct {
+	text  string
+	items []*deliveryItem
+}
+
+// sendDigest 将用户的摘要内容合并成消息发送，有记录保存失败时返回 false
+func (b *Bot) sendDigest(ctx context.Context, userID int64, items []*deliveryItem) bool {
+	langCode := "en"
+	if user, err := b.core.GetUser(ctx, userID); err == nil && user != nil && user.LanguageCode != "" {
+		langCode = user.LanguageCode
+	}
+
+	// 所有订阅都关闭通知或处于免打扰时间时静默发送
+	silent := true
+	for _, item := range items {
+		if item.sub.EnableNotification == 1 && !item.silent {
+			silent = false
+			break
+		}
+	}
+
+	ok := true
+	for _, page := range renderDigestPages(langCode, items, digestMessageLimit) {
+		err := util.BotSendWithRetry(
+			b.tb, &tb.User{ID: userID}, page.text, &tb.SendOptions{
+				ParseMode:             tb.ModeHTML,
+				DisableWebPagePreview: true,
+				DisableNotification:   silent,
+			},
+		)
+		permanent := false
+		if err != nil && strings.Contains(err.Error(), "Forbidden") {
+			permanent = true
+			unsubscribed := map[uint]bool{}
+			for _, item := range page.items {
+				if !unsubscribed[item.sub.SourceID] {
+					unsubscribed[item.sub.SourceID] = true
+					b.unsubscribeBlocked(ctx, item.source, item.sub, err)
+				}
+			}
+		}
+		for _, item := range page.items {
+			ok = b.markDelivery(ctx, item.delivery, permanent, err) && ok
+		}
+	}
+	return ok
+}
+
+// renderDigestPages 按订阅源分组列出文章标题和链接，超过 limit 时分成多条消息
+func renderDigestPages(langCode string, items []*deliveryItem, limit int) []*digestPage {
+	var sourceOrder []uint
+	groups := map[uint][]*deliveryItem{}
+	for _, item := range items {
+		if _, ok := groups[item.source.ID]; !ok {
+			sourceOrder = append(sourceOrder, item.source.ID)
+		}
+		groups[item.source.ID] = append(groups[item.source.ID], item)
+	}
+
+	var pages []*digestPage
+	page := &digestPage{text: i18n.Localize(langCode, "digest_header_format", len(items)) + "\n"}
+	for _, sourceID := range sourceOrder {
+		group := groups[sourceID]
+		header := fmt.Sprintf("\n<b>%s</b>\n", html.EscapeString(group[0].source.Title))
+		page.text += header
+		for _, item := range group {
+			line := digestLine(item.content, limit-utf8.RuneCountInString(header))
+			if utf8.RuneCountInString(page.text)+utf8.RuneCountInString(line) > limit && len(page.items) > 0 {
+				pages = append(pages, page)
+				page = &digestPage{text: header}
+			}
+			if room := limit - utf8.RuneCountInString(page.text); utf8.RuneCountInString(line) > room {
+				// 首页还包含摘要标题，需要进一步截断
+				line = digestLine(item.content, room)
+			}
+			page.text += line
+			page.items = append(page.items, item)
+		}
+	}
+	if len(page.items) > 0 {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+// digestLine 摘要中的一行，标题过长时截断使其不超过 limit
+func digestLine(content *model.Content, limit int) string {
+	title := content.Title
+	if title == "" {
+		title = content.RawLink
+	}
+	link := html.EscapeString(content.RawLink)
+	format := "• <a href=\"%s\">%s</a>\n"
+	room := limit - utf8.RuneCountInString(fmt.Sprintf(format, link, ""))
+
+	escaped := html.EscapeString(title)
+	if utf8.RuneCountInString(escaped) > room {
+		runes := []rune(title)
+		if room > 0 && len(runes) > room {
+			runes = runes[:room]
+		}
+		for len(runes) > 0 && utf8.RuneCountInString(html.EscapeString(string(runes)))+1 > room {
+			runes = runes[:len(runes)-1]
+		}
+		escaped = html.EscapeString(string(runes)) + "…"
+	}
+	return fmt.Sprintf(format, link, escaped)
+}
@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+func TestRenderDigestPages(t *testing.T) {
+	if err := i18n.LoadTranslations("../../locales"); err != nil {
+		t.Fatalf("load translations failed: %v", err)
+	}
+
+	sources := []*model.Source{{ID: 1, Title: "A & B"}, {ID: 2, Title: "C"}}
+	var items []*deliveryItem
+	for i := 0; i < 40; i++ {
+		source := sources[i%2]
+		items = append(
+			items, &deliveryItem{
+				delivery: &model.Delivery{ID: uint(i)},
+				source:   source,
+				sub:      &model.Subscribe{SourceID: source.ID},
+				content: &model.Content{
+					Title:   fmt.Sprintf("title %d <%s>", i, strings.Repeat("x", 20)),
+					RawLink: fmt.Sprintf("https://example.com/%d", i),
+				},
+			},
+		)
+	}
+
+	t.Run(
+		"single page", func(t *testing.T) {
+			pages := renderDigestPages("en", items, digestMessageLimit)
+			assert.Equal(t, 1, len(pages))
+			assert.Equal(t, 40, len(pages[0].items))
+			assert.Contains(t, pages[0].text, "<b>A &amp; B</b>")
+			assert.Contains(t, pages[0].text, "&lt;xxxxxxxxxxxxxxxxxxxx&gt;")
+			// 按订阅源分组
+			assert.Less(t, strings.Index(pages[0].text, "/38\""), strings.Index(pages[0].text, "<b>C</b>"))
+		},
+	)
+
+	t.Run(
+		"paginated", func(t *testing.T) {
+			limit := 500
+			pages := renderDigestPages("en", items, limit)
+			assert.Greater(t, len(pages), 1)
+			count := 0
+			for i, page := range pages {
+				assert.LessOrEqual(t, utf8.RuneCountInString(page.text), limit)
+				if i > 0 {
+					// 新的一页重复订阅源标题
+					assert.True(t, strings.HasPrefix(page.text, "\n<b>"))
+				}
+				count += len(page.items)
+			}
+			assert.Equal(t, 40, count)
+		},
+	)
+
+	t.Run(
+		"long title truncated", func(t *testing.T) {
+			long := []*deliveryItem{
+				{
+					delivery: &model.Delivery{ID: 100},
+					source:   sources[0],
+					sub:      &model.Subscribe{SourceID: 1},
+					content:  &model.Content{Title: strings.Repeat("长", 500), RawLink: "https://example.com/long"},
+				},
+			}
+			pages := renderDigestPages("en", long, 200)
+			assert.Equal(t, 1, len(pages))
+			assert.LessOrEqual(t, utf8.RuneCountInString(pages[0].text), 200)
+			assert.Contains(t, pages[0].text, "…</a>")
+		},
+	)
+}
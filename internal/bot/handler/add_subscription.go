@@ -10,12 +10,18 @@ import (
 	"github.com/zintus/flowerss-bot/internal/bot/message"
 	"github.com/zintus/flowerss-bot/internal/bot/util"
 	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/feed"
 	"github.com/zintus/flowerss-bot/internal/i18n"
 	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/model"
+	"github.com/zintus/flowerss-bot/pkg/client"
 )
 
 var (
 	ErrGetChannelInfoFailedForPerms = errors.New("failed to get channel info for permissions")
+
+	// errSubscribeFailed 订阅源已创建，但添加订阅失败
+	errSubscribeFailed = errors.New("add subscription failed")
 )
 
 type AddSubscription struct {
@@ -44,27 +50,7 @@ func (a *AddSubscription) addSubscriptionForChat(ctx tb.Context) error {
 		return ctx.Send(hint, &tb.SendOptions{ReplyTo: ctx.Message()})
 	}
 
-	source, err := a.core.CreateSource(context.Background(), sourceURL)
-	if err != nil {
-		return ctx.Reply(i18n.Localize(langCode, "addsub_err_create_source_failed_format", err.Error()))
-	}
-
-	log.Infof("%d subscribe [%d]%s %s", ctx.Chat().ID, source.ID, source.Title, source.Link)
-	if err := a.core.AddSubscription(context.Background(), ctx.Chat().ID, source.ID); err != nil {
-		if errors.Is(err, core.ErrSubscriptionExist) {
-			return ctx.Reply(i18n.Localize(langCode, "addsub_err_already_subscribed"))
-		}
-		log.Errorf("add subscription user %d source %d failed %v", ctx.Chat().ID, source.ID, err)
-		return ctx.Reply(i18n.Localize(langCode, "addsub_err_generic_subscribe_failed"))
-	}
-
-	return ctx.Reply(
-		i18n.Localize(langCode, "addsub_success_subscribed_format", source.ID, source.Title, source.Link),
-		&tb.SendOptions{
-			DisableWebPagePreview: true,
-			ParseMode:             tb.ModeMarkdown,
-		},
-	)
+	return a.subscribe(ctx, ctx.Chat().ID, sourceURL)
 }
 
 func (a *AddSubscription) hasChannelPrivilege(bot *tb.Bot, channelChat *tb.Chat, opUserID int64, botID int64) (
@@ -117,22 +103,22 @@ func (a *AddSubscription) addSubscriptionForChannel(ctx tb.Context, channelName
 		return ctx.Reply(i18n.Localize(langCode, "addsub_err_not_channel_admin"))
 	}
 
-	source, err := a.core.CreateSource(context.Background(), sourceURL)
-	if err != nil {
-		return ctx.Reply(i18n.Localize(langCode, "addsub_err_create_source_failed_format", err.Error()))
-	}
+	return a.subscribe(ctx, channelChat.ID, sourceURL)
+}
 
-	log.Infof("%d subscribe [%d]%s %s", channelChat.ID, source.ID, source.Title, source.Link)
-	if err := a.core.AddSubscription(context.Background(), channelChat.ID, source.ID); err != nil {
-		if errors.Is(err, core.ErrSubscriptionExist) {
-			return ctx.Reply(i18n.Localize(langCode, "addsub_err_already_subscribed"))
-		}
-		log.Errorf("add subscription user %d source %d failed %v", channelChat.ID, source.ID, err)
-		return ctx.Reply(i18n.Localize(langCode, "addsub_err_generic_subscribe_failed"))
+// subscribe 为 ownerID 订阅 sourceURL，网页中有多个订阅源时让用户选择
+func (a *AddSubscription) subscribe(ctx tb.Context, ownerID int64, sourceURL string) error {
+	langCode := util.GetLangCode(ctx)
+	source, err := subscribeSource(a.core, ownerID, sourceURL)
+	var multiErr *core.MultipleFeedsError
+	if errors.As(err, &multiErr) {
+		return replyFeedChoices(ctx, ownerID, multiErr.Feeds, langCode)
+	}
+	if err != nil {
+		return ctx.Reply(subscribeErrorText(err, langCode))
 	}
-
 	return ctx.Reply(
-		i18n.Localize(langCode, "addsub_success_subscribed_format", source.ID, source.Title, source.Link),
+		i18n.Localize(langCode, "addsub_success_subscribed_format", source.ID, source.Title, source.DisplayLink()),
 		&tb.SendOptions{
 			DisableWebPagePreview: true,
 			ParseMode:             tb.ModeMarkdown,
@@ -152,3 +138,39 @@ func (a *AddSubscription) Handle(ctx tb.Context) error {
 func (a *AddSubscription) Middlewares() []tb.MiddlewareFunc {
 	return nil
 }
+
+// subscribeSource 创建订阅源并为 ownerID 添加订阅
+func subscribeSource(appCore *core.Core, ownerID int64, sourceURL string) (*model.Source, error) {
+	source, err := appCore.CreateSource(context.Background(), sourceURL)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("%d subscribe [%d]%s %s", ownerID, source.ID, source.Title, source.DisplayLink())
+	if err := appCore.AddSubscription(context.Background(), ownerID, source.ID); err != nil {
+		if errors.Is(err, core.ErrSubscriptionExist) {
+			return nil, err
+		}
+		log.Errorf("add subscription user %d source %d failed %v", ownerID, source.ID, err)
+		return nil, errSubscribeFailed
+	}
+	return source, nil
+}
+
+// subscribeErrorText subscribeSource 失败时回复给用户的文本
+func subscribeErrorText(err error, langCode string) string {
+	switch {
+	case errors.Is(err, core.ErrSubscriptionExist):
+		return i18n.Localize(langCode, "addsub_err_already_subscribed")
+	case errors.Is(err, core.ErrNoFeedFound):
+		return i18n.Localize(langCode, "addsub_err_no_feed_found")
+	case errors.Is(err, feed.ErrFeedTooLarge):
+		return i18n.Localize(langCode, "addsub_err_feed_too_large")
+	case errors.Is(err, client.ErrBlockedAddress):
+		return i18n.Localize(langCode, "addsub_err_blocked_address")
+	case errors.Is(err, errSubscribeFailed):
+		return i18n.Localize(langCode, "addsub_err_generic_subscribe_failed")
+	default:
+		return i18n.Localize(langCode, "addsub_err_create_source_failed_format", err.Error())
+	}
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"html"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/middleware"
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/log"
+)
+
+const (
+	// adminSourcesLimit /admin sources 列出的订阅源数量
+	adminSourcesLimit = 20
+	// adminErrorLimit /admin sources 中错误信息的最大长度
+	adminErrorLimit = 100
+)
+
+// Admin 管理员命令：stats、sources、refresh、broadcast、pause、resume
+type Admin struct {
+	bot  *tb.Bot
+	core *core.Core
+}
+
+func NewAdmin(bot *tb.Bot, core *core.Core) *Admin {
+	return &Admin{bot: bot, core: core}
+}
+
+func (a *Admin) Command() string {
+	return "/admin"
+}
+
+// Description 为空，不在命令菜单中展示
+func (a *Admin) Description() string {
+	return ""
+}
+
+func (a *Admin) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	payload := strings.TrimSpace(ctx.Message().Payload)
+	args := strings.Fields(payload)
+	if len(args) == 0 {
+		return ctx.Reply(i18n.Localize(langCode, "admin_usage_hint"))
+	}
+
+	switch strings.ToLower(args[0]) {
+	case "stats":
+		return a.stats(ctx, langCode)
+	case "sources":
+		return a.sources(ctx, langCode)
+	case "refresh", "pause", "resume":
+		if len(args) != 2 {
+			return ctx.Reply(i18n.Localize(langCode, "admin_usage_hint"))
+		}
+		sourceID, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return ctx.Reply(i18n.Localize(langCode, "admin_err_invalid_source_id", args[1]))
+		}
+		return a.updateSource(ctx, langCode, strings.ToLower(args[0]), uint(sourceID))
+	case "broadcast":
+		text := strings.TrimSpace(payload[len(args[0]):])
+		if text == "" {
+			return ctx.Reply(i18n.Localize(langCode, "admin_usage_hint"))
+		}
+		return a.broadcast(ctx, langCode, text)
+	default:
+		return ctx.Reply(i18n.Localize(langCode, "admin_usage_hint"))
+	}
+}
+
+func (a *Admin) stats(ctx tb.Context, langCode string) error {
+	stats, err := a.core.GetStats(context.Background())
+	if err != nil {
+		log.Errorf("get stats failed, %v", err)
+		return ctx.Reply(i18n.Localize(langCode, "err_internal_error"))
+	}
+	return ctx.Reply(
+		i18n.Localize(
+			langCode, "admin_stats_format",
+			stats.Users, stats.Sources, stats.PausedSources, stats.FailingSources, stats.Subscriptions,
+		),
+	)
+}
+
+func (a *Admin) sources(ctx tb.Context, langCode string) error {
+	sources, err := a.core.GetSourcesByErrorCount(context.Background())
+	if err != nil {
+		log.Errorf("get sources failed, %v", err)
+		return ctx.Reply(i18n.Localize(langCode, "err_internal_error"))
+	}
+	if len(sources) == 0 {
+		return ctx.Reply(i18n.Localize(langCode, "admin_sources_empty"))
+	}
+
+	var b strings.Builder
+	b.WriteString(i18n.Localize(langCode, "admin_sources_header", len(sources)))
+	for i, source := range sources {
+		if i == adminSourcesLimit {
+			b.WriteString("\n" + i18n.Localize(langCode, "admin_sources_more", len(sources)-adminSourcesLimit))
+			break
+		}
+		status := ""
+		if source.Paused {
+			status = " " + i18n.Localize(langCode, "set_tmpl_status_paused")
+		}
+		b.WriteString(
+			"\n" + i18n.Localize(
+				langCode, "admin_sources_item_format",
+				source.ID, html.EscapeString(source.Title), source.ErrorCount, status,
+			),
+		)
+		if source.ErrorCount > 0 && source.LastError != "" {
+			b.WriteString("\n  <i>" + html.EscapeString(truncateRunes(source.LastError, adminErrorLimit)) + "</i>")
+		}
+	}
+	return ctx.Reply(b.String(), &tb.SendOptions{ParseMode: tb.ModeHTML, DisableWebPagePreview: true})
+}
+
+// updateSource 处理 refresh、pause 和 resume
+func (a *Admin) updateSource(ctx tb.Context, langCode string, action string, sourceID uint) error {
+	source, err := a.core.GetSource(context.Background(), sourceID)
+	if err != nil {
+		if errors.Is(err, core.ErrSourceNotExist) {
+			return ctx.Reply(i18n.Localize(langCode, "admin_err_source_not_found", sourceID))
+		}
+		log.Errorf("get source %d failed, %v", sourceID, err)
+		return ctx.Reply(i18n.Localize(langCode, "err_internal_error"))
+	}
+
+	var successKey string
+	switch action {
+	case "refresh":
+		if source.Paused {
+			return ctx.Reply(i18n.Localize(langCode, "admin_err_source_paused", source.Title))
+		}
+		err = a.core.RequestFetch(context.Background(), sourceID)
+		successKey = "admin_refresh_success"
+	case "pause":
+		err = a.core.DisableSourceUpdate(context.Background(), sourceID)
+		successKey = "admin_pause_success"
+	default:
+		err = a.core.EnableSourceUpdate(context.Background(), sourceID)
+		successKey = "admin_resume_success"
+	}
+	if err != nil {
+		log.Errorf("admin %s source %d failed, %v", action, sourceID, err)
+		return ctx.Reply(i18n.Localize(langCode, "err_internal_error"))
+	}
+	log.Infof("admin %d %s source %d", ctx.Sender().ID, action, sourceID)
+	return ctx.Reply(i18n.Localize(langCode, successKey, source.Title))
+}
+
+// broadcast 向所有有订阅的用户发送 text
+func (a *Admin) broadcast(ctx tb.Context, langCode string, text string) error {
+	userIDs, err := a.core.GetSubscriberIDs(context.Background())
+	if err != nil {
+		log.Errorf("get subscriber ids failed, %v", err)
+		return ctx.Reply(i18n.Localize(langCode, "err_internal_error"))
+	}
+
+	var sent, failed int
+	for _, userID := range userIDs {
+		if err := util.BotSendWithRetry(a.bot, &tb.User{ID: userID}, text); err != nil {
+			log.Warnf("admin broadcast to %d failed, %v", userID, err)
+			failed++
+			continue
+		}
+		sent++
+	}
+	log.Infof("admin %d broadcast to %d users, %d failed", ctx.Sender().ID, sent, failed)
+	return ctx.Reply(i18n.Localize(langCode, "admin_broadcast_result", sent, failed))
+}
+
+func (a *Admin) Middlewares() []tb.MiddlewareFunc {
+	return []tb.MiddlewareFunc{middleware.AdminOnly()}
+}
+
+// truncateRunes 截断超过 limit 个字符的文本
+func truncateRunes(s string, limit int) string {
+	if utf8.RuneCountInString(s) <= limit {
+		return s
+	}
+	return string([]rune(s)[:limit]) + "…"
+}
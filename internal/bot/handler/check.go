@@ -0,0 +1,296 @@
+package handler
+
+import (
+	"context"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/chat"
+	"github.com/zintus/flowerss-bot/internal/bot/session"
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/config"
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+const (
+	// checkSourcesPerMessage 每条消息展示的订阅数量，避免超过 telegram 消息长度限制
+	checkSourcesPerMessage = 10
+	// checkErrorLimit 错误信息的最大长度
+	checkErrorLimit = 100
+	checkTimeLayout = "2006-01-02 15:04"
+)
+
+// Check 查看当前 chat 每个订阅的抓取状态，失败或暂停的订阅附带重试和取消订阅按钮
+type Check struct {
+	core *core.Core
+}
+
+func NewCheck(core *core.Core) *Check {
+	return &Check{core: core}
+}
+
+func (c *Check) Command() string {
+	return "/check"
+}
+
+func (c *Check) Description() string {
+	return i18n.Localize(util.DefaultLanguage, "check_command_desc")
+}
+
+func (c *Check) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	mentionChat, _ := session.GetMentionChatFromCtxStore(ctx)
+	ownerID := ctx.Chat().ID
+	if mentionChat != nil {
+		ownerID = mentionChat.ID
+	}
+
+	sources, err := c.core.GetUserSubscribedSources(context.Background(), ownerID)
+	if err != nil {
+		log.Errorf("GetUserSubscribedSources failed, %v", err)
+		return ctx.Reply(i18n.Localize(langCode, "check_err_get_subs_failed"))
+	}
+	if len(sources) == 0 {
+		return ctx.Reply(i18n.Localize(langCode, "check_info_no_subs"))
+	}
+
+	// 需要处理的订阅排在前面
+	sort.SliceStable(
+		sources, func(i, j int) bool {
+			if sources[i].ErrorCount != sources[j].ErrorCount {
+				return sources[i].ErrorCount > sources[j].ErrorCount
+			}
+			if sources[i].Paused != sources[j].Paused {
+				return sources[i].Paused
+			}
+			return sources[i].ID < sources[j].ID
+		},
+	)
+
+	unhealthy := 0
+	for _, source := range sources {
+		if needsAttention(source) {
+			unhealthy++
+		}
+	}
+
+	for start := 0; start < len(sources); start += checkSourcesPerMessage {
+		end := start + checkSourcesPerMessage
+		if end > len(sources) {
+			end = len(sources)
+		}
+
+		var msg strings.Builder
+		if start == 0 {
+			msg.WriteString(i18n.Localize(langCode, "check_header_format", len(sources), unhealthy))
+		}
+		var buttons [][]tb.InlineButton
+		for _, source := range sources[start:end] {
+			if msg.Len() > 0 {
+				msg.WriteString("\n\n")
+			}
+			msg.WriteString(formatSourceHealth(source, langCode))
+			if needsAttention(source) {
+				buttons = append(buttons, checkSourceButtons(ownerID, source, langCode))
+			}
+		}
+
+		opts := &tb.SendOptions{ParseMode: tb.ModeHTML, DisableWebPagePreview: true}
+		if len(buttons) > 0 {
+			opts.ReplyMarkup = &tb.ReplyMarkup{InlineKeyboard: buttons}
+		}
+		if err := util.SendWithRetry(ctx, msg.String(), opts); err != nil {
+			log.Errorf("failed to send check result: %v", err)
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Check) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
+
+// needsAttention 订阅源抓取失败或已暂停
+func needsAttention(source *model.Source) bool {
+	return source.Paused || source.ErrorCount > 0
+}
+
+// formatSourceHealth 生成单个订阅源的状态信息，HTML 格式
+func formatSourceHealth(source *model.Source, langCode string) string {
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return i18n.Localize(langCode, "check_never")
+		}
+		return t.Format(checkTimeLayout)
+	}
+
+	var status string
+	switch {
+	case source.Paused:
+		status = i18n.Localize(langCode, "check_status_paused")
+	case source.ErrorCount > 0:
+		status = i18n.Localize(langCode, "check_status_backoff")
+		if source.NextFetchAt != nil {
+			status += " " + i18n.Localize(langCode, "check_next_retry_format", source.NextFetchAt.Format(checkTimeLayout))
+		}
+	default:
+		status = i18n.Localize(langCode, "check_status_active")
+	}
+
+	lines := []string{
+		i18n.Localize(
+			langCode, "check_item_format", source.ID, html.EscapeString(source.DisplayLink()), html.EscapeString(source.Title),
+		),
+		i18n.Localize(langCode, "check_label_status", status),
+		i18n.Localize(langCode, "check_label_errors", source.ErrorCount, config.ErrorThreshold),
+		i18n.Localize(langCode, "check_label_last_fetch", formatTime(source.LastFetchedAt)),
+		i18n.Localize(langCode, "check_label_last_content", formatTime(source.LastContentAt)),
+	}
+	if source.LastError != "" {
+		lines = append(
+			lines, i18n.Localize(
+				langCode, "check_label_last_error",
+				html.EscapeString(truncateRunes(source.LastError, checkErrorLimit)), formatTime(source.LastErrorAt),
+			),
+		)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// checkSourceButtons 订阅源的重试和取消订阅按钮
+func checkSourceButtons(ownerID int64, source *model.Source, langCode string) []tb.InlineButton {
+	data := session.Marshal(&session.Attachment{UserId: ownerID, SourceId: uint32(source.ID)})
+	return []tb.InlineButton{
+		{
+			Unique: CheckRetryButtonUnique,
+			Text:   i18n.Localize(langCode, "check_btn_retry", source.ID),
+			Data:   data,
+		},
+		{
+			Unique: CheckUnsubscribeButtonUnique,
+			Text:   i18n.Localize(langCode, "check_btn_unsubscribe", source.ID),
+			Data:   data,
+		},
+	}
+}
+
+// checkButtonSubscription 解析按钮数据并校验权限，失败时返回需要提示给用户的文本
+func checkButtonSubscription(ctx tb.Context, appCore *core.Core, langCode string) (*model.Subscribe, string) {
+	attachData, err := session.UnmarshalAttachment(ctx.Callback().Data)
+	if err != nil {
+		return nil, i18n.Localize(langCode, "err_system_error")
+	}
+
+	userID := attachData.GetUserId()
+	// 操作其他 chat（频道）的订阅时需要是该 chat 的管理员
+	if userID != ctx.Chat().ID {
+		channelChat, err := ctx.Bot().ChatByID(userID)
+		if err != nil || !chat.IsChatAdmin(ctx.Bot(), channelChat, ctx.Sender().ID) {
+			return nil, i18n.Localize(langCode, "err_permission_denied")
+		}
+	}
+
+	sub, err := appCore.GetSubscription(context.Background(), userID, uint(attachData.GetSourceId()))
+	if err != nil {
+		return nil, i18n.Localize(langCode, "check_err_not_subscribed")
+	}
+	return sub, ""
+}
+
+type CheckRetryButton struct {
+	core *core.Core
+}
+
+func NewCheckRetryButton(core *core.Core) *CheckRetryButton {
+	return &CheckRetryButton{core: core}
+}
+
+func (b *CheckRetryButton) CallbackUnique() string {
+	return "\f" + CheckRetryButtonUnique
+}
+
+func (b *CheckRetryButton) Description() string {
+	return ""
+}
+
+func (b *CheckRetryButton) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	if ctx.Callback() == nil {
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "err_system_error")})
+	}
+
+	sub, errText := checkButtonSubscription(ctx, b.core, langCode)
+	if sub == nil {
+		return ctx.Respond(&tb.CallbackResponse{Text: errText})
+	}
+	if err := b.core.RetrySource(context.Background(), sub.SourceID); err != nil {
+		log.Errorf("retry source %d failed, %v", sub.SourceID, err)
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "err_system_error")})
+	}
+	log.Infof("%d retry source %d", sub.UserID, sub.SourceID)
+	return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "check_retry_success", sub.SourceID)})
+}
+
+func (b *CheckRetryButton) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
+
+type CheckUnsubscribeButton struct {
+	core *core.Core
+}
+
+func NewCheckUnsubscribeButton(core *core.Core) *CheckUnsubscribeButton {
+	return &CheckUnsubscribeButton{core: core}
+}
+
+func (b *CheckUnsubscribeButton) CallbackUnique() string {
+	return "\f" + CheckUnsubscribeButtonUnique
+}
+
+func (b *CheckUnsubscribeButton) Description() string {
+	return ""
+}
+
+func (b *CheckUnsubscribeButton) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	if ctx.Callback() == nil {
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "err_system_error")})
+	}
+
+	sub, errText := checkButtonSubscription(ctx, b.core, langCode)
+	if sub == nil {
+		return ctx.Respond(&tb.CallbackResponse{Text: errText})
+	}
+	if err := b.core.Unsubscribe(context.Background(), sub.UserID, sub.SourceID); err != nil {
+		log.Errorf("%d unsubscribe source %d failed, %v", sub.UserID, sub.SourceID, err)
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "unsub_err_button_action_failed")})
+	}
+	log.Infof("%d unsubscribe source %d from check", sub.UserID, sub.SourceID)
+
+	// 去掉已取消订阅的按钮，保留其他订阅的按钮。收到的按钮数据是 "\f<unique>|<data>" 格式
+	if msg := ctx.Callback().Message; msg != nil && msg.ReplyMarkup != nil {
+		var rows [][]tb.InlineButton
+		for _, row := range msg.ReplyMarkup.InlineKeyboard {
+			if len(row) > 0 && strings.HasSuffix(row[0].Data, "|"+ctx.Callback().Data) {
+				continue
+			}
+			rows = append(rows, row)
+		}
+		if _, err := ctx.Bot().EditReplyMarkup(msg, &tb.ReplyMarkup{InlineKeyboard: rows}); err != nil {
+			log.Warnf("failed to update check buttons: %v", err)
+		}
+	}
+	return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "check_unsubscribe_success", sub.SourceID)})
+}
+
+func (b *CheckUnsubscribeButton) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+func TestFormatSourceHealth(t *testing.T) {
+	i18n.ResetTranslationsForTest()
+	if err := i18n.LoadTranslations("../../../locales"); err != nil {
+		t.Fatalf("load translations: %v", err)
+	}
+	t.Cleanup(i18n.ResetTranslationsForTest)
+
+	fetched := time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC)
+	nextRetry := time.Date(2024, 1, 4, 8, 30, 0, 0, time.UTC)
+	failing := &model.Source{
+		ID:            1,
+		Title:         "A & B",
+		Link:          "https://example.com/feed?a=1&b=2",
+		ErrorCount:    3,
+		LastError:     "<timeout>",
+		LastFetchedAt: &fetched,
+		NextFetchAt:   &nextRetry,
+	}
+
+	out := formatSourceHealth(failing, "en")
+	for _, want := range []string{
+		`[1] <a href="https://example.com/feed?a=1&amp;b=2">A &amp; B</a>`,
+		"Status: Backing off (next retry 2024-01-04 08:30)",
+		"Last successful fetch: 2024-01-03 10:00",
+		"Last new item: never",
+		"Last error: &lt;timeout&gt; (never)",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in %q", want, out)
+		}
+	}
+	if !needsAttention(failing) {
+		t.Error("failing source should need attention")
+	}
+
+	paused := &model.Source{ID: 2, Paused: true, ErrorCount: 1}
+	if out := formatSourceHealth(paused, "en"); !strings.Contains(out, "Status: Paused") {
+		t.Errorf("expected paused status, got %q", out)
+	}
+
+	healthy := &model.Source{ID: 3, LastFetchedAt: &fetched}
+	out = formatSourceHealth(healthy, "en")
+	if !strings.Contains(out, "Status: Active") || strings.Contains(out, "Last error") {
+		t.Errorf("unexpected healthy output %q", out)
+	}
+	if needsAttention(healthy) {
+		t.Error("healthy source should not need attention")
+	}
+}
@@ -2,11 +2,15 @@
 package handler
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"text/template"
+	"time"
 
 	tb "gopkg.in/telebot.v3"
 
-	"github.com/zintus/flowerss-bot/internal/config"
+	"github.com/zintus/flowerss-bot/internal/core"
 	"github.com/zintus/flowerss-bot/internal/i18n"
 	"github.com/zintus/flowerss-bot/internal/model"
 )
@@ -18,19 +22,50 @@ const (
 	NotificationSwitchButtonUnique = "set_toggle_notice_btn"
 	TelegraphSwitchButtonUnique    = "set_toggle_telegraph_btn"
 	SetFeedItemButtonUnique        = "set_feed_item_btn" // From set.go
+	SetFilterButtonUnique          = "set_set_filter_btn"
+	SearchPageButtonUnique         = "search_page_btn"
+	MediaSwitchButtonUnique        = "set_toggle_media_btn"
+	CheckRetryButtonUnique         = "check_retry_btn"
+	CheckUnsubscribeButtonUnique   = "check_unsub_btn"
+	FeedChoiceButtonUnique         = "sub_feed_choice_btn"
+	SetHTTPButtonUnique            = "set_set_http_btn"
 )
 
 // Common template for feed settings
 const feedSettingTmpl = `
-{{ .L "set_tmpl_header_settings" }}
-{{ .L "set_tmpl_label_id" }} {{ .source.ID }}
-{{ .L "set_tmpl_label_title" }} {{ .source.Title }}
-{{ .L "set_tmpl_label_link" }} {{ .source.Link }}
-{{ .L "set_tmpl_label_updates" }} {{if ge .source.ErrorCount .Count }}{{ .L "set_tmpl_status_paused" }}{{else}}{{ .L "set_tmpl_status_active" }}{{end}}
-{{ .L "set_tmpl_label_interval" }} {{ .sub.Interval }} {{ .L "set_tmpl_unit_minutes" }}
-{{ .L "set_tmpl_label_notifications" }} {{if eq .sub.EnableNotification 0}}{{ .L "set_tmpl_status_off" }}{{else}}{{ .L "set_tmpl_status_on" }}{{end}}
-{{ .L "set_tmpl_label_telegraph" }} {{if eq .sub.EnableTelegraph 0}}{{ .L "set_tmpl_status_off" }}{{else}}{{ .L "set_tmpl_status_on" }}{{end}}
-{{ .L "set_tmpl_label_tags" }} {{if .sub.Tag}}{{ .sub.Tag }}{{else}}{{ .L "set_tmpl_status_none" }}{{end}}
+{{ L "set_tmpl_header_settings" }}
+{{ L "set_tmpl_label_id" }} {{ .source.ID }}
+{{ L "set_tmpl_label_title" }} {{ .source.Title }}
+{{ L "set_tmpl_label_link" }} {{ .source.DisplayLink }}
+{{ L "set_tmpl_label_updates" }} {{if .source.Paused }}{{ L "set_tmpl_status_paused" }}{{else if gt .source.ErrorCount 0 }}{{ L "set_tmpl_status_failing" .source.ErrorCount }}{{else}}{{ L "set_tmpl_status_active" }}{{end}}
+{{- if and (gt .source.ErrorCount 0) .source.LastError }}
+{{ L "set_tmpl_label_last_error" }} {{ html .source.LastError }}{{ with .source.LastErrorAt }} ({{ .Format "2006-01-02 15:04" }}){{ end }}
+{{- end }}
+{{- if and (not .source.Paused) (gt .source.ErrorCount 0) .source.NextFetchAt }}
+{{ L "set_tmpl_label_next_retry" }} {{ .source.NextFetchAt.Format "2006-01-02 15:04" }}
+{{- end }}
+{{ L "set_tmpl_label_interval" }} {{ .sub.Interval }} {{ L "set_tmpl_unit_minutes" }}
+{{- with .fetch }}
+{{ L "set_tmpl_label_fetch_interval" }} {{ minutes .Interval }} {{ L "set_tmpl_unit_minutes" }} ({{ L (printf "set_tmpl_interval_reason_%s" .Reason) }})
+{{- if .SkipHours }}
+{{ L "set_tmpl_label_skip_hours" }} {{ hours .SkipHours }}
+{{- end }}
+{{- if .SkipDays }}
+{{ L "set_tmpl_label_skip_days" }} {{ weekdays .SkipDays }}
+{{- end }}
+{{- end }}
+{{ L "set_tmpl_label_notifications" }} {{if eq .sub.EnableNotification 0}}{{ L "set_tmpl_status_off" }}{{else}}{{ L "set_tmpl_status_on" }}{{end}}
+{{ L "set_tmpl_label_digest" }} {{if .sub.Digest}}{{ .sub.Digest }}{{else}}{{ L "set_tmpl_status_off" }}{{end}}
+{{ L "set_tmpl_label_telegraph" }} {{if eq .sub.EnableTelegraph 0}}{{ L "set_tmpl_status_off" }}{{else}}{{ L "set_tmpl_status_on" }}{{end}}
+{{ L "set_tmpl_label_media" }} {{if eq .sub.EnableMedia 1}}{{ L "set_tmpl_status_on" }}{{else}}{{ L "set_tmpl_status_off" }}{{end}}
+{{ L "set_tmpl_label_http" }} {{if .source.RequestSettings.IsEmpty}}{{ L "set_tmpl_status_none" }}{{else}}{{ html .source.RequestSettings }}{{end}}
+{{ L "set_tmpl_label_tags" }} {{if .sub.Tag}}{{ .sub.Tag }}{{else}}{{ L "set_tmpl_status_none" }}{{end}}
+{{- if .sub.IncludeKeywords }}
+{{ L "set_tmpl_label_include" }} {{ html (rules .sub.IncludeKeywords) }}
+{{- end }}
+{{- if .sub.ExcludeKeywords }}
+{{ L "set_tmpl_label_exclude" }} {{ html (rules .sub.ExcludeKeywords) }}
+{{- end }}
 `
 
 // Common function to generate feed setting buttons
@@ -70,11 +105,35 @@ func genFeedSetBtn(
 	}
 
 	var updatesTextKey string
-	if source.ErrorCount >= config.ErrorThreshold {
+	if source.Paused {
 		updatesTextKey = "set_btn_resume_updates"
 	} else {
 		updatesTextKey = "set_btn_pause_updates"
 	}
+	setFilterKey := tb.InlineButton{
+		Unique: SetFilterButtonUnique,
+		Text:   i18n.Localize(langCode, "set_btn_filter_settings"),
+		Data:   c.Data,
+	}
+
+	var mediaTextKey string
+	if sub.EnableMedia == 1 {
+		mediaTextKey = "set_btn_disable_media"
+	} else {
+		mediaTextKey = "set_btn_enable_media"
+	}
+	toggleMediaKey := tb.InlineButton{
+		Unique: MediaSwitchButtonUnique,
+		Text:   i18n.Localize(langCode, mediaTextKey),
+		Data:   c.Data,
+	}
+
+	setHTTPKey := tb.InlineButton{
+		Unique: SetHTTPButtonUnique,
+		Text:   i18n.Localize(langCode, "set_btn_http_settings"),
+		Data:   c.Data,
+	}
+
 	toggleEnabledKey := tb.InlineButton{
 		Unique: SubscriptionSwitchButtonUnique, // Uses common constant
 		Text:   i18n.Localize(langCode, updatesTextKey),
@@ -90,10 +149,27 @@ func genFeedSetBtn(
 			toggleTelegraphKey,
 			setSubTagKey,
 		},
+		{ // Row 3
+			setFilterKey,
+			toggleMediaKey,
+		},
+		{ // Row 4
+			setHTTPKey,
+		},
 	}
 	return feedSettingKeys
 }
 
+// feedSettingData builds the data for rendering feedSettingTmpl.
+// The fetch schedule is left out when it can't be loaded.
+func feedSettingData(c *core.Core, source *model.Source, sub *model.Subscribe) map[string]interface{} {
+	data := map[string]interface{}{"source": source, "sub": sub}
+	if schedule, err := c.SourceFetchSchedule(context.Background(), source); err == nil {
+		data["fetch"] = schedule
+	}
+	return data
+}
+
 // getTemplateFuncMap provides the template.FuncMap for rendering the feedSettingTmpl.
 // Each handler should use this to ensure "L" function is available for localization.
 func getTemplateFuncMap(langCode string) template.FuncMap {
@@ -101,5 +177,25 @@ func getTemplateFuncMap(langCode string) template.FuncMap {
 		"L": func(key string, args ...interface{}) string {
 			return i18n.Localize(langCode, key, args...)
 		},
+		"rules": func(stored string) string {
+			return strings.Join(core.FilterRules(stored), ", ")
+		},
+		"minutes": func(d time.Duration) int {
+			return int(d / time.Minute)
+		},
+		"hours": func(hours []int) string {
+			s := make([]string, len(hours))
+			for i, hour := range hours {
+				s[i] = fmt.Sprintf("%02d:00", hour)
+			}
+			return strings.Join(s, ", ")
+		},
+		"weekdays": func(days []int) string {
+			s := make([]string, len(days))
+			for i, day := range days {
+				s[i] = time.Weekday(day).String()
+			}
+			return strings.Join(s, ", ")
+		},
 	}
 }
\ No newline at end of file
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/message"
+	"github.com/zintus/flowerss-bot/internal/bot/session"
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/log"
+)
+
+type Digest struct {
+	core *core.Core
+}
+
+func NewDigest(core *core.Core) *Digest {
+	return &Digest{core: core}
+}
+
+func (d *Digest) Command() string {
+	return "/digest"
+}
+
+func (d *Digest) Description() string {
+	return i18n.Localize(util.DefaultLanguage, "digest_command_desc")
+}
+
+func (d *Digest) getMessageWithoutMention(ctx tb.Context) string {
+	mention := message.MentionFromMessage(ctx.Message())
+	if mention == "" {
+		return ctx.Message().Payload
+	}
+	return strings.ReplaceAll(ctx.Message().Payload, mention, "")
+}
+
+func (d *Digest) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	args := strings.Fields(d.getMessageWithoutMention(ctx))
+	if len(args) < 1 {
+		return ctx.Reply(i18n.Localize(langCode, "digest_usage_hint"))
+	}
+
+	subscribeUserID := ctx.Chat().ID
+	mentionChat, _ := session.GetMentionChatFromCtxStore(ctx)
+	if mentionChat != nil {
+		subscribeUserID = mentionChat.ID
+	}
+
+	all := strings.ToLower(args[0]) == "all"
+	sourceID := cast.ToUint(args[0])
+	if len(args) == 1 {
+		if all {
+			return ctx.Reply(i18n.Localize(langCode, "digest_usage_hint"))
+		}
+		sub, err := d.core.GetSubscription(context.Background(), subscribeUserID, sourceID)
+		if err != nil {
+			return ctx.Reply(i18n.Localize(langCode, "digest_err_not_subscribed"))
+		}
+		return ctx.Reply(i18n.Localize(langCode, "digest_show_format", digestText(langCode, sub.Digest)))
+	}
+
+	schedule, err := core.ParseDigestSchedule(strings.Join(args[1:], " "))
+	if err != nil {
+		return ctx.Reply(i18n.Localize(langCode, "digest_usage_hint"))
+	}
+	spec := ""
+	if schedule != nil {
+		spec = schedule.String()
+	}
+
+	if all {
+		err = d.core.SetUserDigest(context.Background(), subscribeUserID, schedule, time.Now())
+	} else {
+		err = d.core.SetSubscriptionDigest(context.Background(), subscribeUserID, sourceID, schedule, time.Now())
+	}
+	if err != nil {
+		log.Errorf("set digest failed, %v", err)
+		if errors.Is(err, core.ErrSubscriptionNotExist) {
+			return ctx.Reply(i18n.Localize(langCode, "digest_err_not_subscribed"))
+		}
+		return ctx.Reply(i18n.Localize(langCode, "digest_err_set_failed"))
+	}
+	return ctx.Reply(i18n.Localize(langCode, "digest_show_format", digestText(langCode, spec)))
+}
+
+func (d *Digest) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
+
+// digestText 摘要设置的展示文本
+func digestText(langCode string, spec string) string {
+	if spec == "" {
+		return i18n.Localize(langCode, "set_tmpl_status_off")
+	}
+	return spec
+}
@@ -0,0 +1,171 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/feed"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/log"
+)
+
+const (
+	// feedChoiceTTL 待选择的订阅源保留时间
+	feedChoiceTTL = 10 * time.Minute
+	// maxFeedChoices 最多展示的订阅源数量
+	maxFeedChoices = 10
+	// feedChoiceTitleLimit 按钮文本的最大长度
+	feedChoiceTitleLimit = 60
+)
+
+// feedChoice 网页中发现的多个订阅源，等待用户选择
+type feedChoice struct {
+	ownerID  int64
+	senderID int64
+	feeds    []feed.DiscoveredFeed
+	expireAt time.Time
+}
+
+// feedChoiceCache 保存待选择的订阅源，按钮数据长度有限，只在按钮中携带 token 和序号
+type feedChoiceCache struct {
+	mu    sync.Mutex
+	items map[string]*feedChoice
+}
+
+var feedChoices = &feedChoiceCache{items: make(map[string]*feedChoice)}
+
+func (c *feedChoiceCache) put(choice *feedChoice) (string, error) {
+	buf := make([]byte, 6)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, v := range c.items {
+		if now.After(v.expireAt) {
+			delete(c.items, k)
+		}
+	}
+	c.items[token] = choice
+	return token, nil
+}
+
+func (c *feedChoiceCache) get(token string) (*feedChoice, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	choice, ok := c.items[token]
+	if !ok || time.Now().After(choice.expireAt) {
+		return nil, false
+	}
+	return choice, true
+}
+
+func (c *feedChoiceCache) delete(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.items, token)
+}
+
+// replyFeedChoices 回复网页中发现的订阅源，由用户点击按钮选择订阅哪一个
+func replyFeedChoices(ctx tb.Context, ownerID int64, feeds []feed.DiscoveredFeed, langCode string) error {
+	if len(feeds) > maxFeedChoices {
+		feeds = feeds[:maxFeedChoices]
+	}
+	token, err := feedChoices.put(
+		&feedChoice{
+			ownerID:  ownerID,
+			senderID: ctx.Sender().ID,
+			feeds:    feeds,
+			expireAt: time.Now().Add(feedChoiceTTL),
+		},
+	)
+	if err != nil {
+		log.Errorf("save feed choices failed, %v", err)
+		return ctx.Reply(i18n.Localize(langCode, "err_internal_error"))
+	}
+
+	var buttons [][]tb.InlineButton
+	for i, f := range feeds {
+		text := f.Title
+		if text == "" {
+			text = f.URL
+		}
+		buttons = append(
+			buttons, []tb.InlineButton{
+				{
+					Unique: FeedChoiceButtonUnique,
+					Text:   truncateRunes(text, feedChoiceTitleLimit),
+					Data:   token + "|" + strconv.Itoa(i),
+				},
+			},
+		)
+	}
+	return ctx.Reply(
+		i18n.Localize(langCode, "addsub_info_choose_feed_format", len(feeds)),
+		&tb.ReplyMarkup{InlineKeyboard: buttons},
+	)
+}
+
+type FeedChoiceButton struct {
+	core *core.Core
+}
+
+func NewFeedChoiceButton(core *core.Core) *FeedChoiceButton {
+	return &FeedChoiceButton{core: core}
+}
+
+func (b *FeedChoiceButton) CallbackUnique() string {
+	return "\f" + FeedChoiceButtonUnique
+}
+
+func (b *FeedChoiceButton) Description() string {
+	return ""
+}
+
+func (b *FeedChoiceButton) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	if ctx.Callback() == nil {
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "err_system_error")})
+	}
+
+	token, indexStr, _ := strings.Cut(ctx.Callback().Data, "|")
+	choice, ok := feedChoices.get(token)
+	if !ok {
+		return ctx.Edit(i18n.Localize(langCode, "addsub_err_choice_expired"))
+	}
+	if choice.senderID != ctx.Sender().ID {
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "err_permission_denied")})
+	}
+	index, err := strconv.Atoi(indexStr)
+	if err != nil || index < 0 || index >= len(choice.feeds) {
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "err_system_error")})
+	}
+
+	source, err := subscribeSource(b.core, choice.ownerID, choice.feeds[index].URL)
+	if err != nil {
+		return ctx.Edit(subscribeErrorText(err, langCode))
+	}
+	feedChoices.delete(token)
+	return ctx.Edit(
+		i18n.Localize(langCode, "addsub_success_subscribed_format", source.ID, source.Title, source.DisplayLink()),
+		&tb.SendOptions{
+			DisableWebPagePreview: true,
+			ParseMode:             tb.ModeMarkdown,
+		},
+	)
+}
+
+func (b *FeedChoiceButton) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
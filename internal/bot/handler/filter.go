@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/spf13/cast"
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/message"
+	"github.com/zintus/flowerss-bot/internal/bot/session"
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/log"
+)
+
+type Filter struct {
+	core *core.Core
+}
+
+func NewFilter(core *core.Core) *Filter {
+	return &Filter{core: core}
+}
+
+func (f *Filter) Command() string {
+	return "/filter"
+}
+
+func (f *Filter) Description() string {
+	return i18n.Localize(util.DefaultLanguage, "filter_command_desc")
+}
+
+func (f *Filter) getMessageWithoutMention(ctx tb.Context) string {
+	mention := message.MentionFromMessage(ctx.Message())
+	if mention == "" {
+		return ctx.Message().Payload
+	}
+	return strings.ReplaceAll(ctx.Message().Payload, mention, "")
+}
+
+func (f *Filter) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	msg := strings.TrimSpace(f.getMessageWithoutMention(ctx))
+	args := strings.SplitN(msg, " ", 3)
+	if args[0] == "" {
+		return ctx.Reply(i18n.Localize(langCode, "filter_usage_hint"))
+	}
+
+	sourceID := cast.ToUint(args[0])
+	subscribeUserID := ctx.Chat().ID
+	mentionChat, _ := session.GetMentionChatFromCtxStore(ctx)
+	if mentionChat != nil {
+		subscribeUserID = mentionChat.ID
+	}
+
+	var err error
+	action := ""
+	if len(args) > 1 {
+		action = strings.ToLower(args[1])
+	}
+	switch action {
+	case "":
+		// 只查看当前规则
+	case "clear":
+		err = f.core.ClearSubscriptionFilter(context.Background(), subscribeUserID, sourceID)
+	case string(core.FilterInclude), string(core.FilterExclude):
+		var rules []string
+		if len(args) > 2 {
+			rules, err = core.ParseFilterRules(args[2])
+			var ruleErr *core.InvalidFilterRuleError
+			if errors.As(err, &ruleErr) {
+				return ctx.Reply(i18n.Localize(langCode, "filter_err_invalid_rule_format", ruleErr.Rule))
+			}
+		}
+		err = f.core.SetSubscriptionFilter(
+			context.Background(), subscribeUserID, sourceID, core.FilterKind(action), rules,
+		)
+	default:
+		return ctx.Reply(i18n.Localize(langCode, "filter_usage_hint"))
+	}
+	if err != nil {
+		log.Errorf("set subscription filter failed, %v", err)
+		if errors.Is(err, core.ErrSubscriptionNotExist) {
+			return ctx.Reply(i18n.Localize(langCode, "filter_err_not_subscribed"))
+		}
+		return ctx.Reply(i18n.Localize(langCode, "filter_err_set_failed"))
+	}
+
+	sub, err := f.core.GetSubscription(context.Background(), subscribeUserID, sourceID)
+	if err != nil {
+		if errors.Is(err, core.ErrSubscriptionNotExist) {
+			return ctx.Reply(i18n.Localize(langCode, "filter_err_not_subscribed"))
+		}
+		return ctx.Reply(i18n.Localize(langCode, "filter_err_set_failed"))
+	}
+	return ctx.Reply(
+		i18n.Localize(
+			langCode, "filter_show_format",
+			filterRulesText(langCode, sub.IncludeKeywords), filterRulesText(langCode, sub.ExcludeKeywords),
+		),
+	)
+}
+
+func (f *Filter) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
+
+// filterRulesText 过滤规则的展示文本
+func filterRulesText(langCode string, stored string) string {
+	rules := core.FilterRules(stored)
+	if len(rules) == 0 {
+		return i18n.Localize(langCode, "set_tmpl_status_none")
+	}
+	return strings.Join(rules, ", ")
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/chat"
+	"github.com/zintus/flowerss-bot/internal/bot/session"
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+)
+
+// SetFilterButtonUnique is defined in common.go
+
+type SetFilterButton struct {
+	bot *tb.Bot
+}
+
+func NewSetFilterButton(bot *tb.Bot) *SetFilterButton {
+	return &SetFilterButton{bot: bot}
+}
+
+func (b *SetFilterButton) CallbackUnique() string {
+	return "\f" + SetFilterButtonUnique
+}
+
+func (b *SetFilterButton) Description() string {
+	return ""
+}
+
+func (b *SetFilterButton) feedSetAuth(c *tb.Callback, attachData *session.Attachment) bool {
+	subscriberID := attachData.GetUserId()
+	if subscriberID != c.Sender.ID {
+		channelChat, err := b.bot.ChatByID(subscriberID)
+		if err != nil {
+			return false
+		}
+
+		if !chat.IsChatAdmin(b.bot, channelChat, c.Sender.ID) {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *SetFilterButton) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	c := ctx.Callback()
+	attachData, err := session.UnmarshalAttachment(ctx.Callback().Data)
+	if err != nil {
+		return ctx.Edit(i18n.Localize(langCode, "err_system_error"))
+	}
+
+	if !b.feedSetAuth(c, attachData) {
+		return ctx.Send(i18n.Localize(langCode, "err_permission_denied"))
+	}
+	sourceID := uint(attachData.GetSourceId())
+	msg := i18n.Localize(langCode, "filterbtn_usage_hint_format", sourceID, sourceID, sourceID)
+	return ctx.Edit(msg, &tb.SendOptions{ParseMode: tb.ModeMarkdown})
+}
+
+func (b *SetFilterButton) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
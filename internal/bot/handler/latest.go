@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cast"
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/message"
+	"github.com/zintus/flowerss-bot/internal/bot/session"
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+const (
+	// defaultLatestCount /latest 默认展示的文章数
+	defaultLatestCount = 5
+	// maxLatestCount /latest 最多展示的文章数
+	maxLatestCount = 20
+	// latestTitleMaxLen 文章标题的最大展示长度
+	latestTitleMaxLen = 200
+	// latestMessageLimit telegram 单条消息的最大长度
+	latestMessageLimit = 4096
+)
+
+type Latest struct {
+	core *core.Core
+}
+
+func NewLatest(core *core.Core) *Latest {
+	return &Latest{core: core}
+}
+
+func (l *Latest) Command() string {
+	return "/latest"
+}
+
+func (l *Latest) Description() string {
+	return i18n.Localize(util.DefaultLanguage, "latest_command_desc")
+}
+
+func (l *Latest) getMessageWithoutMention(ctx tb.Context) string {
+	mention := message.MentionFromMessage(ctx.Message())
+	if mention == "" {
+		return ctx.Message().Payload
+	}
+	return strings.ReplaceAll(ctx.Message().Payload, mention, "")
+}
+
+func (l *Latest) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	args := strings.Fields(l.getMessageWithoutMention(ctx))
+	if len(args) < 1 || len(args) > 2 {
+		return ctx.Reply(i18n.Localize(langCode, "latest_usage_hint"))
+	}
+	sourceID, err := cast.ToUintE(args[0])
+	if err != nil {
+		return ctx.Reply(i18n.Localize(langCode, "latest_usage_hint"))
+	}
+	count := defaultLatestCount
+	if len(args) == 2 {
+		count, err = cast.ToIntE(args[1])
+		if err != nil || count <= 0 {
+			return ctx.Reply(i18n.Localize(langCode, "latest_usage_hint"))
+		}
+		if count > maxLatestCount {
+			count = maxLatestCount
+		}
+	}
+
+	subscribeUserID := ctx.Chat().ID
+	mentionChat, _ := session.GetMentionChatFromCtxStore(ctx)
+	if mentionChat != nil {
+		subscribeUserID = mentionChat.ID
+	}
+
+	// 只能查看已订阅的订阅源
+	stdCtx := context.Background()
+	if _, err := l.core.GetSubscription(stdCtx, subscribeUserID, sourceID); err != nil {
+		return ctx.Reply(i18n.Localize(langCode, "latest_err_not_subscribed"))
+	}
+	source, err := l.core.GetSource(stdCtx, sourceID)
+	if err != nil {
+		return ctx.Reply(i18n.Localize(langCode, "latest_err_not_subscribed"))
+	}
+
+	contents, err := l.core.GetSourceLatestContents(stdCtx, sourceID, count)
+	if err != nil {
+		log.Errorf("get latest contents of source %d failed, %v", sourceID, err)
+		return ctx.Reply(i18n.Localize(langCode, "latest_err_get_failed"))
+	}
+	if len(contents) == 0 {
+		return ctx.Reply(i18n.Localize(langCode, "latest_info_empty"))
+	}
+
+	return ctx.Reply(
+		renderLatestContents(langCode, source, contents), &tb.SendOptions{
+			ParseMode:             tb.ModeHTML,
+			DisableWebPagePreview: true,
+		},
+	)
+}
+
+func (l *Latest) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
+
+// renderLatestContents 列出订阅源的最新文章，超出消息长度的文章不展示
+func renderLatestContents(langCode string, source *model.Source, contents []*model.Content) string {
+	var msg strings.Builder
+	msg.WriteString(i18n.Localize(langCode, "latest_header_format", html.EscapeString(source.Title), len(contents)))
+	msg.WriteString("\n")
+	for _, content := range contents {
+		title := content.Title
+		if title == "" {
+			title = content.RawLink
+		}
+		if utf8.RuneCountInString(title) > latestTitleMaxLen {
+			title = string([]rune(title)[:latestTitleMaxLen]) + "…"
+		}
+		line := fmt.Sprintf("\n• <a href=\"%s\">%s</a>", html.EscapeString(content.RawLink), html.EscapeString(title))
+		if content.PublishedAt != nil {
+			line += " · " + content.PublishedAt.Format("2006-01-02")
+		}
+		if utf8.RuneCountInString(msg.String())+utf8.RuneCountInString(line) > latestMessageLimit {
+			break
+		}
+		msg.WriteString(line)
+	}
+	return msg.String()
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+func TestRenderLatestContents(t *testing.T) {
+	i18n.ResetTranslationsForTest()
+	if err := i18n.LoadTranslations("../../../locales"); err != nil {
+		t.Fatalf("load translations: %v", err)
+	}
+	t.Cleanup(i18n.ResetTranslationsForTest)
+
+	published := time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC)
+	source := &model.Source{ID: 1, Title: "A & B"}
+	contents := []*model.Content{
+		{Title: "<new>", RawLink: "https://example.com/?a=1&b=2", PublishedAt: &published},
+		{RawLink: "https://example.com/untitled"},
+	}
+
+	out := renderLatestContents("en", source, contents)
+	if !strings.Contains(out, "<b>A &amp; B</b>") {
+		t.Errorf("expected escaped source title, got %q", out)
+	}
+	if !strings.Contains(out, `<a href="https://example.com/?a=1&amp;b=2">&lt;new&gt;</a> · 2024-01-03`) {
+		t.Errorf("expected escaped item with date, got %q", out)
+	}
+	if !strings.Contains(out, `>https://example.com/untitled</a>`) {
+		t.Errorf("expected link as title for untitled item, got %q", out)
+	}
+
+	var many []*model.Content
+	for i := 0; i < maxLatestCount; i++ {
+		many = append(many, &model.Content{Title: strings.Repeat("长", 500), RawLink: "https://example.com/" + strings.Repeat("x", 200)})
+	}
+	out = renderLatestContents("en", source, many)
+	if n := utf8.RuneCountInString(out); n > latestMessageLimit {
+		t.Errorf("message too long: %d", n)
+	}
+}
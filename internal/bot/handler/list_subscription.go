@@ -115,7 +115,7 @@ func (l *ListSubscription) replaySubscribedSources(ctx tb.Context, sources []*mo
 		if sources[i].LastContentAt != nil {
 			contentDate = sources[i].LastContentAt.Format("2006-01-02 15:04:05")
 		}
-		msg.WriteString(fmt.Sprintf("[[%d]] [%s](%s) - %s\n", sources[i].ID, sources[i].Title, sources[i].Link, contentDate))
+		msg.WriteString(fmt.Sprintf("[[%d]] [%s](%s) - %s\n", sources[i].ID, sources[i].Title, sources[i].DisplayLink(), contentDate))
 		count++
 		if count == MaxSubsSizePerPage {
 			if err := util.SendWithRetry(ctx, msg.String(), &tb.SendOptions{DisableWebPagePreview: true, ParseMode: tb.ModeMarkdown}); err != nil {
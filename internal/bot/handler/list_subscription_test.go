@@ -59,6 +59,9 @@ func (m *mockListSubSubscriptionStorage) GetSubscriptionsBySourceID(ctx context.
 func (m *mockListSubSubscriptionStorage) CountSubscriptions(ctx context.Context) (int64, error) {
 	panic("not implemented")
 }
+func (m *mockListSubSubscriptionStorage) GetSubscriberIDs(ctx context.Context) ([]int64, error) {
+	panic("not implemented")
+}
 
 func (m *mockListSubSubscriptionStorage) DeleteSubscription(ctx context.Context, userID int64, sourceID uint) (int64, error) {
 	panic("not implemented")
@@ -101,6 +104,10 @@ func (m *mockListSubSourceStorage) GetSourceByURL(ctx context.Context, url strin
 	panic("not implemented")
 }
 
+func (m *mockListSubSourceStorage) GetSourceByURLKey(ctx context.Context, key string) (*model.Source, error) {
+	panic("not implemented")
+}
+
 func (m *mockListSubSourceStorage) Delete(ctx context.Context, id uint) error { return nil }
 
 func (m *mockListSubSourceStorage) UpsertSource(ctx context.Context, sourceID uint, newSource *model.Source) error {
@@ -143,7 +150,7 @@ func TestListSubscription_replaySubscribedSources_Sorted(t *testing.T) {
 		},
 	}
 
-	coreInstance := core.NewCore(nil, nil, sourceStorage, subStorage, nil, nil)
+	coreInstance := core.NewCore(nil, nil, sourceStorage, subStorage, nil, nil, nil, nil)
 	h := NewListSubscription(coreInstance)
 
 	ctx := &mockListSubCtx{
@@ -222,7 +229,7 @@ func TestListSubscription_replaySubscribedSources_EmptyList(t *testing.T) {
 		},
 	}
 
-	coreInstance := core.NewCore(nil, nil, sourceStorage, subStorage, nil, nil)
+	coreInstance := core.NewCore(nil, nil, sourceStorage, subStorage, nil, nil, nil, nil)
 	h := NewListSubscription(coreInstance)
 
 	ctx := &mockListSubCtx{
@@ -0,0 +1,90 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"text/template"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/chat"
+	"github.com/zintus/flowerss-bot/internal/bot/session"
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+)
+
+type MediaSwitchButton struct {
+	bot  *tb.Bot
+	core *core.Core
+}
+
+func NewMediaSwitchButton(bot *tb.Bot, core *core.Core) *MediaSwitchButton {
+	return &MediaSwitchButton{bot: bot, core: core}
+}
+
+func (b *MediaSwitchButton) CallbackUnique() string {
+	return "\f" + MediaSwitchButtonUnique
+}
+
+func (b *MediaSwitchButton) Description() string {
+	return ""
+}
+
+func (b *MediaSwitchButton) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	c := ctx.Callback()
+	if c == nil {
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "notify_switch_err_callback_nil")})
+	}
+
+	attachData, err := session.UnmarshalAttachment(c.Data)
+	if err != nil {
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "notify_switch_err_generic")})
+	}
+	subscriberID := attachData.GetUserId()
+	if subscriberID != c.Sender.ID {
+		channelChat, err := b.bot.ChatByID(subscriberID)
+		if err != nil {
+			return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "notify_switch_err_generic")})
+		}
+		if !chat.IsChatAdmin(b.bot, channelChat, c.Sender.ID) {
+			return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "notify_switch_err_generic")})
+		}
+	}
+
+	sourceID := uint(attachData.GetSourceId())
+	source, err := b.core.GetSource(context.Background(), sourceID)
+	if err != nil {
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "notify_switch_err_generic")})
+	}
+
+	if err := b.core.ToggleSubscriptionMedia(context.Background(), subscriberID, sourceID); err != nil {
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "notify_switch_err_generic")})
+	}
+
+	sub, err := b.core.GetSubscription(context.Background(), subscriberID, sourceID)
+	if err != nil {
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "notify_switch_err_generic")})
+	}
+
+	t, err := template.New("setting template").Funcs(getTemplateFuncMap(langCode)).Parse(feedSettingTmpl)
+	if err != nil {
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "notify_switch_err_generic")})
+	}
+	text := new(bytes.Buffer)
+	if err := t.Execute(text, feedSettingData(b.core, source, sub)); err != nil {
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "notify_switch_err_generic")})
+	}
+
+	_ = ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "subswitch_success_updated")})
+	return ctx.Edit(
+		text.String(),
+		&tb.SendOptions{ParseMode: tb.ModeHTML},
+		&tb.ReplyMarkup{InlineKeyboard: genFeedSetBtn(c, sub, source, langCode)},
+	)
+}
+
+func (b *MediaSwitchButton) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
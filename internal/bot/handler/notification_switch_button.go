@@ -10,7 +10,6 @@ import (
 	"github.com/zintus/flowerss-bot/internal/bot/chat"
 	"github.com/zintus/flowerss-bot/internal/bot/session"
 	"github.com/zintus/flowerss-bot/internal/bot/util"
-	"github.com/zintus/flowerss-bot/internal/config"
 	"github.com/zintus/flowerss-bot/internal/core"
 	"github.com/zintus/flowerss-bot/internal/i18n"
 )
@@ -84,7 +83,7 @@ func (b *NotificationSwitchButton) Handle(ctx tb.Context) error {
 	}
 
 	text := new(bytes.Buffer)
-	err = t.Execute(text, map[string]interface{}{"source": source, "sub": sub, "Count": config.ErrorThreshold})
+	err = t.Execute(text, feedSettingData(b.core, source, sub))
 	if err != nil {
 		// Log error, return generic message
 		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "notify_switch_err_generic")})
@@ -12,6 +12,7 @@ import (
 	"github.com/zintus/flowerss-bot/internal/core"
 	"github.com/zintus/flowerss-bot/internal/i18n"
 	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/model"
 	"github.com/zintus/flowerss-bot/internal/opml"
 
 	tb "gopkg.in/telebot.v3"
@@ -109,7 +110,7 @@ func (o *OnDocument) Handle(ctx tb.Context) error {
 				return
 			}
 
-			log.Infof("%d subscribe [%d]%s %s", ctx.Chat().ID, source.ID, source.Title, source.Link)
+			log.Infof("%d subscribe [%d]%s %s", ctx.Chat().ID, source.ID, source.Title, source.DisplayLink())
 			mu.Lock()
 			successImportList = append(successImportList, outline)
 			mu.Unlock()
@@ -124,10 +125,10 @@ func (o *OnDocument) Handle(ctx tb.Context) error {
 		for i, line := range successImportList {
 			if line.Text != "" {
 				msg.WriteString(
-					fmt.Sprintf("[%d] <a href=\"%s\">%s</a>\n", i+1, line.XMLURL, line.Text),
+					fmt.Sprintf("[%d] <a href=\"%s\">%s</a>\n", i+1, model.RedactLink(line.XMLURL), line.Text),
 				)
 			} else {
-				msg.WriteString(fmt.Sprintf("[%d] %s\n", i+1, line.XMLURL))
+				msg.WriteString(fmt.Sprintf("[%d] %s\n", i+1, model.RedactLink(line.XMLURL)))
 			}
 		}
 		msg.WriteString("\n")
@@ -137,9 +138,9 @@ func (o *OnDocument) Handle(ctx tb.Context) error {
 		msg.WriteString(i18n.Localize(langCode, "ondoc_import_failure_header"))
 		for i, line := range failImportList {
 			if line.Text != "" {
-				msg.WriteString(fmt.Sprintf("[%d] <a href=\"%s\">%s</a>\n", i+1, line.XMLURL, line.Text))
+				msg.WriteString(fmt.Sprintf("[%d] <a href=\"%s\">%s</a>\n", i+1, model.RedactLink(line.XMLURL), line.Text))
 			} else {
-				msg.WriteString(fmt.Sprintf("[%d] %s\n", i+1, line.XMLURL))
+				msg.WriteString(fmt.Sprintf("[%d] %s\n", i+1, model.RedactLink(line.XMLURL)))
 			}
 		}
 	}
@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/message"
+	"github.com/zintus/flowerss-bot/internal/bot/session"
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/log"
+)
+
+type Quiet struct {
+	core *core.Core
+}
+
+func NewQuiet(core *core.Core) *Quiet {
+	return &Quiet{core: core}
+}
+
+func (q *Quiet) Command() string {
+	return "/quiet"
+}
+
+func (q *Quiet) Description() string {
+	return i18n.Localize(util.DefaultLanguage, "quiet_command_desc")
+}
+
+func (q *Quiet) getMessageWithoutMention(ctx tb.Context) string {
+	mention := message.MentionFromMessage(ctx.Message())
+	if mention == "" {
+		return ctx.Message().Payload
+	}
+	return strings.ReplaceAll(ctx.Message().Payload, mention, "")
+}
+
+func (q *Quiet) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	userID := ctx.Chat().ID
+	mentionChat, _ := session.GetMentionChatFromCtxStore(ctx)
+	if mentionChat != nil {
+		userID = mentionChat.ID
+	}
+
+	args := strings.Fields(q.getMessageWithoutMention(ctx))
+	if len(args) == 0 {
+		return q.replySetting(ctx, langCode, userID)
+	}
+
+	// 第一个参数为时间段或 off，之后可以指定时区和处理方式
+	mode := core.QuietHold
+	var location *time.Location
+	for _, arg := range args[1:] {
+		switch core.QuietMode(strings.ToLower(arg)) {
+		case core.QuietHold, core.QuietSilent:
+			mode = core.QuietMode(strings.ToLower(arg))
+		default:
+			loc, err := core.LoadTimezone(arg)
+			if err != nil {
+				return ctx.Reply(i18n.Localize(langCode, "quiet_err_timezone", arg))
+			}
+			location = loc
+		}
+	}
+
+	var quiet *core.QuietHours
+	if strings.ToLower(args[0]) != "off" {
+		var err error
+		quiet, err = core.ParseQuietHours(args[0], mode)
+		if err != nil {
+			return ctx.Reply(i18n.Localize(langCode, "quiet_usage_hint"))
+		}
+	}
+
+	if err := q.core.SetUserQuietHours(context.Background(), userID, quiet, location); err != nil {
+		log.Errorf("set quiet hours failed, %v", err)
+		return ctx.Reply(i18n.Localize(langCode, "quiet_err_set_failed"))
+	}
+	return q.replySetting(ctx, langCode, userID)
+}
+
+// replySetting 回复当前的免打扰和时区设置
+func (q *Quiet) replySetting(ctx tb.Context, langCode string, userID int64) error {
+	window := i18n.Localize(langCode, "set_tmpl_status_off")
+	timezone := i18n.Localize(langCode, "quiet_timezone_default")
+	user, err := q.core.GetUser(context.Background(), userID)
+	if err == nil {
+		if quiet := core.UserQuietHours(user); quiet != nil {
+			window = i18n.Localize(langCode, "quiet_window_format", quiet.String(), quietModeText(langCode, quiet.Mode))
+		}
+		if user.Timezone != "" {
+			timezone = user.Timezone
+		}
+	}
+	return ctx.Reply(i18n.Localize(langCode, "quiet_show_format", window, timezone))
+}
+
+func (q *Quiet) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
+
+// quietModeText 免打扰处理方式的展示文本
+func quietModeText(langCode string, mode core.QuietMode) string {
+	if mode == core.QuietSilent {
+		return i18n.Localize(langCode, "quiet_mode_silent")
+	}
+	return i18n.Localize(langCode, "quiet_mode_hold")
+}
@@ -56,16 +56,16 @@ func (s *RemoveSubscription) removeForChannel(ctx tb.Context, channelName string
 		return ctx.Reply(i18n.Localize(langCode, "unsub_err_get_sub_info_failed"))
 	}
 
-	log.Infof("%d for [%d]%s unsubscribe %s", ctx.Chat().ID, source.ID, source.Title, source.Link)
+	log.Infof("%d for [%d]%s unsubscribe %s", ctx.Chat().ID, source.ID, source.Title, source.DisplayLink())
 	if err := s.core.Unsubscribe(context.Background(), channelChat.ID, source.ID); err != nil {
 		log.Errorf(
 			"%d for [%d]%s unsubscribe %s failed, %v",
-			ctx.Chat().ID, source.ID, source.Title, source.Link, err,
+			ctx.Chat().ID, source.ID, source.Title, source.DisplayLink(), err,
 		)
 		return ctx.Reply(i18n.Localize(langCode, "unsub_err_unsubscribe_failed"))
 	}
 	return ctx.Send(
-		i18n.Localize(langCode, "unsub_success_channel_format", channelChat.Title, channelChat.Username, source.Title, source.Link),
+		i18n.Localize(langCode, "unsub_success_channel_format", channelChat.Title, channelChat.Username, source.Title, source.DisplayLink()),
 		&tb.SendOptions{DisableWebPagePreview: true, ParseMode: tb.ModeMarkdown},
 	)
 }
@@ -113,16 +113,16 @@ func (s *RemoveSubscription) removeForChat(ctx tb.Context) error {
 		return ctx.Reply(i18n.Localize(langCode, "unsub_err_not_subscribed_feed"))
 	}
 
-	log.Infof("%d unsubscribe [%d]%s %s", ctx.Chat().ID, source.ID, source.Title, source.Link)
+	log.Infof("%d unsubscribe [%d]%s %s", ctx.Chat().ID, source.ID, source.Title, source.DisplayLink())
 	if err := s.core.Unsubscribe(context.Background(), ctx.Chat().ID, source.ID); err != nil {
 		log.Errorf(
 			"%d for [%d]%s unsubscribe %s failed, %v",
-			ctx.Chat().ID, source.ID, source.Title, source.Link, err,
+			ctx.Chat().ID, source.ID, source.Title, source.DisplayLink(), err,
 		)
 		return ctx.Reply(i18n.Localize(langCode, "unsub_err_unsubscribe_failed"))
 	}
 	return ctx.Send(
-		i18n.Localize(langCode, "unsub_success_user_format", source.Title, source.Link),
+		i18n.Localize(langCode, "unsub_success_user_format", source.Title, source.DisplayLink()),
 		&tb.SendOptions{DisableWebPagePreview: true, ParseMode: tb.ModeMarkdown},
 	)
 }
@@ -182,7 +182,7 @@ func (r *RemoveSubscriptionItemButton) Handle(ctx tb.Context) error {
 		return ctx.Edit(i18n.Localize(langCode, "unsub_err_button_action_failed"))
 	}
 
-	rtnMsg := i18n.Localize(langCode, "unsub_success_button_format", sourceID, source.Link, source.Title)
+	rtnMsg := i18n.Localize(langCode, "unsub_success_button_format", sourceID, source.DisplayLink(), source.Title)
 	return ctx.Edit(rtnMsg, &tb.SendOptions{ParseMode: tb.ModeHTML})
 }
 
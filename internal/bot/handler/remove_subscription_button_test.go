@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	tb "gopkg.in/telebot.v3"
 
@@ -50,6 +51,9 @@ func (m *mockSourceStorage) GetSources(ctx context.Context) ([]*model.Source, er
 func (m *mockSourceStorage) GetSourceByURL(ctx context.Context, url string) (*model.Source, error) {
 	panic("not implemented")
 }
+func (m *mockSourceStorage) GetSourceByURLKey(ctx context.Context, key string) (*model.Source, error) {
+	panic("not implemented")
+}
 func (m *mockSourceStorage) Delete(ctx context.Context, id uint) error {
 	if m.deleteFunc != nil {
 		return m.deleteFunc(ctx, id)
@@ -89,6 +93,9 @@ func (m *mockSubscriptionStorage) GetSubscriptionsBySourceID(ctx context.Context
 func (m *mockSubscriptionStorage) CountSubscriptions(ctx context.Context) (int64, error) {
 	panic("not implemented")
 }
+func (m *mockSubscriptionStorage) GetSubscriberIDs(ctx context.Context) ([]int64, error) {
+	panic("not implemented")
+}
 func (m *mockSubscriptionStorage) DeleteSubscription(ctx context.Context, userID int64, sourceID uint) (int64, error) {
 	if m.deleteFunc != nil {
 		return m.deleteFunc(ctx, userID, sourceID)
@@ -119,9 +126,24 @@ func (m *mockContentStorage) AddContent(ctx context.Context, content *model.Cont
 func (m *mockContentStorage) DeleteSourceContents(ctx context.Context, sourceID uint) (int64, error) {
 	return 0, nil
 }
+func (m *mockContentStorage) MoveSourceContents(ctx context.Context, fromSourceID, toSourceID uint) (int64, error) {
+	return 0, nil
+}
 func (m *mockContentStorage) HashIDExist(ctx context.Context, hashID string) (bool, error) {
 	return false, nil
 }
+func (m *mockContentStorage) GetSourceContentsSince(ctx context.Context, sourceID uint, since time.Time) ([]*model.Content, error) {
+	return nil, nil
+}
+func (m *mockContentStorage) GetContentsByHashIDs(ctx context.Context, hashIDs []string) ([]*model.Content, error) {
+	return nil, nil
+}
+func (m *mockContentStorage) GetSourceLatestContents(ctx context.Context, sourceID uint, limit int) ([]*model.Content, error) {
+	return nil, nil
+}
+func (m *mockContentStorage) StripContentsBefore(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
 
 // dummy user storage
 type mockUserStorage struct{}
@@ -134,6 +156,15 @@ func (m *mockUserStorage) GetUser(ctx context.Context, id int64) (*model.User, e
 func (m *mockUserStorage) SetUserLanguage(ctx context.Context, userID int64, langCode string) error {
 	return nil
 }
+func (m *mockUserStorage) SetUserQuietHours(ctx context.Context, user *model.User) error {
+	return nil
+}
+func (m *mockUserStorage) SetUserDigest(ctx context.Context, userID int64, digest string) error {
+	return nil
+}
+func (m *mockUserStorage) CountUsers(ctx context.Context) (int64, error) {
+	return 0, nil
+}
 
 func TestRemoveSubscriptionItemButton_Handle(t *testing.T) {
 	i18n.ResetTranslationsForTest()
@@ -161,7 +192,7 @@ func TestRemoveSubscriptionItemButton_Handle(t *testing.T) {
 		},
 		countFunc: func(ctx context.Context, s uint) (int64, error) { return 1, nil },
 	}
-	c := core.NewCore(&mockUserStorage{}, &mockContentStorage{}, mockSrc, mockSub, nil, nil)
+	c := core.NewCore(&mockUserStorage{}, &mockContentStorage{}, mockSrc, mockSub, nil, nil, nil, nil)
 
 	bot, err := tb.NewBot(tb.Settings{Token: "TEST", Offline: true})
 	if err != nil {
@@ -223,7 +254,7 @@ func TestRemoveSubscriptionItemButton_LastSubscriber(t *testing.T) {
 	mockSrc.deleteFunc = func(ctx context.Context, id uint) error {
 		return fmt.Errorf("simulated source delete error")
 	}
-	c := core.NewCore(&mockUserStorage{}, &mockContentStorage{}, mockSrc, mockSub, nil, nil)
+	c := core.NewCore(&mockUserStorage{}, &mockContentStorage{}, mockSrc, mockSub, nil, nil, nil, nil)
 
 	bot, err := tb.NewBot(tb.Settings{Token: "TEST", Offline: true})
 	if err != nil {
@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+	"unicode/utf8"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/message"
+	"github.com/zintus/flowerss-bot/internal/bot/session"
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/log"
+)
+
+const (
+	// searchPageSize 每页展示的搜索结果数
+	searchPageSize = 10
+	// searchTitleMaxLen 搜索结果标题的最大展示长度
+	searchTitleMaxLen = 150
+)
+
+type Search struct {
+	core *core.Core
+}
+
+func NewSearch(core *core.Core) *Search {
+	return &Search{core: core}
+}
+
+func (s *Search) Command() string {
+	return "/search"
+}
+
+func (s *Search) Description() string {
+	return i18n.Localize(util.DefaultLanguage, "search_command_desc")
+}
+
+func (s *Search) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	query := searchQueryFromMessage(ctx.Message())
+	if query == "" {
+		return ctx.Reply(i18n.Localize(langCode, "search_usage_hint"))
+	}
+
+	subscribeUserID := ctx.Chat().ID
+	mentionChat, _ := session.GetMentionChatFromCtxStore(ctx)
+	if mentionChat != nil {
+		subscribeUserID = mentionChat.ID
+	}
+
+	text, markup, err := renderSearchPage(s.core, langCode, subscribeUserID, query, 0)
+	if err != nil {
+		log.Errorf("search contents failed, %v", err)
+		return ctx.Reply(i18n.Localize(langCode, "search_err_failed"))
+	}
+	// 结果回复在搜索命令下，翻页时从被回复的消息中取回搜索语句
+	return ctx.Reply(
+		text, &tb.SendOptions{ParseMode: tb.ModeHTML, DisableWebPagePreview: true, ReplyMarkup: markup},
+	)
+}
+
+func (s *Search) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
+
+// searchQueryFromMessage 从 /search 命令消息中取出搜索语句，去掉命令和频道 mention
+func searchQueryFromMessage(m *tb.Message) string {
+	if m == nil {
+		return ""
+	}
+	text := m.Text
+	if fields := strings.Fields(text); len(fields) > 0 && strings.HasPrefix(fields[0], "/") {
+		text = strings.TrimPrefix(strings.TrimSpace(text), fields[0])
+	}
+	if mention := message.MentionFromMessage(m); mention != "" {
+		text = strings.ReplaceAll(text, mention, "")
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// renderSearchPage 搜索 userID 订阅的文章，生成第 page 页（从 0 开始）的消息和翻页按钮
+func renderSearchPage(
+	appCore *core.Core, langCode string, userID int64, query string, page int,
+) (string, *tb.ReplyMarkup, error) {
+	result, err := appCore.SearchContents(context.Background(), userID, query, page*searchPageSize, searchPageSize)
+	if err != nil {
+		return "", nil, err
+	}
+	if result.Total == 0 {
+		return i18n.Localize(langCode, "search_info_no_result", html.EscapeString(query)), nil, nil
+	}
+
+	pages := int((result.Total + searchPageSize - 1) / searchPageSize)
+	var msg strings.Builder
+	msg.WriteString(
+		i18n.Localize(langCode, "search_result_header_format", html.EscapeString(query), result.Total, page+1, pages),
+	)
+	msg.WriteString("\n")
+	for _, content := range result.Contents {
+		title := content.Title
+		if title == "" {
+			title = content.RawLink
+		}
+		if utf8.RuneCountInString(title) > searchTitleMaxLen {
+			title = string([]rune(title)[:searchTitleMaxLen]) + "…"
+		}
+		msg.WriteString(
+			fmt.Sprintf("\n• <a href=\"%s\">%s</a>", html.EscapeString(content.RawLink), html.EscapeString(title)),
+		)
+		if source, ok := result.Sources[content.SourceID]; ok {
+			msg.WriteString(" · " + html.EscapeString(source.Title))
+		}
+		if content.PublishedAt != nil {
+			msg.WriteString(" · " + content.PublishedAt.Format("2006-01-02"))
+		}
+	}
+
+	var row []tb.InlineButton
+	if page > 0 {
+		row = append(
+			row, tb.InlineButton{
+				Unique: SearchPageButtonUnique,
+				Text:   i18n.Localize(langCode, "search_btn_prev"),
+				Data:   searchPageData(userID, page-1),
+			},
+		)
+	}
+	if page+1 < pages {
+		row = append(
+			row, tb.InlineButton{
+				Unique: SearchPageButtonUnique,
+				Text:   i18n.Localize(langCode, "search_btn_next"),
+				Data:   searchPageData(userID, page+1),
+			},
+		)
+	}
+	var markup *tb.ReplyMarkup
+	if len(row) > 0 {
+		markup = &tb.ReplyMarkup{InlineKeyboard: [][]tb.InlineButton{row}}
+	}
+	return msg.String(), markup, nil
+}
+
+// searchPageData 翻页按钮的数据：搜索的 chat id 和页码
+func searchPageData(userID int64, page int) string {
+	return fmt.Sprintf("%d|%d", userID, page)
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"strconv"
+	"strings"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/chat"
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/log"
+)
+
+// SearchPageButtonUnique is defined in common.go
+
+type SearchPageButton struct {
+	bot  *tb.Bot
+	core *core.Core
+}
+
+func NewSearchPageButton(bot *tb.Bot, core *core.Core) *SearchPageButton {
+	return &SearchPageButton{bot: bot, core: core}
+}
+
+func (b *SearchPageButton) CallbackUnique() string {
+	return "\f" + SearchPageButtonUnique
+}
+
+func (b *SearchPageButton) Description() string {
+	return ""
+}
+
+func (b *SearchPageButton) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	c := ctx.Callback()
+	parts := strings.Split(c.Data, "|")
+	if len(parts) != 2 {
+		return ctx.Edit(i18n.Localize(langCode, "err_system_error"))
+	}
+	userID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return ctx.Edit(i18n.Localize(langCode, "err_system_error"))
+	}
+	page, err := strconv.Atoi(parts[1])
+	if err != nil || page < 0 {
+		return ctx.Edit(i18n.Localize(langCode, "err_system_error"))
+	}
+
+	// 搜索其他 chat（频道）的订阅时需要是该 chat 的管理员
+	if userID != ctx.Chat().ID {
+		channelChat, err := b.bot.ChatByID(userID)
+		if err != nil || !chat.IsChatAdmin(b.bot, channelChat, c.Sender.ID) {
+			return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "err_permission_denied")})
+		}
+	}
+
+	query := ""
+	if c.Message != nil {
+		query = searchQueryFromMessage(c.Message.ReplyTo)
+	}
+	if query == "" {
+		return ctx.Edit(i18n.Localize(langCode, "search_err_expired"))
+	}
+
+	text, markup, err := renderSearchPage(b.core, langCode, userID, query, page)
+	if err != nil {
+		log.Errorf("search contents failed, %v", err)
+		return ctx.Respond(&tb.CallbackResponse{Text: i18n.Localize(langCode, "search_err_failed")})
+	}
+	return ctx.Edit(text, &tb.SendOptions{ParseMode: tb.ModeHTML, DisableWebPagePreview: true, ReplyMarkup: markup})
+}
+
+func (b *SearchPageButton) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
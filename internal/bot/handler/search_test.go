@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"testing"
+
+	tb "gopkg.in/telebot.v3"
+)
+
+func TestSearchQueryFromMessage(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *tb.Message
+		want string
+	}{
+		{name: "nil", msg: nil, want: ""},
+		{name: "command only", msg: &tb.Message{Text: "/search"}, want: ""},
+		{name: "keywords", msg: &tb.Message{Text: "/search  go   generics "}, want: "go generics"},
+		{name: "bot name", msg: &tb.Message{Text: "/search@flowerss_bot 全文 搜索"}, want: "全文 搜索"},
+		{
+			name: "channel mention",
+			msg: &tb.Message{
+				Text:     "/search @channel go",
+				Entities: tb.Entities{{Type: tb.EntityMention, Offset: 8, Length: 8}},
+			},
+			want: "go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				if got := searchQueryFromMessage(tt.msg); got != tt.want {
+					t.Errorf("searchQueryFromMessage() = %q, want %q", got, tt.want)
+				}
+			},
+		)
+	}
+}
+
+func TestSearchPageData(t *testing.T) {
+	if got := searchPageData(-1001234567890, 3); got != "-1001234567890|3" {
+		t.Errorf("searchPageData() = %q", got)
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"github.com/zintus/flowerss-bot/internal/bot/chat"
 	"github.com/zintus/flowerss-bot/internal/bot/session"
 	"github.com/zintus/flowerss-bot/internal/bot/util"
-	"github.com/zintus/flowerss-bot/internal/config"
 	"github.com/zintus/flowerss-bot/internal/core"
 	"github.com/zintus/flowerss-bot/internal/i18n"
 )
@@ -145,7 +144,7 @@ func (r *SetFeedItemButton) Handle(ctx tb.Context) error {
 	}
 
 	text := new(bytes.Buffer)
-	err = t.Execute(text, map[string]interface{}{"source": source, "sub": sub, "Count": config.ErrorThreshold})
+	err = t.Execute(text, feedSettingData(r.core, source, sub))
 	if err != nil {
 		// Log error, return generic message
 		return ctx.Edit(i18n.Localize(langCode, "set_err_button_settings_error"))
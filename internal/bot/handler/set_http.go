@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"html"
+	"net/textproto"
+	"strings"
+
+	"github.com/spf13/cast"
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/chat"
+	"github.com/zintus/flowerss-bot/internal/bot/message"
+	"github.com/zintus/flowerss-bot/internal/bot/session"
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+var errInvalidHeader = errors.New("invalid header")
+
+// forbiddenHeaders 不允许自定义的请求头
+var forbiddenHeaders = map[string]bool{
+	"Host":              true,
+	"Content-Length":    true,
+	"If-None-Match":     true,
+	"If-Modified-Since": true,
+}
+
+// SetHTTP 设置订阅源的自定义请求头、basic auth 和 User-Agent
+type SetHTTP struct {
+	core *core.Core
+}
+
+func NewSetHTTP(core *core.Core) *SetHTTP {
+	return &SetHTTP{core: core}
+}
+
+func (s *SetHTTP) Command() string {
+	return "/sethttp"
+}
+
+func (s *SetHTTP) Description() string {
+	return i18n.Localize(util.DefaultLanguage, "sethttp_command_desc")
+}
+
+func (s *SetHTTP) getMessageWithoutMention(ctx tb.Context) string {
+	mention := message.MentionFromMessage(ctx.Message())
+	if mention == "" {
+		return ctx.Message().Payload
+	}
+	return strings.ReplaceAll(ctx.Message().Payload, mention, "")
+}
+
+func (s *SetHTTP) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	args := strings.SplitN(strings.TrimSpace(s.getMessageWithoutMention(ctx)), " ", 3)
+	if args[0] == "" {
+		return ctx.Reply(i18n.Localize(langCode, "sethttp_usage_hint"), &tb.SendOptions{ParseMode: tb.ModeHTML})
+	}
+
+	sourceID := cast.ToUint(args[0])
+	subscribeUserID := ctx.Chat().ID
+	mentionChat, _ := session.GetMentionChatFromCtxStore(ctx)
+	if mentionChat != nil {
+		subscribeUserID = mentionChat.ID
+	}
+
+	if _, err := s.core.GetSubscription(context.Background(), subscribeUserID, sourceID); err != nil {
+		if errors.Is(err, core.ErrSubscriptionNotExist) {
+			return ctx.Reply(i18n.Localize(langCode, "sethttp_err_not_subscribed"))
+		}
+		log.Errorf("get subscription failed, %v", err)
+		return ctx.Reply(i18n.Localize(langCode, "sethttp_err_set_failed"))
+	}
+	source, err := s.core.GetSource(context.Background(), sourceID)
+	if err != nil {
+		log.Errorf("get source %d failed, %v", sourceID, err)
+		return ctx.Reply(i18n.Localize(langCode, "sethttp_err_set_failed"))
+	}
+
+	action := ""
+	if len(args) > 1 {
+		action = strings.ToLower(args[1])
+	}
+	value := ""
+	if len(args) > 2 {
+		value = strings.TrimSpace(args[2])
+	}
+	if action == "" {
+		return ctx.Reply(formatRequestSettings(source, langCode), &tb.SendOptions{ParseMode: tb.ModeHTML})
+	}
+
+	settings, err := updateRequestSettings(source.RequestSettings, action, value)
+	if err != nil {
+		if errors.Is(err, errInvalidHeader) {
+			return ctx.Reply(i18n.Localize(langCode, "sethttp_err_invalid_header"))
+		}
+		return ctx.Reply(i18n.Localize(langCode, "sethttp_usage_hint"), &tb.SendOptions{ParseMode: tb.ModeHTML})
+	}
+
+	// 消息中可能有凭据，设置后尽量删除
+	if value != "" {
+		if err := ctx.Delete(); err != nil {
+			log.Debugf("delete sethttp message failed, %v", err)
+		}
+	}
+
+	source, err = s.core.SetSourceRequestSettings(context.Background(), subscribeUserID, sourceID, settings)
+	if err != nil {
+		log.Errorf("set source %d request settings failed, %v", sourceID, err)
+		return ctx.Send(i18n.Localize(langCode, "sethttp_err_set_failed"))
+	}
+	log.Infof("%d set request settings of source %d, now source %d", subscribeUserID, sourceID, source.ID)
+	return ctx.Send(formatRequestSettings(source, langCode), &tb.SendOptions{ParseMode: tb.ModeHTML})
+}
+
+func (s *SetHTTP) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
+
+// updateRequestSettings 根据命令修改请求设置，返回修改后的副本
+func updateRequestSettings(current model.RequestSettings, action, value string) (model.RequestSettings, error) {
+	settings := current
+	settings.Headers = make(map[string]string, len(current.Headers))
+	for name, v := range current.Headers {
+		settings.Headers[name] = v
+	}
+
+	switch action {
+	case "clear":
+		return model.RequestSettings{}, nil
+	case "header":
+		name, headerValue, _ := strings.Cut(value, ":")
+		name = textproto.CanonicalMIMEHeaderKey(strings.TrimSpace(name))
+		headerValue = strings.TrimSpace(headerValue)
+		if !validHeaderName(name) || strings.ContainsAny(headerValue, "\r\n") {
+			return current, errInvalidHeader
+		}
+		if name == "User-Agent" {
+			settings.UserAgent = headerValue
+		} else if headerValue == "" {
+			delete(settings.Headers, name)
+		} else {
+			settings.Headers[name] = headerValue
+		}
+	case "bearer":
+		if value == "" {
+			delete(settings.Headers, "Authorization")
+		} else {
+			settings.Headers["Authorization"] = "Bearer " + value
+		}
+	case "cookie":
+		if value == "" {
+			delete(settings.Headers, "Cookie")
+		} else {
+			settings.Headers["Cookie"] = value
+		}
+	case "auth":
+		user, password, _ := strings.Cut(value, " ")
+		settings.BasicAuthUser = user
+		settings.BasicAuthPassword = strings.TrimSpace(password)
+	case "ua":
+		settings.UserAgent = value
+	default:
+		return current, errors.New("unknown action")
+	}
+	if strings.ContainsAny(settings.UserAgent, "\r\n") {
+		return current, errInvalidHeader
+	}
+	if len(settings.Headers) == 0 {
+		settings.Headers = nil
+	}
+	return settings, nil
+}
+
+// validHeaderName 请求头名称只能包含 token 字符
+func validHeaderName(name string) bool {
+	if name == "" || forbiddenHeaders[name] {
+		return false
+	}
+	for _, r := range name {
+		if r > 0x7e || r <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// formatRequestSettings 订阅源请求设置的展示文本，凭据打码，HTML 格式
+func formatRequestSettings(source *model.Source, langCode string) string {
+	var b strings.Builder
+	b.WriteString(i18n.Localize(langCode, "sethttp_header_format", source.ID, html.EscapeString(source.Title)))
+	settings := source.RequestSettings.Masked()
+	if settings.IsEmpty() {
+		b.WriteString("\n" + i18n.Localize(langCode, "sethttp_info_default"))
+		return b.String()
+	}
+	for _, name := range settings.HeaderNames() {
+		b.WriteString("\n" + html.EscapeString(name+": "+settings.Headers[name]))
+	}
+	if settings.BasicAuthUser != "" || settings.BasicAuthPassword != "" {
+		b.WriteString(
+			"\n" + i18n.Localize(
+				langCode, "sethttp_label_basic_auth",
+				html.EscapeString(settings.BasicAuthUser), html.EscapeString(settings.BasicAuthPassword),
+			),
+		)
+	}
+	if settings.UserAgent != "" {
+		b.WriteString("\n" + i18n.Localize(langCode, "sethttp_label_user_agent", html.EscapeString(settings.UserAgent)))
+	}
+	return b.String()
+}
+
+// SetHTTPButton /set 中的请求设置按钮，展示当前设置和修改方法
+type SetHTTPButton struct {
+	bot  *tb.Bot
+	core *core.Core
+}
+
+func NewSetHTTPButton(bot *tb.Bot, core *core.Core) *SetHTTPButton {
+	return &SetHTTPButton{bot: bot, core: core}
+}
+
+func (b *SetHTTPButton) CallbackUnique() string {
+	return "\f" + SetHTTPButtonUnique
+}
+
+func (b *SetHTTPButton) Description() string {
+	return ""
+}
+
+func (b *SetHTTPButton) Handle(ctx tb.Context) error {
+	langCode := util.GetLangCode(ctx)
+	c := ctx.Callback()
+	attachData, err := session.UnmarshalAttachment(c.Data)
+	if err != nil {
+		return ctx.Edit(i18n.Localize(langCode, "err_system_error"))
+	}
+
+	subscriberID := attachData.GetUserId()
+	if subscriberID != c.Sender.ID {
+		channelChat, err := b.bot.ChatByID(subscriberID)
+		if err != nil || !chat.IsChatAdmin(b.bot, channelChat, c.Sender.ID) {
+			return ctx.Send(i18n.Localize(langCode, "err_permission_denied"))
+		}
+	}
+
+	source, err := b.core.GetSource(context.Background(), uint(attachData.GetSourceId()))
+	if err != nil {
+		return ctx.Edit(i18n.Localize(langCode, "err_system_error"))
+	}
+	msg := formatRequestSettings(source, langCode) + "\n\n" + i18n.Localize(langCode, "sethttp_usage_hint")
+	return ctx.Edit(msg, &tb.SendOptions{ParseMode: tb.ModeHTML})
+}
+
+func (b *SetHTTPButton) Middlewares() []tb.MiddlewareFunc {
+	return nil
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+func TestUpdateRequestSettings(t *testing.T) {
+	current := model.RequestSettings{Headers: map[string]string{"X-Token": "a"}}
+
+	got, err := updateRequestSettings(current, "header", "x-feed-key:  abc ")
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"X-Token": "a", "X-Feed-Key": "abc"}, got.Headers)
+	// 原设置不受影响
+	assert.Equal(t, map[string]string{"X-Token": "a"}, current.Headers)
+
+	got, err = updateRequestSettings(current, "header", "X-Token:")
+	assert.Nil(t, err)
+	assert.Nil(t, got.Headers)
+
+	got, err = updateRequestSettings(current, "header", "User-Agent: reader/1.0")
+	assert.Nil(t, err)
+	assert.Equal(t, "reader/1.0", got.UserAgent)
+
+	got, err = updateRequestSettings(current, "bearer", "tok")
+	assert.Nil(t, err)
+	assert.Equal(t, "Bearer tok", got.Headers["Authorization"])
+
+	got, err = updateRequestSettings(current, "cookie", "session=1")
+	assert.Nil(t, err)
+	assert.Equal(t, "session=1", got.Headers["Cookie"])
+
+	got, err = updateRequestSettings(current, "auth", "user pass word")
+	assert.Nil(t, err)
+	assert.Equal(t, "user", got.BasicAuthUser)
+	assert.Equal(t, "pass word", got.BasicAuthPassword)
+
+	got, err = updateRequestSettings(current, "clear", "")
+	assert.Nil(t, err)
+	assert.True(t, got.IsEmpty())
+
+	for _, value := range []string{"Bad Name: x", "Host: example.com", ": x", "X-A: b\r\nX-B: c"} {
+		_, err = updateRequestSettings(current, "header", value)
+		assert.ErrorIs(t, err, errInvalidHeader, value)
+	}
+	_, err = updateRequestSettings(current, "unknown", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,139 @@
+package bot
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+const (
+	// mediaCaptionLimit telegram 媒体消息说明的最大长度
+	mediaCaptionLimit = 1024
+	// mediaGroupLimit telegram 一组媒体的最大数量
+	mediaGroupLimit = 10
+	// photoSizeLimit telegram 通过 URL 发送图片的大小上限
+	photoSizeLimit = 5 << 20
+	// fileSizeLimit telegram 通过 URL 发送音频、视频的大小上限
+	fileSizeLimit = 20 << 20
+)
+
+// errNoMedia 文章没有可以作为媒体发送的附件，或者说明过长
+var errNoMedia = errors.New("no media to send")
+
+// mediaKind 附件对应的 telegram 媒体类型
+type mediaKind string
+
+const (
+	mediaPhoto mediaKind = "photo"
+	mediaAudio mediaKind = "audio"
+	mediaVideo mediaKind = "video"
+)
+
+// mediaExtensions 附件没有 MIME 类型时按扩展名判断媒体类型
+var mediaExtensions = map[string]mediaKind{
+	".jpg":  mediaPhoto,
+	".jpeg": mediaPhoto,
+	".png":  mediaPhoto,
+	".gif":  mediaPhoto,
+	".webp": mediaPhoto,
+	".mp3":  mediaAudio,
+	".m4a":  mediaAudio,
+	".ogg":  mediaAudio,
+	".mp4":  mediaVideo,
+	".mov":  mediaVideo,
+	".webm": mediaVideo,
+}
+
+// contentMedia 选出文章中作为媒体发送的附件，音频优先，其次是视频，最后是图片，
+// 多张图片作为一组发送，超过大小限制的附件会被忽略
+func contentMedia(content *model.Content) (mediaKind, []string) {
+	byKind := map[mediaKind][]string{}
+	for _, enclosure := range content.Enclosures {
+		kind := enclosureKind(enclosure)
+		if kind == "" {
+			continue
+		}
+		limit := int64(fileSizeLimit)
+		if kind == mediaPhoto {
+			limit = photoSizeLimit
+		}
+		if size, err := strconv.ParseInt(enclosure.Length, 10, 64); err == nil && size > limit {
+			continue
+		}
+		byKind[kind] = append(byKind[kind], enclosure.URL)
+	}
+
+	for _, kind := range []mediaKind{mediaAudio, mediaVideo} {
+		if urls := byKind[kind]; len(urls) > 0 {
+			return kind, urls[:1]
+		}
+	}
+	if urls := byKind[mediaPhoto]; len(urls) > 0 {
+		if len(urls) > mediaGroupLimit {
+			urls = urls[:mediaGroupLimit]
+		}
+		return mediaPhoto, urls
+	}
+	return "", nil
+}
+
+// enclosureKind 按 MIME 类型判断附件的媒体类型，没有类型时按扩展名判断，不支持的附件返回空
+func enclosureKind(enclosure model.Enclosure) mediaKind {
+	mimeType := strings.ToLower(enclosure.Type)
+	switch {
+	case strings.HasPrefix(mimeType, "image"):
+		return mediaPhoto
+	case strings.HasPrefix(mimeType, "audio"):
+		return mediaAudio
+	case strings.HasPrefix(mimeType, "video"):
+		return mediaVideo
+	case mimeType != "":
+		return ""
+	}
+
+	u, err := url.Parse(enclosure.URL)
+	if err != nil {
+		return ""
+	}
+	return mediaExtensions[strings.ToLower(path.Ext(u.Path))]
+}
+
+// sendMedia 以 telegram 媒体发送文章附件，caption 为渲染后的推送消息，
+// 没有可发送的附件或说明过长时返回 errNoMedia，一组图片不支持按钮
+func (b *Bot) sendMedia(
+	to tb.Recipient, content *model.Content, caption string, opts *tb.SendOptions, markup *tb.ReplyMarkup,
+) error {
+	if utf8.RuneCountInString(caption) > mediaCaptionLimit {
+		return errNoMedia
+	}
+	kind, urls := contentMedia(content)
+	if len(urls) == 0 {
+		return errNoMedia
+	}
+
+	var what tb.Sendable
+	switch {
+	case kind == mediaAudio:
+		what = &tb.Audio{File: tb.FromURL(urls[0]), Caption: caption}
+	case kind == mediaVideo:
+		what = &tb.Video{File: tb.FromURL(urls[0]), Caption: caption}
+	case len(urls) == 1:
+		what = &tb.Photo{File: tb.FromURL(urls[0]), Caption: caption}
+	default:
+		album := make(tb.Album, 0, len(urls))
+		for _, u := range urls {
+			album = append(album, &tb.Photo{File: tb.FromURL(u)})
+		}
+		album.SetCaption(caption)
+		return util.BotSendAlbumWithRetry(b.tb, to, album, opts)
+	}
+	return util.BotSendWithRetry(b.tb, to, what, opts, markup)
+}
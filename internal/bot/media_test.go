@@ -0,0 +1,76 @@
+package bot
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+func TestContentMedia(t *testing.T) {
+	t.Run("audio before video and photos", func(t *testing.T) {
+		content := &model.Content{
+			Enclosures: model.Enclosures{
+				{URL: "https://example.com/a.jpg", Type: "image/jpeg"},
+				{URL: "https://example.com/b.mp4", Type: "video/mp4"},
+				{URL: "https://example.com/c.mp3", Type: "audio/mpeg"},
+			},
+		}
+		kind, urls := contentMedia(content)
+		assert.Equal(t, mediaAudio, kind)
+		assert.Equal(t, []string{"https://example.com/c.mp3"}, urls)
+	})
+
+	t.Run("skip oversized enclosures", func(t *testing.T) {
+		content := &model.Content{
+			Enclosures: model.Enclosures{
+				{URL: "https://example.com/c.mp3", Type: "audio/mpeg", Length: fmt.Sprint(fileSizeLimit + 1)},
+				{URL: "https://example.com/a.jpg", Type: "image/jpeg", Length: fmt.Sprint(photoSizeLimit)},
+			},
+		}
+		kind, urls := contentMedia(content)
+		assert.Equal(t, mediaPhoto, kind)
+		assert.Equal(t, []string{"https://example.com/a.jpg"}, urls)
+	})
+
+	t.Run("photos are capped to one album", func(t *testing.T) {
+		content := &model.Content{}
+		for i := 0; i < mediaGroupLimit+3; i++ {
+			content.Enclosures = append(
+				content.Enclosures, model.Enclosure{URL: fmt.Sprintf("https://example.com/%d.png", i)},
+			)
+		}
+		kind, urls := contentMedia(content)
+		assert.Equal(t, mediaPhoto, kind)
+		assert.Len(t, urls, mediaGroupLimit)
+	})
+
+	t.Run("no media", func(t *testing.T) {
+		content := &model.Content{
+			Enclosures: model.Enclosures{{URL: "https://example.com/a.pdf", Type: "application/pdf"}},
+		}
+		kind, urls := contentMedia(content)
+		assert.Equal(t, mediaKind(""), kind)
+		assert.Empty(t, urls)
+	})
+}
+
+func TestEnclosureKind(t *testing.T) {
+	tests := []struct {
+		enclosure model.Enclosure
+		want      mediaKind
+	}{
+		{model.Enclosure{URL: "https://example.com/a", Type: "image"}, mediaPhoto},
+		{model.Enclosure{URL: "https://example.com/a", Type: "Audio/MPEG"}, mediaAudio},
+		{model.Enclosure{URL: "https://example.com/a", Type: "video/mp4"}, mediaVideo},
+		{model.Enclosure{URL: "https://example.com/a.mp3", Type: "application/octet-stream"}, ""},
+		{model.Enclosure{URL: "https://example.com/a.MP4?x=1"}, mediaVideo},
+		{model.Enclosure{URL: "https://example.com/a.webp"}, mediaPhoto},
+		{model.Enclosure{URL: "https://example.com/a.txt"}, ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, enclosureKind(tt.enclosure), tt.enclosure.URL)
+	}
+}
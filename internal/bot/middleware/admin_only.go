@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/bot/util"
+	"github.com/zintus/flowerss-bot/internal/config"
+	"github.com/zintus/flowerss-bot/internal/i18n"
+)
+
+// AdminOnly 只允许 admin_users 中的用户使用
+func AdminOnly() tb.MiddlewareFunc {
+	return func(next tb.HandlerFunc) tb.HandlerFunc {
+		return func(c tb.Context) error {
+			if c.Sender() == nil || !config.IsAdmin(c.Sender().ID) {
+				return c.Reply(i18n.Localize(util.GetLangCode(c), "middleware_err_not_admin"))
+			}
+			return next(c)
+		}
+	}
+}
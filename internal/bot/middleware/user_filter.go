@@ -15,6 +15,9 @@ func UserFilter() tb.MiddlewareFunc {
 				return next(c)
 			}
 			userID := c.Sender().ID
+			if config.IsAdmin(userID) {
+				return next(c)
+			}
 			for _, allowUserID := range config.AllowUsers {
 				if allowUserID == userID {
 					return next(c)
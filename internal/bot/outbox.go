@@ -0,0 +1,57 @@
+package bot
+
+import (
+	"context"
+	"time"
+
+	"github.com/zintus/flowerss-bot/internal/log"
+)
+
+const (
+	// outboxTick 没有新内容时检查待重试推送的周期
+	outboxTick = 30 * time.Second
+	// outboxBatchSize 每次从 outbox 取出的推送数量
+	outboxBatchSize = 100
+	// outboxRetention 已结束的推送记录保留时间
+	outboxRetention = 7 * 24 * time.Hour
+	// outboxPurgeInterval 清理已结束推送记录的周期
+	outboxPurgeInterval = time.Hour
+)
+
+// runOutbox 发送 outbox 中的推送，启动时先发送上次退出前未发送的推送，ctx 结束后关闭 outboxDone 并退出
+func (b *Bot) runOutbox(ctx context.Context) {
+	defer close(b.outboxDone)
+	ticker := time.NewTicker(outboxTick)
+	defer ticker.Stop()
+
+	var lastPurge time.Time
+	for {
+		b.BroadcastNews(ctx)
+
+		if ctx.Err() == nil && time.Since(lastPurge) >= outboxPurgeInterval {
+			lastPurge = time.Now()
+			n, err := b.core.PurgeDeliveries(ctx, lastPurge.Add(-outboxRetention))
+			if err != nil {
+				log.Errorf("purge deliveries failed, %v", err)
+			} else if n > 0 {
+				log.Infof("purged %d finished deliveries", n)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Info("outbox stopped")
+			return
+		case <-b.outboxKick:
+		case <-ticker.C:
+		}
+	}
+}
+
+// kickOutbox 唤醒 outbox 立即发送，不阻塞
+func (b *Bot) kickOutbox() {
+	select {
+	case b.outboxKick <- struct{}{}:
+	default:
+	}
+}
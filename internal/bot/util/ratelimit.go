@@ -2,11 +2,14 @@ package util
 
 import (
 	"errors"
+	"net"
+	"strings"
 	"time"
 
 	tb "gopkg.in/telebot.v3"
 
 	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/metrics"
 )
 
 const (
@@ -114,6 +117,7 @@ func BotSendWithRetry(bot *tb.Bot, to tb.Recipient, what interface{}, opts ...in
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		_, err := bot.Send(to, what, opts...)
 		if err == nil {
+			metrics.MessagesSent.Inc()
 			return nil
 		}
 
@@ -128,6 +132,8 @@ func BotSendWithRetry(bot *tb.Bot, to tb.Recipient, what interface{}, opts ...in
 			}
 			log.Warnf("Telegram rate limited on bot send, waiting %d seconds before retry (attempt %d/%d)",
 				waitSeconds, attempt+1, maxRetries)
+			metrics.FloodWaits.Inc()
+			metrics.FloodWaitSeconds.Add(float64(waitSeconds))
 			time.Sleep(time.Duration(waitSeconds) * time.Second)
 			continue
 		}
@@ -135,5 +141,73 @@ func BotSendWithRetry(bot *tb.Bot, to tb.Recipient, what interface{}, opts ...in
 		break
 	}
 
+	metrics.MessagesFailed.Inc(ErrorClass(lastErr))
 	return lastErr
 }
+
+// BotSendAlbumWithRetry sends a media group via Bot.SendAlbum with automatic retry on rate limit errors.
+func BotSendAlbumWithRetry(bot *tb.Bot, to tb.Recipient, album tb.Album, opts ...interface{}) error {
+	var lastErr error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		_, err := bot.SendAlbum(to, album, opts...)
+		if err == nil {
+			metrics.MessagesSent.Inc()
+			return nil
+		}
+
+		lastErr = err
+
+		// Check if it's a rate limit error
+		var floodErr tb.FloodError
+		if errors.As(err, &floodErr) {
+			waitSeconds := floodErr.RetryAfter
+			if waitSeconds <= 0 {
+				waitSeconds = 1
+			}
+			log.Warnf("Telegram rate limited on album send, waiting %d seconds before retry (attempt %d/%d)",
+				waitSeconds, attempt+1, maxRetries)
+			metrics.FloodWaits.Inc()
+			metrics.FloodWaitSeconds.Add(float64(waitSeconds))
+			time.Sleep(time.Duration(waitSeconds) * time.Second)
+			continue
+		}
+
+		break
+	}
+
+	metrics.MessagesFailed.Inc(ErrorClass(lastErr))
+	return lastErr
+}
+
+// ErrorClass 发送错误的类型，用于统计：flood、forbidden、bad_request、not_found、server、network、other
+func ErrorClass(err error) string {
+	var floodErr tb.FloodError
+	if errors.As(err, &floodErr) {
+		return "flood"
+	}
+	var tbErr *tb.Error
+	if errors.As(err, &tbErr) {
+		switch {
+		case tbErr.Code == 403:
+			return "forbidden"
+		case tbErr.Code == 400:
+			return "bad_request"
+		case tbErr.Code == 404:
+			return "not_found"
+		case tbErr.Code >= 500:
+			return "server"
+		}
+		return "other"
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return "network"
+	}
+	switch msg := err.Error(); {
+	case strings.Contains(msg, "Forbidden"):
+		return "forbidden"
+	case strings.Contains(msg, "Bad Request"):
+		return "bad_request"
+	}
+	return "other"
+}
@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	tb "gopkg.in/telebot.v3"
+
+	"github.com/zintus/flowerss-bot/internal/config"
+	"github.com/zintus/flowerss-bot/internal/log"
+)
+
+// webhookSecretHeader telegram 在 webhook 请求中携带 secret token 的请求头
+const webhookSecretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// webhookPoller 通过 webhook 接收 telegram 更新，secret token 不匹配的请求返回 401
+type webhookPoller struct {
+	listen      string
+	publicURL   string
+	secretToken string
+	tlsCert     string
+	tlsKey      string
+
+	listener net.Listener
+	dest     chan<- tb.Update
+}
+
+// newPoller 配置了 webhook 地址时使用 webhook，否则使用长轮询
+func newPoller() (tb.Poller, error) {
+	if config.WebhookURL == "" {
+		return &tb.LongPoller{Timeout: 10 * time.Second}, nil
+	}
+
+	secretToken := config.WebhookSecretToken
+	if secretToken == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		secretToken = hex.EncodeToString(buf)
+	}
+	return &webhookPoller{
+		listen:      config.WebhookListen,
+		publicURL:   config.WebhookURL,
+		secretToken: secretToken,
+		tlsCert:     config.WebhookTLSCert,
+		tlsKey:      config.WebhookTLSKey,
+	}, nil
+}
+
+// register 监听 webhook 地址并向 telegram 注册 webhook，在 Poll 之前调用以便启动失败时返回错误
+func (p *webhookPoller) register(b *tb.Bot) error {
+	listener, err := net.Listen("tcp", p.listen)
+	if err != nil {
+		return err
+	}
+	webhook := &tb.Webhook{
+		SecretToken: p.secretToken,
+		Endpoint:    &tb.WebhookEndpoint{PublicURL: p.publicURL, Cert: p.tlsCert},
+	}
+	if err := b.SetWebhook(webhook); err != nil {
+		_ = listener.Close()
+		return err
+	}
+	p.listener = listener
+	log.Infof("webhook listening on %s, public url %s", p.listen, p.publicURL)
+	return nil
+}
+
+// Poll 处理 webhook 请求，stop 关闭后停止监听
+func (p *webhookPoller) Poll(b *tb.Bot, dest chan tb.Update, stop chan struct{}) {
+	p.dest = dest
+	server := &http.Server{Handler: p}
+	go func() {
+		<-stop
+		if err := server.Shutdown(context.Background()); err != nil {
+			log.Errorf("shutdown webhook server failed, %v", err)
+		}
+	}()
+
+	var err error
+	if p.tlsCert != "" {
+		err = server.ServeTLS(p.listener, p.tlsCert, p.tlsKey)
+	} else {
+		err = server.Serve(p.listener)
+	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Errorf("webhook server exited, %v", err)
+		b.OnError(err, nil)
+		<-stop
+	}
+}
+
+func (p *webhookPoller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	token := r.Header.Get(webhookSecretHeader)
+	if subtle.ConstantTimeCompare([]byte(token), []byte(p.secretToken)) != 1 {
+		http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+		return
+	}
+
+	var update tb.Update
+	if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+	select {
+	case p.dest <- update:
+	case <-r.Context().Done():
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,62 @@
+package bot
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	tb "gopkg.in/telebot.v3"
+)
+
+func TestWebhookPollerServeHTTP(t *testing.T) {
+	dest := make(chan tb.Update, 1)
+	p := &webhookPoller{secretToken: "secret", dest: dest}
+	body := `{"update_id": 42}`
+
+	t.Run("missing secret token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Len(t, dest, 0)
+	})
+
+	t.Run("wrong secret token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(webhookSecretHeader, "other")
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Len(t, dest, 0)
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(webhookSecretHeader, "secret")
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	})
+
+	t.Run("bad body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{"))
+		req.Header.Set(webhookSecretHeader, "secret")
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Len(t, dest, 0)
+	})
+
+	t.Run("valid update", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set(webhookSecretHeader, "secret")
+		rec := httptest.NewRecorder()
+		p.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		if assert.Len(t, dest, 1) {
+			assert.Equal(t, 42, (<-dest).ID)
+		}
+	})
+}
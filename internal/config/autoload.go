@@ -32,6 +32,7 @@ func init() {
 	printVersionFlag := flag.Bool("v", false, "prints flowerss-bot version")
 
 	testTpl := flag.Bool("testtpl", false, "test template")
+	mergeDuplicates := flag.Bool("merge-duplicates", false, "merge sources whose links point to the same feed, then exit")
 
 	testing.Init()
 	flag.Parse()
@@ -61,6 +62,7 @@ func init() {
 		os.Exit(0)
 	}
 
+	MergeDuplicateSources = *mergeDuplicates
 	BotToken = viper.GetString("bot_token")
 	Socks5 = viper.GetString("socks5")
 	UserAgent = viper.GetString("user_agent")
@@ -98,6 +100,16 @@ func init() {
 		}
 	}
 
+	if viper.IsSet("admin_users") {
+		for _, userIDStr := range viper.GetStringSlice("admin_users") {
+			userID, err := strconv.ParseInt(userIDStr, 10, 64)
+			if err != nil {
+				panic(fmt.Errorf("fatal error config file: %w", err))
+			}
+			AdminUsers = append(AdminUsers, userID)
+		}
+	}
+
 	if viper.IsSet("disable_web_page_preview") {
 		DisableWebPagePreview = viper.GetBool("disable_web_page_preview")
 	}
@@ -106,14 +118,99 @@ func init() {
 		TelegramEndpoint = viper.GetString("telegram.endpoint")
 	}
 
+	if viper.IsSet("webhook.url") {
+		WebhookURL = viper.GetString("webhook.url")
+	}
+
+	if viper.IsSet("webhook.listen") {
+		WebhookListen = viper.GetString("webhook.listen")
+	}
+
+	if viper.IsSet("webhook.secret_token") {
+		WebhookSecretToken = viper.GetString("webhook.secret_token")
+	}
+
+	if viper.IsSet("webhook.tls_cert") {
+		WebhookTLSCert = viper.GetString("webhook.tls_cert")
+		WebhookTLSKey = viper.GetString("webhook.tls_key")
+	}
+
+	if viper.IsSet("metrics.listen") {
+		MetricsListen = viper.GetString("metrics.listen")
+	}
+
+	if viper.IsSet("feed_hints.enabled") {
+		HonorFeedHints = viper.GetBool("feed_hints.enabled")
+	}
+
+	if viper.IsSet("feed_hints.max_interval") {
+		FeedHintsMaxInterval = viper.GetInt("feed_hints.max_interval")
+	}
+
+	if viper.IsSet("redirect_follow_threshold") {
+		RedirectFollowThreshold = viper.GetInt("redirect_follow_threshold")
+	}
+
+	if viper.IsSet("adaptive_polling.enabled") {
+		AdaptivePolling = viper.GetBool("adaptive_polling.enabled")
+	}
+
+	if viper.IsSet("adaptive_polling.min_interval") {
+		AdaptiveMinInterval = viper.GetInt("adaptive_polling.min_interval")
+	}
+
+	if viper.IsSet("adaptive_polling.max_interval") {
+		AdaptiveMaxInterval = viper.GetInt("adaptive_polling.max_interval")
+	}
+
+	if viper.IsSet("websub.callback_url") {
+		WebSubCallbackURL = strings.TrimSuffix(viper.GetString("websub.callback_url"), "/")
+	}
+
+	if viper.IsSet("websub.listen") {
+		WebSubListen = viper.GetString("websub.listen")
+	}
+
+	if viper.IsSet("websub.fallback_interval") {
+		WebSubFallbackInterval = viper.GetInt("websub.fallback_interval")
+	}
+
+	if viper.IsSet("websub.max_lease") {
+		WebSubMaxLease = viper.GetInt("websub.max_lease")
+	}
+
 	if viper.IsSet("error_threshold") {
 		ErrorThreshold = uint(viper.GetInt("error_threshold"))
 	}
 
+	if viper.IsSet("error_backoff_max") {
+		ErrorBackoffMax = viper.GetInt("error_backoff_max")
+	}
+
 	if viper.IsSet("update_interval") {
 		UpdateInterval = viper.GetInt("update_interval")
 	}
 
+	if viper.IsSet("fetch_workers") {
+		FetchWorkers = viper.GetInt("fetch_workers")
+	}
+
+	if viper.IsSet("fetch_host_concurrency") {
+		FetchHostConcurrency = viper.GetInt("fetch_host_concurrency")
+	}
+
+	if viper.IsSet("content_retention_days") {
+		ContentRetentionDays = viper.GetInt("content_retention_days")
+	}
+
+	if viper.IsSet("fetch_max_body_size") {
+		FetchMaxBodySize = viper.GetInt("fetch_max_body_size")
+	}
+
+	if viper.IsSet("fetch_allowed_hosts") {
+		FetchAllowedHosts = viper.GetStringSlice("fetch_allowed_hosts")
+	}
+
 	if viper.IsSet("mysql.host") {
 		EnableMysql = true
 		mysqlConfig = mysql.NewConfig()
@@ -42,6 +42,24 @@ var (
 	// ErrorThreshold rss源抓取错误阈值
 	ErrorThreshold uint = 100
 
+	// ErrorBackoffMax 抓取失败后重试间隔的上限（分钟）
+	ErrorBackoffMax int = 1440
+
+	// FetchWorkers 同时抓取订阅源的最大数量
+	FetchWorkers int = 10
+
+	// FetchHostConcurrency 同一主机同时抓取的最大数量，0 为不限制
+	FetchHostConcurrency int = 2
+
+	// ContentRetentionDays 文章正文的保留天数，过期后只保留标题和链接，0 为永久保留
+	ContentRetentionDays int = 30
+
+	// FetchMaxBodySize 订阅源响应的最大大小，单位 MB，0 为不限制
+	FetchMaxBodySize int = 10
+
+	// FetchAllowedHosts 抓取订阅源时允许访问的内网主机名、IP 或网段
+	FetchAllowedHosts []string
+
 	// MessageTpl rss更新推送模版
 	MessageTpl *template.Template
 
@@ -51,15 +69,59 @@ var (
 	// TelegramEndpoint telegram bot 服务器地址，默认为空
 	TelegramEndpoint string = tb.DefaultApiURL
 
+	// WebhookURL telegram 推送更新的公开地址，设置后使用 webhook 代替长轮询
+	WebhookURL string
+	// WebhookListen webhook 监听地址
+	WebhookListen string = ":8443"
+	// WebhookSecretToken 校验 webhook 请求的 secret token，为空时启动时随机生成
+	WebhookSecretToken string
+	// WebhookTLSCert webhook 的 TLS 证书路径，为空时监听 http，由反向代理处理 TLS
+	WebhookTLSCert string
+	// WebhookTLSKey webhook 的 TLS 私钥路径
+	WebhookTLSKey string
+
+	// MetricsListen 指标和健康检查 http 服务的监听地址，为空时不启动
+	MetricsListen string
+
+	// HonorFeedHints 是否遵循订阅源声明的 ttl、skipHours、skipDays 和 sy:updatePeriod
+	HonorFeedHints bool = true
+	// FeedHintsMaxInterval 订阅源声明的最小抓取间隔的上限，单位分钟，0 为不限制
+	FeedHintsMaxInterval int = 24 * 60
+
+	// RedirectFollowThreshold 连续多少次抓取被永久重定向到同一地址后更新订阅源链接，0 为不更新
+	RedirectFollowThreshold int = 3
+
+	// AdaptivePolling 是否根据订阅源最近的发布频率调整抓取间隔
+	AdaptivePolling bool = true
+	// AdaptiveMinInterval 自适应抓取间隔的下限，单位分钟
+	AdaptiveMinInterval int = 5
+	// AdaptiveMaxInterval 自适应抓取间隔的上限，单位分钟
+	AdaptiveMaxInterval int = 12 * 60
+
+	// WebSubCallbackURL hub 推送更新的公开地址前缀，为空时不使用 WebSub
+	WebSubCallbackURL string
+	// WebSubListen WebSub 回调服务的监听地址
+	WebSubListen string = ":8090"
+	// WebSubFallbackInterval WebSub 订阅有效时轮询的最小间隔，单位分钟
+	WebSubFallbackInterval int = 60
+	// WebSubMaxLease hub 确认的订阅有效期上限，单位小时
+	WebSubMaxLease int = 10 * 24
+
 	// UserAgent User-Agent
 	UserAgent string
 
+	// MergeDuplicateSources 合并链接规范形式相同的订阅源后退出，由 -merge-duplicates 参数设置
+	MergeDuplicateSources bool
+
 	// RunMode 运行模式 Release / Debug
 	RunMode RunType = ReleaseMode
 
 	// AllowUsers 允许使用bot的用户
 	AllowUsers []int64
 
+	// AdminUsers 可以使用 /admin 命令的用户，也允许使用bot
+	AdminUsers []int64
+
 	// DBLogMode 是否打印数据库日志
 	DBLogMode bool = false
 
@@ -161,6 +223,16 @@ func (td *TplData) Render(mode tb.ParseMode) (string, error) {
 	return buf.String(), nil
 }
 
+// IsAdmin 用户是否为管理员
+func IsAdmin(userID int64) bool {
+	for _, adminID := range AdminUsers {
+		if adminID == userID {
+			return true
+		}
+	}
+	return false
+}
+
 // GetString get string config value by key
 func GetString(key string) string {
 	var value string
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+// Stats bot 的整体统计
+type Stats struct {
+	Users          int64
+	Sources        int
+	PausedSources  int
+	FailingSources int
+	Subscriptions  int64
+}
+
+// GetStats 统计用户、订阅源和订阅数量
+func (c *Core) GetStats(ctx context.Context) (*Stats, error) {
+	users, err := c.userStorage.CountUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	subscriptions, err := c.subscriptionStorage.CountSubscriptions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sources, err := c.sourceStorage.GetSources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{Users: users, Sources: len(sources), Subscriptions: subscriptions}
+	for _, source := range sources {
+		if source.Paused {
+			stats.PausedSources++
+		} else if source.ErrorCount > 0 {
+			stats.FailingSources++
+		}
+	}
+	return stats, nil
+}
+
+// GetSourcesByErrorCount 按连续错误次数从多到少排列的订阅源，次数相同时按 ID 排列
+func (c *Core) GetSourcesByErrorCount(ctx context.Context) ([]*model.Source, error) {
+	sources, err := c.sourceStorage.GetSources(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(
+		sources, func(i, j int) bool {
+			if sources[i].ErrorCount != sources[j].ErrorCount {
+				return sources[i].ErrorCount > sources[j].ErrorCount
+			}
+			return sources[i].ID < sources[j].ID
+		},
+	)
+	return sources, nil
+}
+
+// GetSubscriberIDs 有订阅的所有用户 ID
+func (c *Core) GetSubscriberIDs(ctx context.Context) ([]int64, error) {
+	return c.subscriptionStorage.GetSubscriberIDs(ctx)
+}
+
+// RequestFetch 将订阅源安排为立即抓取，并唤醒调度器
+func (c *Core) RequestFetch(ctx context.Context, sourceID uint) error {
+	if err := c.ScheduleSourceFetch(ctx, sourceID, time.Now()); err != nil {
+		return err
+	}
+	c.kickFetch()
+	return nil
+}
+
+// kickFetch 唤醒调度器，已有未处理的通知时直接返回
+func (c *Core) kickFetch() {
+	select {
+	case c.fetchKick <- struct{}{}:
+	default:
+	}
+}
+
+// FetchRequests 有订阅源需要立即抓取时收到通知
+func (c *Core) FetchRequests() <-chan struct{} {
+	return c.fetchKick
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+func TestCore_GetStats(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+
+	t.Run(
+		"count error", func(t *testing.T) {
+			s.User.EXPECT().CountUsers(ctx).Return(int64(0), errors.New("err")).Times(1)
+			_, err := c.GetStats(ctx)
+			assert.Error(t, err)
+		},
+	)
+
+	t.Run(
+		"ok", func(t *testing.T) {
+			s.User.EXPECT().CountUsers(ctx).Return(int64(3), nil).Times(1)
+			s.Subscription.EXPECT().CountSubscriptions(ctx).Return(int64(7), nil).Times(1)
+			s.Source.EXPECT().GetSources(ctx).Return(
+				[]*model.Source{
+					{ID: 1},
+					{ID: 2, Paused: true, ErrorCount: 5},
+					{ID: 3, ErrorCount: 1},
+				}, nil,
+			).Times(1)
+
+			stats, err := c.GetStats(ctx)
+			assert.Nil(t, err)
+			assert.Equal(
+				t, &Stats{Users: 3, Sources: 3, PausedSources: 1, FailingSources: 1, Subscriptions: 7}, stats,
+			)
+		},
+	)
+}
+
+func TestCore_GetSourcesByErrorCount(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+
+	s.Source.EXPECT().GetSources(ctx).Return(
+		[]*model.Source{{ID: 3, ErrorCount: 1}, {ID: 1}, {ID: 2, ErrorCount: 9}, {ID: 4, ErrorCount: 1}}, nil,
+	).Times(1)
+	sources, err := c.GetSourcesByErrorCount(ctx)
+	assert.Nil(t, err)
+	var ids []uint
+	for _, source := range sources {
+		ids = append(ids, source.ID)
+	}
+	assert.Equal(t, []uint{2, 3, 4, 1}, ids)
+}
+
+func TestCore_RequestFetch(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+	sourceID := uint(101)
+
+	s.Source.EXPECT().GetSource(ctx, sourceID).Return(&model.Source{ID: sourceID}, nil).Times(2)
+	s.Source.EXPECT().UpsertSource(ctx, sourceID, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, sourceID uint, source *model.Source) error {
+			assert.NotNil(t, source.NextFetchAt)
+			return nil
+		},
+	).Times(2)
+
+	// 多次请求只唤醒一次调度器，不会阻塞
+	assert.Nil(t, c.RequestFetch(ctx, sourceID))
+	assert.Nil(t, c.RequestFetch(ctx, sourceID))
+	select {
+	case <-c.FetchRequests():
+	default:
+		t.Fatal("scheduler not kicked")
+	}
+	select {
+	case <-c.FetchRequests():
+		t.Fatal("scheduler kicked twice")
+	default:
+	}
+}
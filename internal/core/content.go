@@ -0,0 +1,47 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+// GetSourceLatestContents 获取订阅源最新的 limit 篇文章，从新到旧排列
+func (c *Core) GetSourceLatestContents(ctx context.Context, sourceID uint, limit int) ([]*model.Content, error) {
+	return c.contentStorage.GetSourceLatestContents(ctx, sourceID, limit)
+}
+
+// PurgeContents 清空 before 之前入库文章的正文，文章记录保留用于去重
+func (c *Core) PurgeContents(ctx context.Context, before time.Time) (int64, error) {
+	return c.contentStorage.StripContentsBefore(ctx, before)
+}
+
+// itemPublishedAt 文章的发布时间，没有时使用更新时间
+func itemPublishedAt(item *gofeed.Item) *time.Time {
+	if item.PublishedParsed != nil {
+		return item.PublishedParsed
+	}
+	return item.UpdatedParsed
+}
+
+// itemEnclosures 文章的附件，文章图片作为图片附件
+func itemEnclosures(item *gofeed.Item) model.Enclosures {
+	var enclosures model.Enclosures
+	seen := map[string]bool{}
+	for _, enclosure := range item.Enclosures {
+		if enclosure == nil || enclosure.URL == "" || seen[enclosure.URL] {
+			continue
+		}
+		seen[enclosure.URL] = true
+		enclosures = append(
+			enclosures, model.Enclosure{URL: enclosure.URL, Type: enclosure.Type, Length: enclosure.Length},
+		)
+	}
+	if item.Image != nil && item.Image.URL != "" && !seen[item.Image.URL] {
+		enclosures = append(enclosures, model.Enclosure{URL: item.Image.URL, Type: "image"})
+	}
+	return enclosures
+}
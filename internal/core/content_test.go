@@ -0,0 +1,43 @@
+package core
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+func TestItemPublishedAt(t *testing.T) {
+	published := time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC)
+	updated := published.Add(time.Hour)
+
+	assert.Equal(t, &published, itemPublishedAt(&gofeed.Item{PublishedParsed: &published, UpdatedParsed: &updated}))
+	assert.Equal(t, &updated, itemPublishedAt(&gofeed.Item{UpdatedParsed: &updated}))
+	assert.Nil(t, itemPublishedAt(&gofeed.Item{}))
+}
+
+func TestItemEnclosures(t *testing.T) {
+	item := &gofeed.Item{
+		Enclosures: []*gofeed.Enclosure{
+			{URL: "https://example.com/a.mp3", Type: "audio/mpeg", Length: "1024"},
+			{URL: ""},
+			nil,
+		},
+	}
+	assert.Equal(
+		t, model.Enclosures{{URL: "https://example.com/a.mp3", Type: "audio/mpeg", Length: "1024"}}, itemEnclosures(item),
+	)
+	assert.Nil(t, itemEnclosures(&gofeed.Item{}))
+
+	item.Image = &gofeed.Image{URL: "https://example.com/cover.jpg"}
+	item.Enclosures = append(item.Enclosures, &gofeed.Enclosure{URL: "https://example.com/a.mp3"})
+	assert.Equal(
+		t, model.Enclosures{
+			{URL: "https://example.com/a.mp3", Type: "audio/mpeg", Length: "1024"},
+			{URL: "https://example.com/cover.jpg", Type: "image"},
+		}, itemEnclosures(item),
+	)
+}
@@ -2,6 +2,7 @@ package core
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"strings"
@@ -28,6 +29,7 @@ var (
 	ErrSubscriptionNotExist = errors.New("subscription not exist")
 	ErrSourceNotExist       = errors.New("source not exist")
 	ErrContentNotExist      = errors.New("content not exist")
+	ErrNoFeedFound          = errors.New("no feed found on the page")
 )
 
 type Core struct {
@@ -36,9 +38,27 @@ type Core struct {
 	contentStorage      storage.Content
 	sourceStorage       storage.Source
 	subscriptionStorage storage.Subscription
+	deliveryStorage     storage.Delivery
+	contentSearch       storage.ContentSearch
 
 	feedParser *feed.FeedParser
 	httpClient *client.HttpClient
+
+	// db 由 NewCoreFormConfig 打开的数据库连接，退出时关闭
+	db *sql.DB
+	// fetchKick 唤醒调度器立即抓取
+	fetchKick chan struct{}
+
+	// hubPending 已向 hub 发出、还未确认的订阅请求，key 为订阅源 ID
+	hubPending   map[uint]pendingHubRequest
+	hubPendingMu sync.Mutex
+
+	// ctx 后台任务使用的 ctx，由 Init 设置，结束时取消进行中的后台任务
+	ctx context.Context
+	// background 进行中的后台任务，退出时由 Wait 等待
+	background       sync.WaitGroup
+	backgroundMu     sync.Mutex
+	backgroundClosed bool
 }
 
 func (c *Core) FeedParser() *feed.FeedParser {
@@ -54,6 +74,8 @@ func NewCore(
 	contentStorage storage.Content,
 	sourceStorage storage.Source,
 	subscriptionStorage storage.Subscription,
+	deliveryStorage storage.Delivery,
+	contentSearch storage.ContentSearch,
 	parser *feed.FeedParser,
 	httpClient *client.HttpClient,
 ) *Core {
@@ -62,8 +84,13 @@ func NewCore(
 		contentStorage:      contentStorage,
 		sourceStorage:       sourceStorage,
 		subscriptionStorage: subscriptionStorage,
+		deliveryStorage:     deliveryStorage,
+		contentSearch:       contentSearch,
 		feedParser:          parser,
 		httpClient:          httpClient,
+		fetchKick:           make(chan struct{}, 1),
+		hubPending:          make(map[uint]pendingHubRequest),
+		ctx:                 context.Background(),
 	}
 }
 
@@ -93,6 +120,12 @@ func NewCoreFormConfig() *Core {
 	sqlDB.SetMaxOpenConns(50)
 
 	subscriptionStorage := storage.NewSubscriptionStorageImpl(db)
+	var contentSearch storage.ContentSearch
+	if config.EnableMysql {
+		contentSearch = storage.NewMysqlContentSearchImpl(db)
+	} else {
+		contentSearch = storage.NewSQLiteContentSearchImpl(db)
+	}
 
 	// httpclient
 	clientOpts := []client.HttpClientOption{
@@ -107,33 +140,101 @@ func NewCoreFormConfig() *Core {
 	}
 	httpClient := client.NewHttpClient(clientOpts...)
 
-	// feedParser
-	feedParser := feed.NewFeedParser(httpClient)
+	// feedParser，订阅源链接由用户提供，禁止访问内网地址
+	guard, err := client.NewAddressGuard(config.FetchAllowedHosts)
+	if err != nil {
+		log.Fatalf("invalid fetch_allowed_hosts: %v", err)
+	}
+	feedParser := feed.NewFeedParser(
+		client.NewHttpClient(append(clientOpts, client.WithAddressGuard(guard))...),
+		feed.WithMaxBodySize(int64(config.FetchMaxBodySize)<<20),
+	)
 
-	return NewCore(
+	appCore := NewCore(
 		storage.NewUserStorageImpl(db),
 		storage.NewContentStorageImpl(db),
 		storage.NewSourceStorageImpl(db),
 		subscriptionStorage,
+		storage.NewDeliveryStorageImpl(db),
+		contentSearch,
 		feedParser,
 		httpClient,
 	)
+	appCore.db = sqlDB
+	return appCore
 }
 
-func (c *Core) Init() error {
-	if err := c.userStorage.Init(context.Background()); err != nil {
+// Init 初始化存储，ctx 同时用于后台任务，ctx 结束时取消进行中的后台任务
+func (c *Core) Init(ctx context.Context) error {
+	c.ctx = ctx
+	if err := c.userStorage.Init(ctx); err != nil {
 		return err
 	}
-	if err := c.contentStorage.Init(context.Background()); err != nil {
+	if err := c.contentStorage.Init(ctx); err != nil {
 		return err
 	}
-	if err := c.sourceStorage.Init(context.Background()); err != nil {
+	if err := c.contentSearch.Init(ctx); err != nil {
 		return err
 	}
-	if err := c.subscriptionStorage.Init(context.Background()); err != nil {
+	if err := c.sourceStorage.Init(ctx); err != nil {
 		return err
 	}
-	return nil
+	if err := c.subscriptionStorage.Init(ctx); err != nil {
+		return err
+	}
+	if err := c.deliveryStorage.Init(ctx); err != nil {
+		return err
+	}
+	return c.fillSourceURLKeys(ctx)
+}
+
+// Ping 检查数据库连接是否可用
+func (c *Core) Ping(ctx context.Context) error {
+	if c.db == nil {
+		return nil
+	}
+	return c.db.PingContext(ctx)
+}
+
+// goBackground 在后台运行 fn，Init 的 ctx 已结束或已调用 Wait 时不再运行
+func (c *Core) goBackground(fn func(ctx context.Context)) {
+	c.backgroundMu.Lock()
+	defer c.backgroundMu.Unlock()
+	if c.backgroundClosed || c.ctx.Err() != nil {
+		return
+	}
+	c.background.Add(1)
+	go func() {
+		defer c.background.Done()
+		fn(c.ctx)
+	}()
+}
+
+// Wait 不再开始新的后台任务并等待进行中的完成，ctx 结束时不再等待并返回 ctx 的错误，在关闭数据库前调用
+func (c *Core) Wait(ctx context.Context) error {
+	c.backgroundMu.Lock()
+	c.backgroundClosed = true
+	c.backgroundMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		c.background.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close 关闭数据库连接
+func (c *Core) Close() error {
+	if c.db == nil {
+		return nil
+	}
+	return c.db.Close()
 }
 
 // GetUserSubscribedSources 获取用户订阅的订阅源
@@ -167,6 +268,7 @@ func (c *Core) AddSubscription(ctx context.Context, userID int64, sourceID uint)
 		return ErrSubscriptionExist
 	}
 
+	now := time.Now()
 	subscription := &model.Subscribe{
 		UserID:             userID,
 		SourceID:           sourceID,
@@ -174,6 +276,15 @@ func (c *Core) AddSubscription(ctx context.Context, userID int64, sourceID uint)
 		EnableTelegraph:    1,
 		Interval:           config.UpdateInterval,
 		WaitTime:           config.UpdateInterval,
+		LastDeliveredAt:    &now,
+	}
+	// 使用 /digest all 设置的摘要计划
+	user, err := c.userStorage.GetUser(ctx, userID)
+	if err != nil && !errors.Is(err, storage.ErrRecordNotFound) {
+		return err
+	}
+	if user != nil {
+		subscription.Digest = user.Digest
 	}
 	return c.subscriptionStorage.AddSubscription(ctx, subscription)
 }
@@ -217,9 +328,17 @@ func (c *Core) Unsubscribe(ctx context.Context, userID int64, sourceID uint) err
 
 // removeSource 移除订阅源
 func (c *Core) removeSource(ctx context.Context, sourceID uint) error {
+	var source *model.Source
+	if config.WebSubCallbackURL != "" {
+		// 删除后取消 hub 订阅
+		source, _ = c.GetSource(ctx, sourceID)
+	}
 	if err := c.sourceStorage.Delete(ctx, sourceID); err != nil {
 		return err
 	}
+	if source != nil {
+		c.unsubscribeHub(source)
+	}
 
 	count, err := c.contentStorage.DeleteSourceContents(ctx, sourceID)
 	if err != nil {
@@ -229,9 +348,9 @@ func (c *Core) removeSource(ctx context.Context, sourceID uint) error {
 	return nil
 }
 
-// GetSourceByURL 获取用户订阅的订阅源
+// GetSourceByURL 获取用户订阅的订阅源，sourceURL 的各种变体都指向同一个订阅源，见 SourceURLKey
 func (c *Core) GetSourceByURL(ctx context.Context, sourceURL string) (*model.Source, error) {
-	source, err := c.sourceStorage.GetSourceByURL(ctx, sourceURL)
+	source, err := c.sourceStorage.GetSourceByURLKey(ctx, SourceURLKey(sourceURL))
 	if err != nil {
 		if err == storage.ErrRecordNotFound {
 			return nil, ErrSourceNotExist
@@ -258,10 +377,24 @@ func (c *Core) GetSources(ctx context.Context) ([]*model.Source, error) {
 	return c.sourceStorage.GetSources(ctx)
 }
 
-// CreateSource 创建订阅源
+// MultipleFeedsError 网页中发现了多个订阅源，需要用户选择其中一个
+type MultipleFeedsError struct {
+	Feeds []feed.DiscoveredFeed
+}
+
+func (e *MultipleFeedsError) Error() string {
+	return fmt.Sprintf("found %d feeds on the page", len(e.Feeds))
+}
+
+// CreateSource 创建订阅源，sourceURL 是网页时从网页中查找订阅源，
+// 找到多个时返回 MultipleFeedsError
 func (c *Core) CreateSource(ctx context.Context, sourceURL string) (*model.Source, error) {
+	sourceURL = NormalizeSourceURL(sourceURL)
 	s, err := c.GetSourceByURL(ctx, sourceURL)
 	if err == nil {
+		if err := c.upgradeSourceScheme(ctx, s, sourceURL); err != nil {
+			return nil, err
+		}
 		return s, nil
 	}
 
@@ -269,17 +402,57 @@ func (c *Core) CreateSource(ctx context.Context, sourceURL string) (*model.Sourc
 		return nil, err
 	}
 
-	rssFeed, err := c.feedParser.ParseFromURL(ctx, sourceURL)
+	result, err := c.feedParser.Fetch(ctx, sourceURL, nil)
+	if errors.Is(err, feed.ErrHTMLPage) {
+		feeds, discoverErr := c.feedParser.Discover(ctx, sourceURL)
+		if discoverErr != nil {
+			log.Errorf("discover feeds of %s failed, %v", model.RedactLink(sourceURL), discoverErr)
+			return nil, discoverErr
+		}
+		switch len(feeds) {
+		case 0:
+			return nil, ErrNoFeedFound
+		case 1:
+			log.Infof("discovered feed %s from %s", model.RedactLink(feeds[0].URL), model.RedactLink(sourceURL))
+			sourceURL = NormalizeSourceURL(feeds[0].URL)
+		default:
+			return nil, &MultipleFeedsError{Feeds: feeds}
+		}
+
+		if s, err := c.GetSourceByURL(ctx, sourceURL); err == nil {
+			if err := c.upgradeSourceScheme(ctx, s, sourceURL); err != nil {
+				return nil, err
+			}
+			return s, nil
+		} else if err != ErrSourceNotExist {
+			return nil, err
+		}
+		result, err = c.feedParser.Fetch(ctx, sourceURL, nil)
+	}
 	if err != nil {
-		log.Errorf("ParseFromURL %s failed, %v", sourceURL, err)
+		log.Errorf("Fetch %s failed, %v", model.RedactLink(sourceURL), err)
 		return nil, err
 	}
+	rssFeed := result.Feed
 
 	s = &model.Source{
 		Title:           rssFeed.Title,
 		Link:            sourceURL,
-		ErrorCount:      config.ErrorThreshold + 1, // 避免task更新
-		LastPublishedAt: rssFeed.UpdatedParsed,     // NEW – initialise from feed
+		URLKey:          SourceURLKey(sourceURL),
+		Paused:          true,                  // 避免task更新
+		LastPublishedAt: rssFeed.UpdatedParsed, // NEW – initialise from feed
+		ETag:            result.ETag,
+		LastModified:    result.LastModified,
+		FeedHints:       result.Hints,
+	}
+	if result.Hub != "" {
+		topic := result.Self
+		if topic == "" {
+			topic = sourceURL
+		}
+		if err := setSourceHub(s, result.Hub, topic); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := c.sourceStorage.AddSource(ctx, s); err != nil {
@@ -287,30 +460,35 @@ func (c *Core) CreateSource(ctx context.Context, sourceURL string) (*model.Sourc
 		return nil, err
 	}
 	defer func() {
-		if clearErr := c.ClearSourceErrorCount(ctx, s.ID); clearErr != nil {
-			log.Errorf("failed to clear source error count: %v", clearErr)
+		if enableErr := c.EnableSourceUpdate(ctx, s.ID); enableErr != nil {
+			log.Errorf("failed to enable source update: %v", enableErr)
 		}
 	}()
 
+	// 订阅源已经入库，文章入库失败时下次抓取会重新保存
 	if _, err := c.AddSourceContents(ctx, s, rssFeed.Items); err != nil {
 		log.Errorf("add source content failed, %v", err)
-		return nil, err
 	}
+	c.subscribeHubAsync(s)
 	return s, nil
 }
 
+// AddSourceContents 保存订阅源的新文章，返回入库成功的文章，有文章入库失败时同时返回错误
 func (c *Core) AddSourceContents(
 	ctx context.Context, source *model.Source, items []*gofeed.Item,
 ) ([]*model.Content, error) {
 	var wg sync.WaitGroup
 	var contents []*model.Content
-	for _, item := range items {
+	saved := make([]bool, len(items))
+	// 同一次抓取的文章使用相同的入库时间，之后按发布时间排序
+	fetchedAt := time.Now()
+	for i, item := range items {
 		wg.Add(1)
 		previewURL := ""
 		if config.EnableTelegraph {
 			publishContent := ""
 			if config.UnrenderURL != "" && config.UnrenderToken != "" && item.Link != "" {
-				if html, err := tgraph.FetchHTML(item.Link); err != nil {
+				if html, err := tgraph.FetchHTML(c.feedParser.Client().AddressGuard(), item.Link); err != nil {
 					log.Warnf("unrender fetch failed for %s: %v, falling back to feed content", item.Link, err)
 				} else {
 					publishContent = html
@@ -332,29 +510,50 @@ func (c *Core) AddSourceContents(
 			Description:  item.Content, //replace all kinds of <br> tag
 			SourceID:     source.ID,
 			RawID:        item.GUID,
-			HashID:       model.GenHashID(source.Link, item.GUID, item.Link),
+			HashID:       model.GenHashID(source.ContentKey(), item.GUID, item.Link),
 			RawLink:      item.Link,
 			TelegraphURL: previewURL,
+			Summary:      item.Description,
+			Author:       itemAuthor(item),
+			Categories:   strings.Join(item.Categories, "\n"),
+			PublishedAt:  itemPublishedAt(item),
+			Enclosures:   itemEnclosures(item),
+			EditTime:     model.EditTime{CreatedAt: fetchedAt},
 		}
 		contents = append(contents, content)
-		go func() {
+		go func(i int) {
 			defer wg.Done()
 			if err := c.contentStorage.AddContent(ctx, content); err != nil {
 				log.Errorf("add content %#v failed, %v", content, err)
+				return
 			}
-		}()
+			saved[i] = true
+		}(i)
 	}
 	wg.Wait()
 
+	stored := contents[:0]
+	for i, content := range contents {
+		if saved[i] {
+			stored = append(stored, content)
+		}
+	}
+	failed := len(contents) - len(stored)
+	contents = stored
+
 	// Update LastContentAt with our local timestamp when content is added
 	if len(contents) > 0 {
-		now := time.Now()
-		source.LastContentAt = &now
-		if err := c.sourceStorage.UpsertSource(ctx, source.ID, source); err != nil {
+		published := contentPublishTimes(contents, fetchedAt)
+		source.LastContentAt = &fetchedAt
+		source.PublishHistory = source.PublishHistory.Add(published...)
+		if err := c.updateSourceLastContentAt(ctx, source.ID, &fetchedAt, published); err != nil {
 			log.Errorf("failed to update LastContentAt for source %d: %v", source.ID, err)
 		}
 	}
 
+	if failed > 0 {
+		return contents, fmt.Errorf("save %d of %d contents failed", failed, len(items))
+	}
 	return contents, nil
 }
 
@@ -403,7 +602,7 @@ func (c *Core) SetSubscriptionTag(ctx context.Context, userID int64, sourceID ui
 	return c.subscriptionStorage.UpdateSubscription(ctx, userID, sourceID, subscription)
 }
 
-// SetSubscriptionInterval
+// SetSubscriptionInterval 设置订阅更新间隔，若新间隔比订阅源当前的抓取计划更短则提前下次抓取
 func (c *Core) SetSubscriptionInterval(ctx context.Context, userID int64, sourceID uint, interval int) error {
 	subscription, err := c.GetSubscription(ctx, userID, sourceID)
 	if err != nil {
@@ -411,12 +610,108 @@ func (c *Core) SetSubscriptionInterval(ctx context.Context, userID int64, source
 	}
 
 	subscription.Interval = interval
-	return c.subscriptionStorage.UpdateSubscription(ctx, userID, sourceID, subscription)
+	subscription.IntervalSet = true
+	if err := c.subscriptionStorage.UpdateSubscription(ctx, userID, sourceID, subscription); err != nil {
+		return err
+	}
+
+	source, err := c.GetSource(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+	next := time.Now().Add(time.Duration(interval) * time.Minute)
+	if source.NextFetchAt == nil || !source.NextFetchAt.After(next) {
+		return nil
+	}
+	return c.ScheduleSourceFetch(ctx, sourceID, next)
+}
+
+// FetchSchedule 订阅源的抓取间隔及其来源，用于展示
+type FetchSchedule struct {
+	Interval time.Duration
+	Reason   IntervalReason
+	// SkipHours 和 SkipDays 为订阅源声明且生效的不抓取时段
+	SkipHours []int
+	SkipDays  []int
+}
+
+// SourceFetchSchedule 订阅源当前的抓取间隔及其来源
+func (c *Core) SourceFetchSchedule(ctx context.Context, source *model.Source) (*FetchSchedule, error) {
+	subs, err := c.GetSourceAllSubscriptions(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	schedule := &FetchSchedule{}
+	schedule.Interval, schedule.Reason = SourceEffectiveInterval(source, subs, time.Now())
+	if config.HonorFeedHints {
+		schedule.SkipHours = source.FeedHints.SkipHours
+		schedule.SkipDays = source.FeedHints.SkipDays
+	}
+	return schedule, nil
+}
+
+// UpdateSourceFeedHints 保存订阅源声明的抓取提示
+func (c *Core) UpdateSourceFeedHints(ctx context.Context, sourceID uint, hints model.FeedHints) error {
+	source, err := c.GetSource(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+	source.FeedHints = hints
+	return c.sourceStorage.UpsertSource(ctx, sourceID, source)
+}
+
+// ScheduleSourceFetch 设置订阅源的下次抓取时间
+func (c *Core) ScheduleSourceFetch(ctx context.Context, sourceID uint, at time.Time) error {
+	source, err := c.GetSource(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+
+	source.NextFetchAt = &at
+	return c.sourceStorage.UpsertSource(ctx, sourceID, source)
+}
+
+// MarkSubscriptionDelivered 记录订阅者最近一次收到推送的时间
+func (c *Core) MarkSubscriptionDelivered(ctx context.Context, sub *model.Subscribe, at time.Time) error {
+	sub.LastDeliveredAt = &at
+	return c.subscriptionStorage.UpdateSubscription(ctx, sub.UserID, sub.SourceID, sub)
 }
 
-// EnableSourceUpdate 开启订阅源更新
+// GetSourceContentsSince 获取订阅源在 since 之后入库的文章
+func (c *Core) GetSourceContentsSince(
+	ctx context.Context, sourceID uint, since time.Time,
+) ([]*model.Content, error) {
+	return c.contentStorage.GetSourceContentsSince(ctx, sourceID, since)
+}
+
+// EnableSourceUpdate 开启订阅源更新，清空错误计数并尽快抓取
 func (c *Core) EnableSourceUpdate(ctx context.Context, sourceID uint) error {
-	return c.ClearSourceErrorCount(ctx, sourceID)
+	source, err := c.GetSource(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+
+	source.Paused = false
+	source.ErrorCount = 0
+	source.NextFetchAt = nil
+	return c.sourceStorage.UpsertSource(ctx, sourceID, source)
+}
+
+// RetrySource 恢复订阅源更新并立即重新抓取，保留错误计数，重试仍然失败时继续退避
+func (c *Core) RetrySource(ctx context.Context, sourceID uint) error {
+	source, err := c.GetSource(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	source.Paused = false
+	source.NextFetchAt = &now
+	if err := c.sourceStorage.UpsertSource(ctx, sourceID, source); err != nil {
+		return err
+	}
+	c.kickFetch()
+	return nil
 }
 
 // DisableSourceUpdate 关闭订阅源更新
@@ -426,7 +721,7 @@ func (c *Core) DisableSourceUpdate(ctx context.Context, sourceID uint) error {
 		return err
 	}
 
-	source.ErrorCount = config.ErrorThreshold + 1
+	source.Paused = true
 	return c.sourceStorage.UpsertSource(ctx, sourceID, source)
 }
 
@@ -440,6 +735,44 @@ func (c *Core) UpdateSourceLastPublishedAt(ctx context.Context, sourceID uint, t
 	return c.sourceStorage.UpsertSource(ctx, sourceID, source)
 }
 
+// UpdateSourceCacheValidators stores the ETag and Last-Modified headers used for conditional requests
+func (c *Core) UpdateSourceCacheValidators(ctx context.Context, sourceID uint, etag, lastModified string) error {
+	source, err := c.GetSource(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+	source.ETag = etag
+	source.LastModified = lastModified
+	return c.sourceStorage.UpsertSource(ctx, sourceID, source)
+}
+
+// updateSourceLastContentAt sets LastContentAt and records the publish times of new contents
+// on the stored source, leaving the other fields as they are in storage rather than in the caller's copy
+func (c *Core) updateSourceLastContentAt(
+	ctx context.Context, sourceID uint, ts *time.Time, published []time.Time,
+) error {
+	source, err := c.GetSource(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+	source.LastContentAt = ts
+	source.PublishHistory = source.PublishHistory.Add(published...)
+	return c.sourceStorage.UpsertSource(ctx, sourceID, source)
+}
+
+// contentPublishTimes 新文章的发布时间，没有发布时间或发布时间晚于入库时间时使用入库时间
+func contentPublishTimes(contents []*model.Content, fetchedAt time.Time) []time.Time {
+	times := make([]time.Time, 0, len(contents))
+	for _, content := range contents {
+		if content.PublishedAt != nil && !content.PublishedAt.After(fetchedAt) {
+			times = append(times, *content.PublishedAt)
+		} else {
+			times = append(times, fetchedAt)
+		}
+	}
+	return times
+}
+
 // ClearSourceErrorCount 清空订阅源错误计数
 func (c *Core) ClearSourceErrorCount(ctx context.Context, sourceID uint) error {
 	source, err := c.GetSource(ctx, sourceID)
@@ -451,17 +784,36 @@ func (c *Core) ClearSourceErrorCount(ctx context.Context, sourceID uint) error {
 	return c.sourceStorage.UpsertSource(ctx, sourceID, source)
 }
 
-// SourceErrorCountIncr 增加订阅源错误计数
-func (c *Core) SourceErrorCountIncr(ctx context.Context, sourceID uint) error {
+// MarkSourceFetched 记录订阅源最近一次抓取成功的时间并清空错误计数
+func (c *Core) MarkSourceFetched(ctx context.Context, sourceID uint, at time.Time) error {
 	source, err := c.GetSource(ctx, sourceID)
 	if err != nil {
 		return err
 	}
 
-	source.ErrorCount += 1
+	source.ErrorCount = 0
+	source.LastFetchedAt = &at
 	return c.sourceStorage.UpsertSource(ctx, sourceID, source)
 }
 
+// SourceErrorCountIncr 增加订阅源错误计数并记录本次错误，返回更新后的订阅源
+func (c *Core) SourceErrorCountIncr(
+	ctx context.Context, sourceID uint, fetchErr error, at time.Time,
+) (*model.Source, error) {
+	source, err := c.GetSource(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	source.ErrorCount += 1
+	source.LastError = truncateError(fetchErr)
+	source.LastErrorAt = &at
+	if err := c.sourceStorage.UpsertSource(ctx, sourceID, source); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
 func (c *Core) ToggleSubscriptionNotice(ctx context.Context, userID int64, sourceID uint) error {
 	subscription, err := c.GetSubscription(ctx, userID, sourceID)
 	if err != nil {
@@ -481,10 +833,12 @@ func (c *Core) ToggleSourceUpdateStatus(ctx context.Context, sourceID uint) erro
 		return err
 	}
 
-	if source.ErrorCount < config.ErrorThreshold {
-		source.ErrorCount = config.ErrorThreshold + 1
-	} else {
+	if source.Paused {
+		source.Paused = false
 		source.ErrorCount = 0
+		source.NextFetchAt = nil
+	} else {
+		source.Paused = true
 	}
 	return c.sourceStorage.UpsertSource(ctx, sourceID, source)
 }
@@ -502,6 +856,20 @@ func (c *Core) ToggleSubscriptionTelegraph(ctx context.Context, userID int64, so
 	return c.subscriptionStorage.UpsertSubscription(ctx, userID, sourceID, subscription)
 }
 
+// ToggleSubscriptionMedia 切换订阅是否以 telegram 媒体发送附件
+func (c *Core) ToggleSubscriptionMedia(ctx context.Context, userID int64, sourceID uint) error {
+	subscription, err := c.GetSubscription(ctx, userID, sourceID)
+	if err != nil {
+		return err
+	}
+	if subscription.EnableMedia == 1 {
+		subscription.EnableMedia = 0
+	} else {
+		subscription.EnableMedia = 1
+	}
+	return c.subscriptionStorage.UpsertSubscription(ctx, userID, sourceID, subscription)
+}
+
 func (c *Core) GetSourceAllSubscriptions(
 	ctx context.Context, sourceID uint,
 ) ([]*model.Subscribe, error) {
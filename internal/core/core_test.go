@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
@@ -18,6 +19,8 @@ type mockStorage struct {
 	Content      *mock.MockContent
 	Source       *mock.MockSource
 	Subscription *mock.MockSubscription
+	Delivery     *mock.MockDelivery
+	Search       *mock.MockContentSearch
 	Ctrl         *gomock.Controller
 }
 
@@ -29,9 +32,11 @@ func getTestCore(t *testing.T) (*Core, *mockStorage) {
 		User:         mock.NewMockUser(ctrl),
 		Content:      mock.NewMockContent(ctrl),
 		Source:       mock.NewMockSource(ctrl),
+		Delivery:     mock.NewMockDelivery(ctrl),
+		Search:       mock.NewMockContentSearch(ctrl),
 		Ctrl:         ctrl,
 	}
-	c := NewCore(s.User, s.Content, s.Source, s.Subscription, nil, nil)
+	c := NewCore(s.User, s.Content, s.Source, s.Subscription, s.Delivery, s.Search, nil, nil)
 	return c, s
 }
 
@@ -61,6 +66,7 @@ func TestCore_AddSubscription(t *testing.T) {
 	t.Run(
 		"subscribe fail", func(t *testing.T) {
 			s.Subscription.EXPECT().SubscriptionExist(ctx, userID, sourceID).Return(false, nil).Times(1)
+			s.User.EXPECT().GetUser(ctx, userID).Return(nil, storage.ErrRecordNotFound).Times(1)
 			s.Subscription.EXPECT().AddSubscription(ctx, gomock.Any()).Return(errors.New("err")).Times(1)
 
 			err := c.AddSubscription(ctx, userID, sourceID)
@@ -71,12 +77,29 @@ func TestCore_AddSubscription(t *testing.T) {
 	t.Run(
 		"subscribe ok", func(t *testing.T) {
 			s.Subscription.EXPECT().SubscriptionExist(ctx, userID, sourceID).Return(false, nil).Times(1)
+			s.User.EXPECT().GetUser(ctx, userID).Return(nil, storage.ErrRecordNotFound).Times(1)
 			s.Subscription.EXPECT().AddSubscription(ctx, gomock.Any()).Return(nil).Times(1)
 
 			err := c.AddSubscription(ctx, userID, sourceID)
 			assert.Nil(t, err)
 		},
 	)
+
+	t.Run(
+		"user digest", func(t *testing.T) {
+			s.Subscription.EXPECT().SubscriptionExist(ctx, userID, sourceID).Return(false, nil).Times(1)
+			s.User.EXPECT().GetUser(ctx, userID).Return(&model.User{ID: userID, Digest: "daily 09:00"}, nil).Times(1)
+			s.Subscription.EXPECT().AddSubscription(ctx, gomock.Any()).DoAndReturn(
+				func(ctx context.Context, sub *model.Subscribe) error {
+					assert.Equal(t, "daily 09:00", sub.Digest)
+					return nil
+				},
+			).Times(1)
+
+			err := c.AddSubscription(ctx, userID, sourceID)
+			assert.Nil(t, err)
+		},
+	)
 }
 
 func TestCore_GetUserSubscribedSources(t *testing.T) {
@@ -240,7 +263,7 @@ func TestCore_GetSourceByURL(t *testing.T) {
 
 	t.Run(
 		"source err", func(t *testing.T) {
-			s.Source.EXPECT().GetSourceByURL(ctx, sourceURL).Return(
+			s.Source.EXPECT().GetSourceByURLKey(ctx, SourceURLKey(sourceURL)).Return(
 				nil, errors.New("err"),
 			).Times(1)
 			got, err := c.GetSourceByURL(ctx, sourceURL)
@@ -251,7 +274,7 @@ func TestCore_GetSourceByURL(t *testing.T) {
 
 	t.Run(
 		"source not exist", func(t *testing.T) {
-			s.Source.EXPECT().GetSourceByURL(ctx, sourceURL).Return(
+			s.Source.EXPECT().GetSourceByURLKey(ctx, SourceURLKey(sourceURL)).Return(
 				nil, storage.ErrRecordNotFound,
 			).Times(1)
 			got, err := c.GetSourceByURL(ctx, sourceURL)
@@ -262,7 +285,7 @@ func TestCore_GetSourceByURL(t *testing.T) {
 
 	t.Run(
 		"ok", func(t *testing.T) {
-			s.Source.EXPECT().GetSourceByURL(ctx, sourceURL).Return(
+			s.Source.EXPECT().GetSourceByURLKey(ctx, SourceURLKey(sourceURL)).Return(
 				&model.Source{}, nil,
 			).Times(1)
 			got, err := c.GetSourceByURL(ctx, sourceURL)
@@ -443,6 +466,51 @@ func TestCore_ClearSourceErrorCount(t *testing.T) {
 	)
 }
 
+func TestCore_MarkSourceFetched(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+	sourceID := uint(1)
+	fetchedAt := time.Now()
+
+	s.Source.EXPECT().GetSource(ctx, sourceID).Return(
+		&model.Source{ID: sourceID, ErrorCount: 3}, nil,
+	).Times(1)
+	s.Source.EXPECT().UpsertSource(ctx, sourceID, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, sourceID uint, source *model.Source) error {
+			assert.Equal(t, uint(0), source.ErrorCount)
+			assert.Equal(t, fetchedAt, *source.LastFetchedAt)
+			return nil
+		},
+	).Times(1)
+	assert.Nil(t, c.MarkSourceFetched(ctx, sourceID, fetchedAt))
+}
+
+func TestCore_RetrySource(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+	sourceID := uint(1)
+
+	s.Source.EXPECT().GetSource(ctx, sourceID).Return(
+		&model.Source{ID: sourceID, ErrorCount: 3, Paused: true}, nil,
+	).Times(1)
+	s.Source.EXPECT().UpsertSource(ctx, sourceID, gomock.Any()).DoAndReturn(
+		func(ctx context.Context, sourceID uint, source *model.Source) error {
+			assert.False(t, source.Paused)
+			assert.Equal(t, uint(3), source.ErrorCount)
+			assert.NotNil(t, source.NextFetchAt)
+			return nil
+		},
+	).Times(1)
+	assert.Nil(t, c.RetrySource(ctx, sourceID))
+	select {
+	case <-c.FetchRequests():
+	default:
+		t.Fatal("scheduler not kicked")
+	}
+}
+
 func TestCore_ToggleSubscriptionNotice(t *testing.T) {
 	c, s := getTestCore(t)
 	defer s.Ctrl.Finish()
@@ -583,3 +651,98 @@ func TestCore_ToggleSubscriptionTelegraph(t *testing.T) {
 		},
 	)
 }
+
+func TestCore_SetSubscriptionInterval(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+	userID := int64(123)
+	sourceID := uint(1)
+
+	t.Run(
+		"get subscription err", func(t *testing.T) {
+			s.Subscription.EXPECT().GetSubscription(ctx, userID, sourceID).Return(
+				nil, errors.New("err"),
+			).Times(1)
+			err := c.SetSubscriptionInterval(ctx, userID, sourceID, 5)
+			assert.Error(t, err)
+		},
+	)
+
+	t.Run(
+		"next fetch already sooner", func(t *testing.T) {
+			next := time.Now().Add(time.Minute)
+			s.Subscription.EXPECT().GetSubscription(ctx, userID, sourceID).Return(
+				&model.Subscribe{}, nil,
+			).Times(1)
+			s.Subscription.EXPECT().UpdateSubscription(ctx, userID, sourceID, gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ int64, _ uint, sub *model.Subscribe) error {
+					assert.Equal(t, 5, sub.Interval)
+					assert.True(t, sub.IntervalSet)
+					return nil
+				},
+			).Times(1)
+			s.Source.EXPECT().GetSource(ctx, sourceID).Return(
+				&model.Source{NextFetchAt: &next}, nil,
+			).Times(1)
+
+			err := c.SetSubscriptionInterval(ctx, userID, sourceID, 5)
+			assert.Nil(t, err)
+		},
+	)
+
+	t.Run(
+		"pull next fetch in", func(t *testing.T) {
+			next := time.Now().Add(time.Hour)
+			s.Subscription.EXPECT().GetSubscription(ctx, userID, sourceID).Return(
+				&model.Subscribe{}, nil,
+			).Times(1)
+			s.Subscription.EXPECT().UpdateSubscription(ctx, userID, sourceID, gomock.Any()).Return(nil).Times(1)
+			s.Source.EXPECT().GetSource(ctx, sourceID).Return(
+				&model.Source{NextFetchAt: &next}, nil,
+			).Times(2)
+			s.Source.EXPECT().UpsertSource(ctx, sourceID, gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ uint, source *model.Source) error {
+					assert.True(t, source.NextFetchAt.Before(time.Now().Add(6*time.Minute)))
+					return nil
+				},
+			).Times(1)
+
+			err := c.SetSubscriptionInterval(ctx, userID, sourceID, 5)
+			assert.Nil(t, err)
+		},
+	)
+}
+
+func TestCore_SourceErrorCountIncr(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+	sourceID := uint(1)
+	now := time.Now()
+
+	t.Run(
+		"get source err", func(t *testing.T) {
+			s.Source.EXPECT().GetSource(ctx, sourceID).Return(
+				nil, errors.New("err"),
+			).Times(1)
+			_, err := c.SourceErrorCountIncr(ctx, sourceID, errors.New("fetch failed"), now)
+			assert.Error(t, err)
+		},
+	)
+
+	t.Run(
+		"record error", func(t *testing.T) {
+			s.Source.EXPECT().GetSource(ctx, sourceID).Return(
+				&model.Source{ErrorCount: 2}, nil,
+			).Times(1)
+			s.Source.EXPECT().UpsertSource(ctx, sourceID, gomock.Any()).Return(nil).Times(1)
+
+			source, err := c.SourceErrorCountIncr(ctx, sourceID, errors.New("fetch failed"), now)
+			assert.Nil(t, err)
+			assert.Equal(t, uint(3), source.ErrorCount)
+			assert.Equal(t, "fetch failed", source.LastError)
+			assert.Equal(t, now, *source.LastErrorAt)
+		},
+	)
+}
@@ -0,0 +1,115 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+const (
+	// deliveryMaxAttempts 推送发送失败的最大尝试次数，达到后放弃
+	deliveryMaxAttempts = 8
+	// deliveryRetryBase 推送首次重试的等待时间，之后逐次翻倍
+	deliveryRetryBase = 30 * time.Second
+	// deliveryRetryMax 推送重试等待时间的上限
+	deliveryRetryMax = time.Hour
+)
+
+// EnqueueDeliveries 为每个订阅者的每篇文章写入待发送的推送记录，开启摘要的订阅按用户时区在下一个摘要时间发送，
+// 已存在的记录不会重复写入
+func (c *Core) EnqueueDeliveries(
+	ctx context.Context, contents []*model.Content, subs []*model.Subscribe, at time.Time,
+) error {
+	deliveries := make([]*model.Delivery, 0, len(contents)*len(subs))
+	locations := map[int64]*time.Location{}
+	for _, sub := range subs {
+		nextAttemptAt, digest := at, false
+		if sub.Digest != "" {
+			loc, ok := locations[sub.UserID]
+			if !ok {
+				loc = c.userLocation(ctx, sub.UserID)
+				locations[sub.UserID] = loc
+			}
+			if next, ok := subscriptionDigestAt(sub, at, loc); ok {
+				nextAttemptAt, digest = next, true
+			}
+		}
+		for _, content := range contents {
+			deliveries = append(
+				deliveries, &model.Delivery{
+					ContentHashID: content.HashID,
+					UserID:        sub.UserID,
+					SourceID:      sub.SourceID,
+					Status:        model.DeliveryStatusPending,
+					Digest:        digest,
+					NextAttemptAt: nextAttemptAt,
+				},
+			)
+		}
+	}
+	return c.deliveryStorage.AddDeliveries(ctx, deliveries)
+}
+
+// GetDueDeliveries 获取已到发送时间的推送记录
+func (c *Core) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*model.Delivery, error) {
+	return c.deliveryStorage.GetDueDeliveries(ctx, now, limit)
+}
+
+// GetContentsByHashIDs 按 hash id 批量获取文章
+func (c *Core) GetContentsByHashIDs(ctx context.Context, hashIDs []string) ([]*model.Content, error) {
+	return c.contentStorage.GetContentsByHashIDs(ctx, hashIDs)
+}
+
+// MarkDeliverySent 标记推送已发送
+func (c *Core) MarkDeliverySent(ctx context.Context, delivery *model.Delivery, at time.Time) error {
+	delivery.Status = model.DeliveryStatusSent
+	delivery.Attempts += 1
+	delivery.SentAt = &at
+	return c.deliveryStorage.UpdateDelivery(ctx, delivery)
+}
+
+// RetryDelivery 记录发送失败，按退避时间安排重试，达到最大尝试次数后放弃
+func (c *Core) RetryDelivery(ctx context.Context, delivery *model.Delivery, sendErr error, now time.Time) error {
+	delivery.Attempts += 1
+	delivery.LastError = truncateError(sendErr)
+	if delivery.Attempts >= deliveryMaxAttempts {
+		delivery.Status = model.DeliveryStatusFailed
+		return c.deliveryStorage.UpdateDelivery(ctx, delivery)
+	}
+
+	delay := deliveryRetryBase
+	for i := uint(1); i < delivery.Attempts && delay < deliveryRetryMax; i++ {
+		delay *= 2
+	}
+	if delay > deliveryRetryMax {
+		delay = deliveryRetryMax
+	}
+	delivery.NextAttemptAt = now.Add(delay)
+	return c.deliveryStorage.UpdateDelivery(ctx, delivery)
+}
+
+// MarkDeliveryFiltered 标记推送因过滤规则不发送
+func (c *Core) MarkDeliveryFiltered(ctx context.Context, delivery *model.Delivery) error {
+	delivery.Status = model.DeliveryStatusFiltered
+	return c.deliveryStorage.UpdateDelivery(ctx, delivery)
+}
+
+// HoldDelivery 免打扰期间暂缓推送，until 时再发送
+func (c *Core) HoldDelivery(ctx context.Context, delivery *model.Delivery, until time.Time) error {
+	delivery.NextAttemptAt = until
+	return c.deliveryStorage.UpdateDelivery(ctx, delivery)
+}
+
+// FailDelivery 放弃推送，用于重试也无法成功的错误
+func (c *Core) FailDelivery(ctx context.Context, delivery *model.Delivery, reason error) error {
+	delivery.Status = model.DeliveryStatusFailed
+	delivery.Attempts += 1
+	delivery.LastError = truncateError(reason)
+	return c.deliveryStorage.UpdateDelivery(ctx, delivery)
+}
+
+// PurgeDeliveries 清理 before 之前已结束的推送记录
+func (c *Core) PurgeDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	return c.deliveryStorage.DeleteFinishedDeliveries(ctx, before)
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+func TestCore_EnqueueDeliveries(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+	now := time.Now()
+
+	contents := []*model.Content{{HashID: "a"}, {HashID: "b"}}
+	subs := []*model.Subscribe{{UserID: 1, SourceID: 3}, {UserID: 2, SourceID: 3}}
+	s.Delivery.EXPECT().AddDeliveries(ctx, gomock.Any()).DoAndReturn(
+		func(_ context.Context, deliveries []*model.Delivery) error {
+			assert.Equal(t, 4, len(deliveries))
+			for _, delivery := range deliveries {
+				assert.Equal(t, model.DeliveryStatusPending, delivery.Status)
+				assert.Equal(t, uint(3), delivery.SourceID)
+				assert.Equal(t, now, delivery.NextAttemptAt)
+			}
+			return nil
+		},
+	).Times(1)
+
+	err := c.EnqueueDeliveries(ctx, contents, subs, now)
+	assert.Nil(t, err)
+
+	// 摘要时间按用户时区计算，2024-01-03 10:15 UTC 为上海时间 18:15
+	at := time.Date(2024, 1, 3, 10, 15, 0, 0, time.UTC)
+	digestSub := &model.Subscribe{UserID: 3, SourceID: 3, Digest: "daily 09:00"}
+	s.User.EXPECT().GetUser(ctx, int64(3)).Return(&model.User{ID: 3, Timezone: "Asia/Shanghai"}, nil).Times(1)
+	s.Delivery.EXPECT().AddDeliveries(ctx, gomock.Any()).DoAndReturn(
+		func(_ context.Context, deliveries []*model.Delivery) error {
+			assert.Equal(t, 2, len(deliveries))
+			for _, delivery := range deliveries {
+				assert.True(t, delivery.Digest)
+				assert.Equal(t, time.Date(2024, 1, 4, 1, 0, 0, 0, time.UTC), delivery.NextAttemptAt)
+			}
+			return nil
+		},
+	).Times(1)
+	err = c.EnqueueDeliveries(ctx, contents, []*model.Subscribe{digestSub}, at)
+	assert.Nil(t, err)
+}
+
+func TestCore_RetryDelivery(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+	now := time.Now()
+	sendErr := errors.New("timeout")
+
+	t.Run(
+		"schedule retry", func(t *testing.T) {
+			delivery := &model.Delivery{Status: model.DeliveryStatusPending, Attempts: 2}
+			s.Delivery.EXPECT().UpdateDelivery(ctx, delivery).Return(nil).Times(1)
+
+			err := c.RetryDelivery(ctx, delivery, sendErr, now)
+			assert.Nil(t, err)
+			assert.Equal(t, model.DeliveryStatusPending, delivery.Status)
+			assert.Equal(t, uint(3), delivery.Attempts)
+			assert.Equal(t, "timeout", delivery.LastError)
+			assert.Equal(t, now.Add(4*deliveryRetryBase), delivery.NextAttemptAt)
+		},
+	)
+
+	t.Run(
+		"retry delay capped", func(t *testing.T) {
+			delivery := &model.Delivery{Status: model.DeliveryStatusPending, Attempts: deliveryMaxAttempts - 2}
+			s.Delivery.EXPECT().UpdateDelivery(ctx, delivery).Return(nil).Times(1)
+
+			err := c.RetryDelivery(ctx, delivery, sendErr, now)
+			assert.Nil(t, err)
+			assert.False(t, delivery.NextAttemptAt.After(now.Add(deliveryRetryMax)))
+		},
+	)
+
+	t.Run(
+		"give up", func(t *testing.T) {
+			delivery := &model.Delivery{Status: model.DeliveryStatusPending, Attempts: deliveryMaxAttempts - 1}
+			s.Delivery.EXPECT().UpdateDelivery(ctx, delivery).Return(nil).Times(1)
+
+			err := c.RetryDelivery(ctx, delivery, sendErr, now)
+			assert.Nil(t, err)
+			assert.Equal(t, model.DeliveryStatusFailed, delivery.Status)
+		},
+	)
+}
@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+// ErrInvalidDigest 摘要设置无法解析
+var ErrInvalidDigest = errors.New("invalid digest schedule")
+
+// DigestPeriod 摘要发送周期
+type DigestPeriod string
+
+const (
+	DigestHourly DigestPeriod = "hourly"
+	DigestDaily  DigestPeriod = "daily"
+	DigestWeekly DigestPeriod = "weekly"
+)
+
+// defaultDigestHour 未指定时间时，每日和每周摘要的发送时间
+const defaultDigestHour = 9
+
+// DigestSchedule 摘要发送计划
+type DigestSchedule struct {
+	Period  DigestPeriod
+	Weekday time.Weekday // 只用于每周摘要
+	Hour    int          // 每小时摘要不使用
+	Minute  int
+}
+
+// weekdayNames 按 time.Weekday 顺序排列的星期缩写
+var weekdayNames = [...]string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// ParseDigestSchedule 解析摘要设置：hourly、daily [HH:MM]、weekly [mon..sun] [HH:MM]，
+// 空字符串或 off 表示关闭摘要，返回 nil
+func ParseDigestSchedule(spec string) (*DigestSchedule, error) {
+	fields := strings.Fields(strings.ToLower(spec))
+	if len(fields) == 0 || fields[0] == "off" {
+		if len(fields) > 1 {
+			return nil, ErrInvalidDigest
+		}
+		return nil, nil
+	}
+
+	schedule := &DigestSchedule{Period: DigestPeriod(fields[0]), Hour: defaultDigestHour}
+	args := fields[1:]
+	switch schedule.Period {
+	case DigestHourly:
+		schedule.Hour = 0
+		if len(args) != 0 {
+			return nil, ErrInvalidDigest
+		}
+		return schedule, nil
+	case DigestWeekly:
+		schedule.Weekday = time.Monday
+		if len(args) > 0 {
+			for weekday, name := range weekdayNames {
+				if args[0] == name {
+					schedule.Weekday = time.Weekday(weekday)
+					args = args[1:]
+					break
+				}
+			}
+		}
+	case DigestDaily:
+	default:
+		return nil, ErrInvalidDigest
+	}
+
+	if len(args) > 1 {
+		return nil, ErrInvalidDigest
+	}
+	if len(args) == 1 {
+		t, err := time.Parse("15:04", args[0])
+		if err != nil {
+			return nil, ErrInvalidDigest
+		}
+		schedule.Hour, schedule.Minute = t.Hour(), t.Minute()
+	}
+	return schedule, nil
+}
+
+// String 摘要设置的规范写法，可以被 ParseDigestSchedule 解析
+func (s *DigestSchedule) String() string {
+	switch s.Period {
+	case DigestHourly:
+		return string(DigestHourly)
+	case DigestWeekly:
+		return fmt.Sprintf("%s %s %02d:%02d", DigestWeekly, weekdayNames[s.Weekday], s.Hour, s.Minute)
+	default:
+		return fmt.Sprintf("%s %02d:%02d", DigestDaily, s.Hour, s.Minute)
+	}
+}
+
+// Next after 之后（不含）的下一个发送时间，使用 after 所在的时区
+func (s *DigestSchedule) Next(after time.Time) time.Time {
+	if s.Period == DigestHourly {
+		hour := time.Date(after.Year(), after.Month(), after.Day(), after.Hour(), 0, 0, 0, after.Location())
+		return hour.Add(time.Hour)
+	}
+
+	next := time.Date(after.Year(), after.Month(), after.Day(), s.Hour, s.Minute, 0, 0, after.Location())
+	if s.Period == DigestWeekly {
+		next = next.AddDate(0, 0, (int(s.Weekday)-int(next.Weekday())+7)%7)
+	}
+	for !next.After(after) {
+		if s.Period == DigestWeekly {
+			next = next.AddDate(0, 0, 7)
+		} else {
+			next = next.AddDate(0, 0, 1)
+		}
+	}
+	return next
+}
+
+// subscriptionDigestAt 订阅开启摘要时返回 at 之后的下一个摘要发送时间，摘要时间按 loc 时区计算，
+// 返回值使用 at 的时区
+func subscriptionDigestAt(sub *model.Subscribe, at time.Time, loc *time.Location) (time.Time, bool) {
+	schedule, err := ParseDigestSchedule(sub.Digest)
+	if err != nil || schedule == nil {
+		return time.Time{}, false
+	}
+	return schedule.Next(at.In(loc)).In(at.Location()), true
+}
+
+// SetSubscriptionDigest 设置订阅的摘要计划，schedule 为 nil 时关闭摘要，
+// 已排队的摘要内容改按新计划发送，关闭时立即发送
+func (c *Core) SetSubscriptionDigest(
+	ctx context.Context, userID int64, sourceID uint, schedule *DigestSchedule, now time.Time,
+) error {
+	subscription, err := c.GetSubscription(ctx, userID, sourceID)
+	if err != nil {
+		return err
+	}
+
+	subscription.Digest = ""
+	if schedule != nil {
+		subscription.Digest = schedule.String()
+	}
+	if err := c.subscriptionStorage.UpsertSubscription(ctx, userID, sourceID, subscription); err != nil {
+		return err
+	}
+
+	next := now
+	if schedule != nil {
+		next = schedule.Next(now.In(c.userLocation(ctx, userID))).In(now.Location())
+	}
+	return c.deliveryStorage.RescheduleDigestDeliveries(ctx, userID, sourceID, next)
+}
+
+// SetUserDigest 为用户的所有订阅设置摘要计划，该计划保存在用户上，之后添加的订阅同样使用
+func (c *Core) SetUserDigest(ctx context.Context, userID int64, schedule *DigestSchedule, now time.Time) error {
+	digest := ""
+	if schedule != nil {
+		digest = schedule.String()
+	}
+	if err := c.userStorage.SetUserDigest(ctx, userID, digest); err != nil {
+		return err
+	}
+
+	sources, err := c.GetUserSubscribedSources(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		if err := c.SetSubscriptionDigest(ctx, userID, source.ID, schedule, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
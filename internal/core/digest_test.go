@@ -0,0 +1,136 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+func TestParseDigestSchedule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    string
+		wantErr bool
+	}{
+		{spec: "", want: ""},
+		{spec: "off", want: ""},
+		{spec: "hourly", want: "hourly"},
+		{spec: "daily", want: "daily 09:00"},
+		{spec: "Daily 18:30", want: "daily 18:30"},
+		{spec: "weekly", want: "weekly mon 09:00"},
+		{spec: "weekly fri", want: "weekly fri 09:00"},
+		{spec: "weekly sun 07:05", want: "weekly sun 07:05"},
+		{spec: "weekly 20:00", want: "weekly mon 20:00"},
+		{spec: "daily 25:00", wantErr: true},
+		{spec: "hourly 10:00", wantErr: true},
+		{spec: "monthly", wantErr: true},
+		{spec: "off now", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.spec, func(t *testing.T) {
+				schedule, err := ParseDigestSchedule(tt.spec)
+				if tt.wantErr {
+					assert.ErrorIs(t, err, ErrInvalidDigest)
+					return
+				}
+				assert.Nil(t, err)
+				if tt.want == "" {
+					assert.Nil(t, schedule)
+					return
+				}
+				assert.Equal(t, tt.want, schedule.String())
+			},
+		)
+	}
+}
+
+func TestDigestSchedule_Next(t *testing.T) {
+	// 2024-01-03 is a Wednesday
+	now := time.Date(2024, 1, 3, 10, 15, 0, 0, time.UTC)
+
+	t.Run(
+		"hourly", func(t *testing.T) {
+			schedule := &DigestSchedule{Period: DigestHourly}
+			assert.Equal(t, time.Date(2024, 1, 3, 11, 0, 0, 0, time.UTC), schedule.Next(now))
+		},
+	)
+
+	t.Run(
+		"daily later today", func(t *testing.T) {
+			schedule := &DigestSchedule{Period: DigestDaily, Hour: 18}
+			assert.Equal(t, time.Date(2024, 1, 3, 18, 0, 0, 0, time.UTC), schedule.Next(now))
+		},
+	)
+
+	t.Run(
+		"daily tomorrow", func(t *testing.T) {
+			schedule := &DigestSchedule{Period: DigestDaily, Hour: 9}
+			assert.Equal(t, time.Date(2024, 1, 4, 9, 0, 0, 0, time.UTC), schedule.Next(now))
+		},
+	)
+
+	t.Run(
+		"weekly", func(t *testing.T) {
+			schedule := &DigestSchedule{Period: DigestWeekly, Weekday: time.Monday, Hour: 9}
+			assert.Equal(t, time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC), schedule.Next(now))
+		},
+	)
+
+	t.Run(
+		"weekly same day passed", func(t *testing.T) {
+			schedule := &DigestSchedule{Period: DigestWeekly, Weekday: time.Wednesday, Hour: 9}
+			assert.Equal(t, time.Date(2024, 1, 10, 9, 0, 0, 0, time.UTC), schedule.Next(now))
+		},
+	)
+}
+
+func TestCore_SetSubscriptionDigest(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+	userID := int64(123)
+	sourceID := uint(1)
+	now := time.Date(2024, 1, 3, 10, 15, 0, 0, time.UTC)
+
+	t.Run(
+		"enable", func(t *testing.T) {
+			s.Subscription.EXPECT().GetSubscription(ctx, userID, sourceID).Return(&model.Subscribe{}, nil).Times(1)
+			s.Subscription.EXPECT().UpsertSubscription(ctx, userID, sourceID, gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ int64, _ uint, sub *model.Subscribe) error {
+					assert.Equal(t, "daily 18:00", sub.Digest)
+					return nil
+				},
+			).Times(1)
+			s.User.EXPECT().GetUser(ctx, userID).Return(&model.User{ID: userID, Timezone: "UTC"}, nil).Times(1)
+			s.Delivery.EXPECT().RescheduleDigestDeliveries(
+				ctx, userID, sourceID, time.Date(2024, 1, 3, 18, 0, 0, 0, time.UTC),
+			).Return(nil).Times(1)
+
+			schedule := &DigestSchedule{Period: DigestDaily, Hour: 18}
+			assert.Nil(t, c.SetSubscriptionDigest(ctx, userID, sourceID, schedule, now))
+		},
+	)
+
+	t.Run(
+		"disable sends queued digest now", func(t *testing.T) {
+			s.Subscription.EXPECT().GetSubscription(ctx, userID, sourceID).Return(
+				&model.Subscribe{Digest: "hourly"}, nil,
+			).Times(1)
+			s.Subscription.EXPECT().UpsertSubscription(ctx, userID, sourceID, gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ int64, _ uint, sub *model.Subscribe) error {
+					assert.Empty(t, sub.Digest)
+					return nil
+				},
+			).Times(1)
+			s.Delivery.EXPECT().RescheduleDigestDeliveries(ctx, userID, sourceID, now).Return(nil).Times(1)
+
+			assert.Nil(t, c.SetSubscriptionDigest(ctx, userID, sourceID, nil, now))
+		},
+	)
+}
@@ -0,0 +1,220 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+
+	strip "github.com/grokify/html-strip-tags-go"
+	"github.com/mmcdole/gofeed"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+// InvalidFilterRuleError 过滤规则无法解析
+type InvalidFilterRuleError struct {
+	Rule string
+	Err  error
+}
+
+func (e *InvalidFilterRuleError) Error() string {
+	return fmt.Sprintf("invalid filter rule %s: %v", e.Rule, e.Err)
+}
+
+func (e *InvalidFilterRuleError) Unwrap() error {
+	return e.Err
+}
+
+// FilterKind 过滤规则类型
+type FilterKind string
+
+const (
+	// FilterInclude 设置后只推送匹配任一规则的文章
+	FilterInclude FilterKind = "include"
+	// FilterExclude 不推送匹配任一规则的文章
+	FilterExclude FilterKind = "exclude"
+)
+
+// ParseFilterRules 解析以逗号分隔的过滤规则，以 / 包裹的规则为正则表达式，其余为关键词，均不区分大小写。
+// 正则表达式中可以包含逗号，如 /\d{2,4}/
+func ParseFilterRules(text string) ([]string, error) {
+	var rules []string
+	for _, rule := range splitFilterRules(text) {
+		if isRegexRule(rule) {
+			if _, err := compileRegexRule(rule); err != nil {
+				return nil, &InvalidFilterRuleError{Rule: rule, Err: err}
+			}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// FilterRules 订阅中保存的过滤规则列表
+func FilterRules(stored string) []string {
+	if stored == "" {
+		return nil
+	}
+	return strings.Split(stored, "\n")
+}
+
+// ContentMatchesFilter 文章是否通过订阅的过滤规则，匹配标题、正文、摘要、作者和分类
+func ContentMatchesFilter(sub *model.Subscribe, content *model.Content) bool {
+	include := FilterRules(sub.IncludeKeywords)
+	exclude := FilterRules(sub.ExcludeKeywords)
+	if len(include) == 0 && len(exclude) == 0 {
+		return true
+	}
+
+	text := strings.Join(
+		[]string{
+			content.Title,
+			strip.StripTags(html.UnescapeString(content.Description)),
+			strip.StripTags(html.UnescapeString(content.Summary)),
+			content.Author,
+			content.Categories,
+		}, "\n",
+	)
+	for _, rule := range exclude {
+		if matchFilterRule(rule, text) {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, rule := range include {
+		if matchFilterRule(rule, text) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSubscriptionFilter 设置订阅的包含或排除规则，rules 为空时清除该类规则
+func (c *Core) SetSubscriptionFilter(
+	ctx context.Context, userID int64, sourceID uint, kind FilterKind, rules []string,
+) error {
+	subscription, err := c.GetSubscription(ctx, userID, sourceID)
+	if err != nil {
+		return err
+	}
+
+	switch kind {
+	case FilterInclude:
+		subscription.IncludeKeywords = strings.Join(rules, "\n")
+	case FilterExclude:
+		subscription.ExcludeKeywords = strings.Join(rules, "\n")
+	default:
+		return fmt.Errorf("unknown filter kind %q", kind)
+	}
+	return c.subscriptionStorage.UpsertSubscription(ctx, userID, sourceID, subscription)
+}
+
+// ClearSubscriptionFilter 清除订阅的所有过滤规则
+func (c *Core) ClearSubscriptionFilter(ctx context.Context, userID int64, sourceID uint) error {
+	subscription, err := c.GetSubscription(ctx, userID, sourceID)
+	if err != nil {
+		return err
+	}
+
+	subscription.IncludeKeywords = ""
+	subscription.ExcludeKeywords = ""
+	return c.subscriptionStorage.UpsertSubscription(ctx, userID, sourceID, subscription)
+}
+
+// splitFilterRules 按逗号分隔规则并去掉空白和空规则，以 / 开头的规则到后面紧跟逗号或结尾的 / 为止
+func splitFilterRules(text string) []string {
+	var rules []string
+	for text != "" {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		end := strings.IndexByte(text, ',')
+		if strings.HasPrefix(text, "/") {
+			if regexEnd := regexRuleEnd(text); regexEnd > 0 {
+				end = regexEnd
+			}
+		}
+		rule := text
+		text = ""
+		if end >= 0 && end < len(rule) {
+			rule, text = rule[:end], rule[end+1:]
+		}
+		if rule = strings.TrimSpace(rule); rule != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// regexRuleEnd 以 / 开头的规则后第一个逗号的位置，该逗号之前只有空白且紧跟结束的 /，
+// 规则在结尾结束时返回 len(text)，找不到结束的 / 时返回 -1。转义的 \/ 不作为结束
+func regexRuleEnd(text string) int {
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '/':
+			rest := strings.TrimLeftFunc(text[i+1:], unicode.IsSpace)
+			if rest == "" {
+				return len(text)
+			}
+			if rest[0] == ',' {
+				return len(text) - len(rest)
+			}
+		}
+	}
+	return -1
+}
+
+func isRegexRule(rule string) bool {
+	return len(rule) > 2 && strings.HasPrefix(rule, "/") && strings.HasSuffix(rule, "/")
+}
+
+func compileRegexRule(rule string) (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + rule[1:len(rule)-1])
+}
+
+// regexRuleCache 编译过的正则规则，每篇文章和每个订阅者都要匹配，避免重复编译。无法编译的规则保存为 nil
+var regexRuleCache sync.Map
+
+// cachedRegexRule 编译并缓存正则规则，无法编译时返回 nil
+func cachedRegexRule(rule string) *regexp.Regexp {
+	if re, ok := regexRuleCache.Load(rule); ok {
+		return re.(*regexp.Regexp)
+	}
+	re, err := compileRegexRule(rule)
+	if err != nil {
+		re = nil
+	}
+	regexRuleCache.Store(rule, re)
+	return re
+}
+
+func matchFilterRule(rule, text string) bool {
+	if isRegexRule(rule) {
+		re := cachedRegexRule(rule)
+		if re == nil {
+			return false
+		}
+		return re.MatchString(text)
+	}
+	return strings.Contains(strings.ToLower(text), strings.ToLower(rule))
+}
+
+// itemAuthor 文章作者，多个作者以逗号分隔
+func itemAuthor(item *gofeed.Item) string {
+	var names []string
+	for _, author := range item.Authors {
+		if author != nil && author.Name != "" {
+			names = append(names, author.Name)
+		}
+	}
+	if len(names) == 0 && item.Author != nil {
+		names = append(names, item.Author.Name)
+	}
+	return strings.Join(names, ", ")
+}
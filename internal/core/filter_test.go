@@ -0,0 +1,144 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+func TestParseFilterRules(t *testing.T) {
+	t.Run(
+		"keywords and regex", func(t *testing.T) {
+			rules, err := ParseFilterRules(" golang, /rust(lang)?/ ,, release notes ")
+			assert.Nil(t, err)
+			assert.Equal(t, []string{"golang", "/rust(lang)?/", "release notes"}, rules)
+		},
+	)
+
+	t.Run(
+		"regex with comma", func(t *testing.T) {
+			rules, err := ParseFilterRules(`/\d{2,4}/, go , /a\/b,c/ ,/usr, /x/y`)
+			assert.Nil(t, err)
+			assert.Equal(t, []string{`/\d{2,4}/`, "go", `/a\/b,c/`, "/usr", "/x/y"}, rules)
+		},
+	)
+
+	t.Run(
+		"invalid regex", func(t *testing.T) {
+			_, err := ParseFilterRules("ok, /(/")
+			var ruleErr *InvalidFilterRuleError
+			assert.True(t, errors.As(err, &ruleErr))
+			assert.Equal(t, "/(/", ruleErr.Rule)
+		},
+	)
+
+	t.Run(
+		"empty", func(t *testing.T) {
+			rules, err := ParseFilterRules("  ")
+			assert.Nil(t, err)
+			assert.Nil(t, rules)
+		},
+	)
+}
+
+func TestMatchFilterRule(t *testing.T) {
+	assert.True(t, matchFilterRule(`/\d{2,4}/`, "version 123"))
+	assert.False(t, matchFilterRule(`/\d{2,4}/`, "version 1"))
+	// 编译结果被缓存，无法编译的规则不匹配
+	assert.NotNil(t, cachedRegexRule(`/\d{2,4}/`))
+	assert.Same(t, cachedRegexRule(`/\d{2,4}/`), cachedRegexRule(`/\d{2,4}/`))
+	assert.False(t, matchFilterRule("/(/", "("))
+}
+
+func TestContentMatchesFilter(t *testing.T) {
+	content := &model.Content{
+		Title:       "Go 1.22 released",
+		Description: "<p>Loop variables &amp; <b>range</b> over integers</p>",
+		Author:      "The Go Team",
+		Categories:  "programming\nrelease",
+	}
+
+	tests := []struct {
+		name    string
+		include string
+		exclude string
+		want    bool
+	}{
+		{"no rules", "", "", true},
+		{"include title keyword", "GO 1.22", "", true},
+		{"include description text", "range over", "", true},
+		{"html tags are not matched", "<b>", "", false},
+		{"include author", "go team", "", true},
+		{"include category", "programming", "", true},
+		{"include none matched", "rust\npython", "", false},
+		{"include regex", `/go\s?1\.2\d/`, "", true},
+		{"exclude keyword", "", "release", false},
+		{"exclude wins over include", "go", "integers", false},
+		{"exclude not matched", "", "rust", true},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				sub := &model.Subscribe{IncludeKeywords: tt.include, ExcludeKeywords: tt.exclude}
+				assert.Equal(t, tt.want, ContentMatchesFilter(sub, content))
+			},
+		)
+	}
+}
+
+func TestCore_SetSubscriptionFilter(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+	userID := int64(123)
+	sourceID := uint(1)
+
+	t.Run(
+		"get subscription err", func(t *testing.T) {
+			s.Subscription.EXPECT().GetSubscription(ctx, userID, sourceID).Return(
+				nil, errors.New("err"),
+			).Times(1)
+			err := c.SetSubscriptionFilter(ctx, userID, sourceID, FilterInclude, []string{"go"})
+			assert.Error(t, err)
+		},
+	)
+
+	t.Run(
+		"set include", func(t *testing.T) {
+			s.Subscription.EXPECT().GetSubscription(ctx, userID, sourceID).Return(
+				&model.Subscribe{ExcludeKeywords: "ads"}, nil,
+			).Times(1)
+			s.Subscription.EXPECT().UpsertSubscription(ctx, userID, sourceID, gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ int64, _ uint, sub *model.Subscribe) error {
+					assert.Equal(t, "go\n/rust/", sub.IncludeKeywords)
+					assert.Equal(t, "ads", sub.ExcludeKeywords)
+					return nil
+				},
+			).Times(1)
+			err := c.SetSubscriptionFilter(ctx, userID, sourceID, FilterInclude, []string{"go", "/rust/"})
+			assert.Nil(t, err)
+		},
+	)
+
+	t.Run(
+		"clear", func(t *testing.T) {
+			s.Subscription.EXPECT().GetSubscription(ctx, userID, sourceID).Return(
+				&model.Subscribe{IncludeKeywords: "go", ExcludeKeywords: "ads"}, nil,
+			).Times(1)
+			s.Subscription.EXPECT().UpsertSubscription(ctx, userID, sourceID, gomock.Any()).DoAndReturn(
+				func(_ context.Context, _ int64, _ uint, sub *model.Subscribe) error {
+					assert.Empty(t, sub.IncludeKeywords)
+					assert.Empty(t, sub.ExcludeKeywords)
+					return nil
+				},
+			).Times(1)
+			err := c.ClearSubscriptionFilter(ctx, userID, sourceID)
+			assert.Nil(t, err)
+		},
+	)
+}
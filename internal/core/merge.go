@@ -0,0 +1,121 @@
+package core
+
+import (
+	"context"
+	"net/url"
+	"sort"
+
+	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+// fillSourceURLKeys 为还没有 URLKey 的订阅源补上链接的规范形式
+func (c *Core) fillSourceURLKeys(ctx context.Context) error {
+	sources, err := c.sourceStorage.GetSources(ctx)
+	if err != nil {
+		return err
+	}
+	for _, source := range sources {
+		if source.URLKey != "" {
+			continue
+		}
+		source.URLKey = SourceURLKey(source.Link)
+		if err := c.sourceStorage.UpsertSource(ctx, source.ID, source); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MergeDuplicateSources 重新计算所有订阅源链接的规范形式，把规范形式相同的订阅源连同订阅、
+// 文章和推送记录合并到一起，返回被合并掉的订阅源数。依次优先保留没有暂停的、https 的、没有错误的、
+// 最早创建的订阅源，保留的订阅源是 http 而组内有 https 链接时改用 https；有自定义请求设置的订阅源不参与合并
+func (c *Core) MergeDuplicateSources(ctx context.Context) (int, error) {
+	sources, err := c.sourceStorage.GetSources(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	groups := map[string][]*model.Source{}
+	var keys []string
+	for _, source := range sources {
+		key := SourceURLKey(source.Link)
+		if source.URLKey != key {
+			source.URLKey = key
+			if err := c.sourceStorage.UpsertSource(ctx, source.ID, source); err != nil {
+				return 0, err
+			}
+		}
+		if !source.RequestSettings.IsEmpty() {
+			continue
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], source)
+	}
+
+	merged := 0
+	for _, key := range keys {
+		group := groups[key]
+		if len(group) < 2 {
+			continue
+		}
+		sort.Slice(
+			group, func(i, j int) bool {
+				a, b := group[i], group[j]
+				if a.Paused != b.Paused {
+					return !a.Paused
+				}
+				if isHTTPS(a.Link) != isHTTPS(b.Link) {
+					return isHTTPS(a.Link)
+				}
+				if (a.ErrorCount == 0) != (b.ErrorCount == 0) {
+					return a.ErrorCount == 0
+				}
+				return a.ID < b.ID
+			},
+		)
+		into := group[0]
+		for _, from := range group[1:] {
+			if err := c.upgradeSourceScheme(ctx, into, from.Link); err != nil {
+				return merged, err
+			}
+		}
+		for _, from := range group[1:] {
+			subs, err := c.GetSourceAllSubscriptions(ctx, from.ID)
+			if err != nil {
+				return merged, err
+			}
+			if err := c.mergeSource(ctx, from, into, subs); err != nil {
+				return merged, err
+			}
+			log.Infof("merged duplicate source [%d]%s into [%d]%s", from.ID, from.DisplayLink(), into.ID, into.DisplayLink())
+			merged++
+		}
+	}
+	return merged, nil
+}
+
+// upgradeSourceScheme 订阅源的链接是 http 而 link 是同一订阅源的 https 链接时，把订阅源的链接改为 link。
+// 原链接保留在 OriginLink 中，已入库文章的 hash id 不变
+func (c *Core) upgradeSourceScheme(ctx context.Context, source *model.Source, link string) error {
+	if isHTTPS(source.Link) || !isHTTPS(link) || SourceURLKey(source.Link) != SourceURLKey(link) {
+		return nil
+	}
+	oldLink := source.DisplayLink()
+	if source.OriginLink == "" {
+		source.OriginLink = source.Link
+	}
+	source.Link = NormalizeSourceURL(link)
+	if err := c.sourceStorage.UpsertSource(ctx, source.ID, source); err != nil {
+		return err
+	}
+	log.Infof("source [%d]%s upgraded to %s", source.ID, oldLink, source.DisplayLink())
+	return nil
+}
+
+func isHTTPS(link string) bool {
+	u, err := url.Parse(link)
+	return err == nil && u.Scheme == "https"
+}
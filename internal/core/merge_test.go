@@ -0,0 +1,107 @@
+package core
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+	"github.com/zintus/flowerss-bot/internal/storage"
+)
+
+func TestCore_MergeDuplicateSources(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+
+	sources := []*model.Source{
+		{ID: 1, Link: "http://example.com/feed/", URLKey: "//example.com/feed", ErrorCount: 3},
+		{ID: 2, Link: "https://www.example.com/feed"},
+		{ID: 3, Link: "https://example.com/feed#top", URLKey: "//example.com/feed"},
+		{ID: 4, Link: "https://example.com/feed", RequestSettings: model.RequestSettings{UserAgent: "reader"}},
+		{ID: 5, Link: "https://other.example.com/feed", URLKey: "//other.example.com/feed"},
+	}
+	s.Source.EXPECT().GetSources(ctx).Return(sources, nil)
+	// 只保存规范形式有变化的订阅源
+	s.Source.EXPECT().UpsertSource(ctx, uint(2), gomock.Any()).Return(nil)
+	s.Source.EXPECT().UpsertSource(ctx, uint(4), gomock.Any()).Return(nil)
+
+	// 保留没有错误且最早创建的 2
+	for _, from := range []uint{1, 3} {
+		from := from
+		s.Subscription.EXPECT().GetSubscriptionsBySourceID(ctx, from, gomock.Any()).Return(
+			&storage.GetSubscriptionsResult{Subscriptions: []*model.Subscribe{{UserID: int64(from * 10), SourceID: from}}}, nil,
+		)
+		s.Subscription.EXPECT().SubscriptionExist(ctx, int64(from*10), uint(2)).Return(false, nil)
+		s.Subscription.EXPECT().UpdateSubscription(ctx, int64(from*10), from, gomock.Any()).DoAndReturn(
+			func(ctx context.Context, userID int64, sourceID uint, sub *model.Subscribe) error {
+				assert.Equal(t, uint(2), sub.SourceID)
+				assert.NotNil(t, sub.LastDeliveredAt)
+				return nil
+			},
+		)
+		s.Content.EXPECT().MoveSourceContents(ctx, from, uint(2)).Return(int64(1), nil)
+		s.Delivery.EXPECT().MoveSourceDeliveries(ctx, from, uint(2)).Return(nil)
+		s.Source.EXPECT().Delete(ctx, from).Return(nil)
+	}
+
+	merged, err := c.MergeDuplicateSources(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, merged)
+	assert.Equal(t, "//example.com/feed", sources[1].URLKey)
+}
+
+func TestCore_MergeDuplicateSources_Survivor(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+
+	sources := []*model.Source{
+		{ID: 1, Link: "http://example.com/feed", URLKey: "//example.com/feed", Paused: true},
+		{ID: 2, Link: "http://www.example.com/feed", URLKey: "//example.com/feed"},
+		{ID: 3, Link: "https://example.com/feed/", URLKey: "//example.com/feed", Paused: true},
+	}
+	s.Source.EXPECT().GetSources(ctx).Return(sources, nil)
+	// 保留没有暂停的 2，链接改为组内的 https 链接
+	s.Source.EXPECT().UpsertSource(ctx, uint(2), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, id uint, source *model.Source) error {
+			assert.Equal(t, "https://example.com/feed/", source.Link)
+			assert.Equal(t, "http://www.example.com/feed", source.OriginLink)
+			return nil
+		},
+	)
+	for _, from := range []uint{1, 3} {
+		s.Subscription.EXPECT().GetSubscriptionsBySourceID(ctx, from, gomock.Any()).Return(
+			&storage.GetSubscriptionsResult{}, nil,
+		)
+		s.Content.EXPECT().MoveSourceContents(ctx, from, uint(2)).Return(int64(0), nil)
+		s.Delivery.EXPECT().MoveSourceDeliveries(ctx, from, uint(2)).Return(nil)
+		s.Source.EXPECT().Delete(ctx, from).Return(nil)
+	}
+
+	merged, err := c.MergeDuplicateSources(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, merged)
+}
+
+func TestCore_CreateSource_UpgradeScheme(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+
+	existing := &model.Source{ID: 1, Link: "http://example.com/feed", URLKey: "//example.com/feed"}
+	s.Source.EXPECT().GetSourceByURLKey(ctx, "//example.com/feed").Return(existing, nil).Times(2)
+	s.Source.EXPECT().UpsertSource(ctx, uint(1), gomock.Any()).Return(nil)
+
+	got, err := c.CreateSource(ctx, "https://www.example.com/feed")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://www.example.com/feed", got.Link)
+	assert.Equal(t, "http://example.com/feed", got.ContentKey())
+
+	// 已经是 https 的订阅源不会被改回 http
+	got, err = c.CreateSource(ctx, "http://example.com/feed")
+	assert.Nil(t, err)
+	assert.Equal(t, "https://www.example.com/feed", got.Link)
+}
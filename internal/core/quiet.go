@@ -0,0 +1,182 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+	"github.com/zintus/flowerss-bot/internal/storage"
+)
+
+// ErrInvalidQuietHours 免打扰时间无法解析
+var ErrInvalidQuietHours = errors.New("invalid quiet hours")
+
+// QuietMode 免打扰期间推送的处理方式
+type QuietMode string
+
+const (
+	// QuietHold 免打扰期间暂存推送，结束后再发送
+	QuietHold QuietMode = "hold"
+	// QuietSilent 免打扰期间照常推送，但不发出通知
+	QuietSilent QuietMode = "silent"
+)
+
+// QuietHours 用户的免打扰时间段，结束时间早于开始时间表示跨越零点
+type QuietHours struct {
+	Start    int // 自零点起的分钟数
+	End      int
+	Mode     QuietMode
+	Location *time.Location
+}
+
+// ParseQuietHours 解析 HH:MM-HH:MM 格式的免打扰时间段，Location 为 nil
+func ParseQuietHours(window string, mode QuietMode) (*QuietHours, error) {
+	if mode != QuietHold && mode != QuietSilent {
+		return nil, ErrInvalidQuietHours
+	}
+	parts := strings.Split(window, "-")
+	if len(parts) != 2 {
+		return nil, ErrInvalidQuietHours
+	}
+	start, err := parseClock(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	if start == end {
+		return nil, ErrInvalidQuietHours
+	}
+	return &QuietHours{Start: start, End: end, Mode: mode}, nil
+}
+
+// LoadTimezone 按 IANA 名称加载时区，如 Europe/Berlin
+func LoadTimezone(name string) (*time.Location, error) {
+	// time.LoadLocation 会把空字符串当作 UTC，这里不允许
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("unknown time zone %q", name)
+	}
+	return time.LoadLocation(name)
+}
+
+// String 免打扰时间段的 HH:MM-HH:MM 写法
+func (q *QuietHours) String() string {
+	return formatClock(q.Start) + "-" + formatClock(q.End)
+}
+
+// Until now 处于免打扰时间段内时，返回时间段结束的时间
+func (q *QuietHours) Until(now time.Time) (time.Time, bool) {
+	if q == nil {
+		return time.Time{}, false
+	}
+	local := now
+	if q.Location != nil {
+		local = now.In(q.Location)
+	}
+	minute := local.Hour()*60 + local.Minute()
+	var active bool
+	if q.Start < q.End {
+		active = minute >= q.Start && minute < q.End
+	} else {
+		active = minute >= q.Start || minute < q.End
+	}
+	if !active {
+		return time.Time{}, false
+	}
+
+	end := time.Date(local.Year(), local.Month(), local.Day(), q.End/60, q.End%60, 0, 0, local.Location())
+	if !end.After(local) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end.In(now.Location()), true
+}
+
+// UserLocation 用户设置的时区，未设置或无法加载时使用服务器时区
+func UserLocation(user *model.User) *time.Location {
+	if user == nil || user.Timezone == "" {
+		return time.Local
+	}
+	loc, err := LoadTimezone(user.Timezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// UserQuietHours 用户保存的免打扰设置，未开启时返回 nil
+func UserQuietHours(user *model.User) *QuietHours {
+	if user == nil || user.QuietStart == "" {
+		return nil
+	}
+	mode := QuietMode(user.QuietMode)
+	if mode == "" {
+		mode = QuietHold
+	}
+	quiet, err := ParseQuietHours(user.QuietStart+"-"+user.QuietEnd, mode)
+	if err != nil {
+		return nil
+	}
+	quiet.Location = UserLocation(user)
+	return quiet
+}
+
+// GetUserQuietHours 获取用户的免打扰设置，用户不存在或未开启时返回 nil
+func (c *Core) GetUserQuietHours(ctx context.Context, userID int64) (*QuietHours, error) {
+	user, err := c.userStorage.GetUser(ctx, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return UserQuietHours(user), nil
+}
+
+// SetUserQuietHours 设置用户的免打扰时间，quiet 为 nil 时关闭免打扰，location 为 nil 时保留原有时区
+func (c *Core) SetUserQuietHours(ctx context.Context, userID int64, quiet *QuietHours, location *time.Location) error {
+	user, err := c.userStorage.GetUser(ctx, userID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrRecordNotFound) {
+			return err
+		}
+		user = &model.User{ID: userID}
+	}
+
+	if location != nil {
+		user.Timezone = location.String()
+	}
+	user.QuietStart, user.QuietEnd, user.QuietMode = "", "", ""
+	if quiet != nil {
+		user.QuietStart = formatClock(quiet.Start)
+		user.QuietEnd = formatClock(quiet.End)
+		user.QuietMode = string(quiet.Mode)
+	}
+	return c.userStorage.SetUserQuietHours(ctx, user)
+}
+
+// userLocation 按用户 id 获取时区，获取失败时使用服务器时区
+func (c *Core) userLocation(ctx context.Context, userID int64) *time.Location {
+	user, err := c.userStorage.GetUser(ctx, userID)
+	if err != nil {
+		return time.Local
+	}
+	return UserLocation(user)
+}
+
+// parseClock 将 HH:MM 解析为自零点起的分钟数
+func parseClock(text string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(text))
+	if err != nil {
+		return 0, ErrInvalidQuietHours
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func formatClock(minutes int) string {
+	return fmt.Sprintf("%02d:%02d", minutes/60, minutes%60)
+}
@@ -0,0 +1,137 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+	"github.com/zintus/flowerss-bot/internal/storage"
+)
+
+func TestParseQuietHours(t *testing.T) {
+	quiet, err := ParseQuietHours("23:00-07:30", QuietHold)
+	assert.Nil(t, err)
+	assert.Equal(t, 23*60, quiet.Start)
+	assert.Equal(t, 7*60+30, quiet.End)
+	assert.Equal(t, "23:00-07:30", quiet.String())
+
+	for _, window := range []string{"", "23:00", "23:00-", "24:00-07:00", "07:00-07:00"} {
+		_, err := ParseQuietHours(window, QuietHold)
+		assert.ErrorIs(t, err, ErrInvalidQuietHours, window)
+	}
+	_, err = ParseQuietHours("23:00-07:00", QuietMode("loud"))
+	assert.ErrorIs(t, err, ErrInvalidQuietHours)
+}
+
+func TestQuietHours_Until(t *testing.T) {
+	berlin, err := LoadTimezone("Europe/Berlin")
+	assert.Nil(t, err)
+	overnight := &QuietHours{Start: 23 * 60, End: 7 * 60, Mode: QuietHold, Location: berlin}
+	daytime := &QuietHours{Start: 12 * 60, End: 14 * 60, Mode: QuietSilent, Location: time.UTC}
+
+	tests := []struct {
+		name   string
+		quiet  *QuietHours
+		now    time.Time
+		active bool
+		until  time.Time
+	}{
+		{
+			// 柏林冬令时为 UTC+1
+			name: "before midnight", quiet: overnight, now: time.Date(2024, 1, 3, 22, 30, 0, 0, time.UTC),
+			active: true, until: time.Date(2024, 1, 4, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "after midnight", quiet: overnight, now: time.Date(2024, 1, 4, 3, 0, 0, 0, time.UTC),
+			active: true, until: time.Date(2024, 1, 4, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "outside overnight", quiet: overnight, now: time.Date(2024, 1, 4, 6, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "inside daytime", quiet: daytime, now: time.Date(2024, 1, 4, 13, 0, 0, 0, time.UTC),
+			active: true, until: time.Date(2024, 1, 4, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "outside daytime", quiet: daytime, now: time.Date(2024, 1, 4, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "not set", quiet: nil, now: time.Date(2024, 1, 4, 14, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				until, active := tt.quiet.Until(tt.now)
+				assert.Equal(t, tt.active, active)
+				if tt.active {
+					assert.True(t, tt.until.Equal(until), until.String())
+				}
+			},
+		)
+	}
+}
+
+func TestUserQuietHours(t *testing.T) {
+	assert.Nil(t, UserQuietHours(nil))
+	assert.Nil(t, UserQuietHours(&model.User{ID: 1}))
+
+	quiet := UserQuietHours(
+		&model.User{ID: 1, Timezone: "Europe/Berlin", QuietStart: "23:00", QuietEnd: "07:00", QuietMode: "silent"},
+	)
+	assert.NotNil(t, quiet)
+	assert.Equal(t, QuietSilent, quiet.Mode)
+	assert.Equal(t, "Europe/Berlin", quiet.Location.String())
+
+	// 无法识别的时区使用服务器时区
+	assert.Equal(t, time.Local, UserLocation(&model.User{Timezone: "Mars/Olympus"}))
+}
+
+func TestCore_SetUserQuietHours(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+	userID := int64(123)
+	berlin, _ := LoadTimezone("Europe/Berlin")
+
+	t.Run(
+		"new user", func(t *testing.T) {
+			s.User.EXPECT().GetUser(ctx, userID).Return(nil, storage.ErrRecordNotFound).Times(1)
+			s.User.EXPECT().SetUserQuietHours(ctx, gomock.Any()).DoAndReturn(
+				func(_ context.Context, user *model.User) error {
+					assert.Equal(t, userID, user.ID)
+					assert.Equal(t, "Europe/Berlin", user.Timezone)
+					assert.Equal(t, "23:00", user.QuietStart)
+					assert.Equal(t, "07:00", user.QuietEnd)
+					assert.Equal(t, "hold", user.QuietMode)
+					return nil
+				},
+			).Times(1)
+
+			quiet := &QuietHours{Start: 23 * 60, End: 7 * 60, Mode: QuietHold}
+			assert.Nil(t, c.SetUserQuietHours(ctx, userID, quiet, berlin))
+		},
+	)
+
+	t.Run(
+		"turn off keeps timezone", func(t *testing.T) {
+			s.User.EXPECT().GetUser(ctx, userID).Return(
+				&model.User{ID: userID, Timezone: "Europe/Berlin", QuietStart: "23:00", QuietEnd: "07:00"}, nil,
+			).Times(1)
+			s.User.EXPECT().SetUserQuietHours(ctx, gomock.Any()).DoAndReturn(
+				func(_ context.Context, user *model.User) error {
+					assert.Equal(t, "Europe/Berlin", user.Timezone)
+					assert.Equal(t, "", user.QuietStart)
+					assert.Equal(t, "", user.QuietEnd)
+					return nil
+				},
+			).Times(1)
+
+			assert.Nil(t, c.SetUserQuietHours(ctx, userID, nil, nil))
+		},
+	)
+}
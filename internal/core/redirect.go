@@ -0,0 +1,130 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/zintus/flowerss-bot/internal/config"
+	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+// SourceMove 订阅源因永久重定向更新了链接
+type SourceMove struct {
+	// Source 更新后的订阅源，新链接已有订阅源时为合并到的订阅源
+	Source *model.Source
+	// OldLink 更新前的链接，密码已打码
+	OldLink string
+	// Subscriptions 原订阅源的订阅
+	Subscriptions []*model.Subscribe
+}
+
+// RecordSourceRedirect 记录一次成功抓取观察到的永久重定向地址，target 为空表示没有被重定向。
+// 连续 RedirectFollowThreshold 次重定向到同一地址后更新订阅源链接并返回 SourceMove，否则返回 nil
+func (c *Core) RecordSourceRedirect(ctx context.Context, sourceID uint, target string) (*SourceMove, error) {
+	source, err := c.GetSource(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	if target == source.Link {
+		target = ""
+	}
+	if target == "" {
+		if source.RedirectURL == "" {
+			return nil, nil
+		}
+		source.RedirectURL, source.RedirectCount = "", 0
+		return nil, c.sourceStorage.UpsertSource(ctx, sourceID, source)
+	}
+
+	if target == source.RedirectURL {
+		source.RedirectCount++
+	} else {
+		source.RedirectURL, source.RedirectCount = target, 1
+	}
+	if config.RedirectFollowThreshold <= 0 || source.RedirectCount < config.RedirectFollowThreshold {
+		return nil, c.sourceStorage.UpsertSource(ctx, sourceID, source)
+	}
+	return c.moveSource(ctx, source, target)
+}
+
+// moveSource 将订阅源的链接改为 link，link 已有订阅源时把订阅源合并过去。
+// 原链接保留在 OriginLink 中，已入库文章的 hash id 不变
+func (c *Core) moveSource(ctx context.Context, source *model.Source, link string) (*SourceMove, error) {
+	subs, err := c.GetSourceAllSubscriptions(ctx, source.ID)
+	if err != nil {
+		return nil, err
+	}
+	move := &SourceMove{OldLink: source.DisplayLink(), Subscriptions: subs}
+
+	// 有自定义请求设置的订阅源不与其他订阅源合并
+	if source.RequestSettings.IsEmpty() {
+		existing, err := c.GetSourceByURL(ctx, link)
+		if err != nil && !errors.Is(err, ErrSourceNotExist) {
+			return nil, err
+		}
+		if existing != nil && existing.ID != source.ID {
+			if err := c.upgradeSourceScheme(ctx, existing, link); err != nil {
+				return nil, err
+			}
+			if err := c.mergeSource(ctx, source, existing, subs); err != nil {
+				return nil, err
+			}
+			log.Infof("source [%d]%s moved, merged into source %d", source.ID, move.OldLink, existing.ID)
+			move.Source = existing
+			return move, nil
+		}
+	}
+
+	if source.OriginLink == "" {
+		source.OriginLink = source.Link
+	}
+	source.Link = link
+	source.URLKey = SourceURLKey(link)
+	source.RedirectURL, source.RedirectCount = "", 0
+	if err := c.sourceStorage.UpsertSource(ctx, source.ID, source); err != nil {
+		return nil, err
+	}
+	log.Infof("source [%d]%s moved to %s", source.ID, move.OldLink, source.DisplayLink())
+	move.Source = source
+	return move, nil
+}
+
+// mergeSource 将 from 的订阅、文章和推送记录移到 into 后删除 from，subs 为 from 的订阅。
+// 已订阅 into 的用户在 from 上的订阅直接删除；文章保留原来的 hash id，不会被重复推送
+func (c *Core) mergeSource(ctx context.Context, from, into *model.Source, subs []*model.Subscribe) error {
+	now := time.Now()
+	for _, sub := range subs {
+		exist, err := c.subscriptionStorage.SubscriptionExist(ctx, sub.UserID, into.ID)
+		if err != nil {
+			return err
+		}
+		if exist {
+			if _, err := c.subscriptionStorage.DeleteSubscription(ctx, sub.UserID, from.ID); err != nil {
+				return err
+			}
+			continue
+		}
+		sub.SourceID = into.ID
+		// into 中已有的文章不再推送
+		sub.LastDeliveredAt = &now
+		if err := c.subscriptionStorage.UpdateSubscription(ctx, sub.UserID, from.ID, sub); err != nil {
+			return err
+		}
+	}
+
+	count, err := c.contentStorage.MoveSourceContents(ctx, from.ID, into.ID)
+	if err != nil {
+		return err
+	}
+	if err := c.deliveryStorage.MoveSourceDeliveries(ctx, from.ID, into.ID); err != nil {
+		return err
+	}
+	if err := c.sourceStorage.Delete(ctx, from.ID); err != nil {
+		return err
+	}
+	c.unsubscribeHub(from)
+	log.Infof("merge source %d into %d with %d subscriptions and %d contents", from.ID, into.ID, len(subs), count)
+	return nil
+}
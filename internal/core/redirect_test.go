@@ -0,0 +1,176 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/config"
+	"github.com/zintus/flowerss-bot/internal/model"
+	"github.com/zintus/flowerss-bot/internal/storage"
+)
+
+func TestCore_RecordSourceRedirect(t *testing.T) {
+	threshold := config.RedirectFollowThreshold
+	defer func() { config.RedirectFollowThreshold = threshold }()
+	config.RedirectFollowThreshold = 3
+	ctx := context.Background()
+	const (
+		oldLink = "https://example.com/feed"
+		newLink = "https://feeds.example.com/feed"
+	)
+	subs := []*model.Subscribe{{ID: 1, UserID: 10, SourceID: 1}, {ID: 2, UserID: 20, SourceID: 1}}
+	expectSubs := func(s *mockStorage) {
+		s.Subscription.EXPECT().GetSubscriptionsBySourceID(ctx, uint(1), gomock.Any()).Return(
+			&storage.GetSubscriptionsResult{Subscriptions: subs}, nil,
+		)
+	}
+
+	t.Run(
+		"count observations", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			s.Source.EXPECT().GetSource(ctx, uint(1)).Return(
+				&model.Source{ID: 1, Link: oldLink, RedirectURL: "https://other.example.com/", RedirectCount: 2}, nil,
+			)
+			s.Source.EXPECT().UpsertSource(ctx, uint(1), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, id uint, source *model.Source) error {
+					assert.Equal(t, newLink, source.RedirectURL)
+					assert.Equal(t, 1, source.RedirectCount)
+					assert.Equal(t, oldLink, source.Link)
+					return nil
+				},
+			)
+			move, err := c.RecordSourceRedirect(ctx, 1, newLink)
+			assert.Nil(t, err)
+			assert.Nil(t, move)
+		},
+	)
+
+	t.Run(
+		"reset", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			s.Source.EXPECT().GetSource(ctx, uint(1)).Return(
+				&model.Source{ID: 1, Link: oldLink, RedirectURL: newLink, RedirectCount: 2}, nil,
+			)
+			s.Source.EXPECT().UpsertSource(ctx, uint(1), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, id uint, source *model.Source) error {
+					assert.Equal(t, "", source.RedirectURL)
+					assert.Equal(t, 0, source.RedirectCount)
+					return nil
+				},
+			)
+			move, err := c.RecordSourceRedirect(ctx, 1, "")
+			assert.Nil(t, err)
+			assert.Nil(t, move)
+		},
+	)
+
+	t.Run(
+		"update link", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			s.Source.EXPECT().GetSource(ctx, uint(1)).Return(
+				&model.Source{ID: 1, Link: oldLink, RedirectURL: newLink, RedirectCount: 2}, nil,
+			)
+			expectSubs(s)
+			s.Source.EXPECT().GetSourceByURLKey(ctx, SourceURLKey(newLink)).Return(nil, storage.ErrRecordNotFound)
+			s.Source.EXPECT().UpsertSource(ctx, uint(1), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, id uint, source *model.Source) error {
+					assert.Equal(t, newLink, source.Link)
+					assert.Equal(t, oldLink, source.OriginLink)
+					assert.Equal(t, oldLink, source.ContentKey())
+					assert.Equal(t, 0, source.RedirectCount)
+					return nil
+				},
+			)
+			move, err := c.RecordSourceRedirect(ctx, 1, newLink)
+			assert.Nil(t, err)
+			if assert.NotNil(t, move) {
+				assert.Equal(t, uint(1), move.Source.ID)
+				assert.Equal(t, oldLink, move.OldLink)
+				assert.Len(t, move.Subscriptions, 2)
+			}
+		},
+	)
+
+	t.Run(
+		"merge", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			s.Source.EXPECT().GetSource(ctx, uint(1)).Return(
+				&model.Source{ID: 1, Link: oldLink, RedirectURL: newLink, RedirectCount: 2}, nil,
+			)
+			expectSubs(s)
+			s.Source.EXPECT().GetSourceByURLKey(ctx, SourceURLKey(newLink)).Return(&model.Source{ID: 2, Link: newLink}, nil)
+			s.Subscription.EXPECT().SubscriptionExist(ctx, int64(10), uint(2)).Return(true, nil)
+			s.Subscription.EXPECT().DeleteSubscription(ctx, int64(10), uint(1)).Return(int64(1), nil)
+			s.Subscription.EXPECT().SubscriptionExist(ctx, int64(20), uint(2)).Return(false, nil)
+			s.Subscription.EXPECT().UpdateSubscription(ctx, int64(20), uint(1), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, userID int64, sourceID uint, sub *model.Subscribe) error {
+					assert.Equal(t, uint(2), sub.SourceID)
+					return nil
+				},
+			)
+			s.Content.EXPECT().MoveSourceContents(ctx, uint(1), uint(2)).Return(int64(5), nil)
+			s.Delivery.EXPECT().MoveSourceDeliveries(ctx, uint(1), uint(2)).Return(nil)
+			s.Source.EXPECT().Delete(ctx, uint(1)).Return(nil)
+
+			move, err := c.RecordSourceRedirect(ctx, 1, newLink)
+			assert.Nil(t, err)
+			if assert.NotNil(t, move) {
+				assert.Equal(t, uint(2), move.Source.ID)
+				assert.Len(t, move.Subscriptions, 2)
+			}
+		},
+	)
+
+	t.Run(
+		"merged subscriptions skip target backlog", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			lastDelivered := time.Now().Add(-24 * time.Hour)
+			// 目标订阅源在订阅者上次推送之后入库的文章
+			backlogAt := time.Now().Add(-time.Hour)
+			sub := &model.Subscribe{ID: 3, UserID: 30, SourceID: 1, LastDeliveredAt: &lastDelivered}
+			s.Source.EXPECT().GetSource(ctx, uint(1)).Return(
+				&model.Source{ID: 1, Link: oldLink, RedirectURL: newLink, RedirectCount: 2}, nil,
+			)
+			s.Subscription.EXPECT().GetSubscriptionsBySourceID(ctx, uint(1), gomock.Any()).Return(
+				&storage.GetSubscriptionsResult{Subscriptions: []*model.Subscribe{sub}}, nil,
+			)
+			s.Source.EXPECT().GetSourceByURLKey(ctx, SourceURLKey(newLink)).Return(&model.Source{ID: 2, Link: newLink}, nil)
+			s.Subscription.EXPECT().SubscriptionExist(ctx, int64(30), uint(2)).Return(false, nil)
+			s.Subscription.EXPECT().UpdateSubscription(ctx, int64(30), uint(1), gomock.Any()).Return(nil)
+			s.Content.EXPECT().MoveSourceContents(ctx, uint(1), uint(2)).Return(int64(0), nil)
+			s.Delivery.EXPECT().MoveSourceDeliveries(ctx, uint(1), uint(2)).Return(nil)
+			s.Source.EXPECT().Delete(ctx, uint(1)).Return(nil)
+
+			_, err := c.RecordSourceRedirect(ctx, 1, newLink)
+			assert.Nil(t, err)
+			if assert.NotNil(t, sub.LastDeliveredAt) {
+				assert.True(t, sub.LastDeliveredAt.After(backlogAt))
+			}
+		},
+	)
+
+	t.Run(
+		"disabled", func(t *testing.T) {
+			config.RedirectFollowThreshold = 0
+			defer func() { config.RedirectFollowThreshold = 3 }()
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			s.Source.EXPECT().GetSource(ctx, uint(1)).Return(
+				&model.Source{ID: 1, Link: oldLink, RedirectURL: newLink, RedirectCount: 5}, nil,
+			)
+			s.Source.EXPECT().UpsertSource(ctx, uint(1), gomock.Any()).Return(nil)
+			move, err := c.RecordSourceRedirect(ctx, 1, newLink)
+			assert.Nil(t, err)
+			assert.Nil(t, move)
+		},
+	)
+}
@@ -0,0 +1,142 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/zintus/flowerss-bot/internal/feed"
+	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+// SourceFetchOptions 抓取订阅源时的请求参数，包括缓存校验和自定义请求设置
+func SourceFetchOptions(source *model.Source) *feed.FetchOptions {
+	settings := source.RequestSettings
+	return &feed.FetchOptions{
+		ETag:              source.ETag,
+		LastModified:      source.LastModified,
+		Headers:           settings.Headers,
+		BasicAuthUser:     settings.BasicAuthUser,
+		BasicAuthPassword: settings.BasicAuthPassword,
+		UserAgent:         settings.UserAgent,
+	}
+}
+
+// SetSourceRequestSettings 修改用户订阅的订阅源的请求设置，返回修改后用户订阅的订阅源。
+// 有请求设置的订阅源只属于一个订阅者：给公共订阅源添加设置时为该用户复制一个订阅源，
+// 清空设置时将订阅移回相同链接的公共订阅源，没有时再复制一个公共订阅源。
+// 两种订阅源生成文章 hash id 的方式不同，复制时会预先保存已有文章，避免重复推送
+func (c *Core) SetSourceRequestSettings(
+	ctx context.Context, userID int64, sourceID uint, settings model.RequestSettings,
+) (*model.Source, error) {
+	sub, err := c.GetSubscription(ctx, userID, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := c.GetSource(ctx, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case settings.IsEmpty() && source.RequestSettings.IsEmpty():
+		return source, nil
+	case source.RequestSettings.IsEmpty():
+		return c.forkSource(ctx, sub, source, settings)
+	case settings.IsEmpty():
+		public, err := c.GetSourceByURL(ctx, source.Link)
+		if err == nil {
+			return public, c.moveSubscription(ctx, sub, public)
+		}
+		if err != ErrSourceNotExist {
+			return nil, err
+		}
+		return c.forkSource(ctx, sub, source, settings)
+	}
+
+	source.RequestSettings = settings
+	// 换了凭据后旧的缓存校验不再可靠，尽快用新设置抓取
+	source.ETag = ""
+	source.LastModified = ""
+	source.NextFetchAt = nil
+	if err := c.sourceStorage.UpsertSource(ctx, sourceID, source); err != nil {
+		return nil, err
+	}
+	c.kickFetch()
+	return source, nil
+}
+
+// forkSource 使用新的请求设置为订阅者复制订阅源，并把订阅移到新的订阅源
+func (c *Core) forkSource(
+	ctx context.Context, sub *model.Subscribe, source *model.Source, settings model.RequestSettings,
+) (*model.Source, error) {
+	forked := &model.Source{
+		Title:           source.Title,
+		Link:            source.Link,
+		URLKey:          source.URLKey,
+		Paused:          true, // 避免task更新
+		RequestSettings: settings,
+	}
+	// 预先保存已有的文章，避免首次抓取时把它们都当作新文章推送
+	result, fetchErr := c.feedParser.Fetch(ctx, forked.Link, SourceFetchOptions(forked))
+	if fetchErr != nil {
+		log.Warnf("fetch forked source %s failed, %v", forked.DisplayLink(), fetchErr)
+	} else {
+		forked.ETag = result.ETag
+		forked.LastModified = result.LastModified
+		if result.Feed != nil && result.Feed.Title != "" {
+			forked.Title = result.Feed.Title
+		}
+	}
+
+	if err := c.sourceStorage.AddSource(ctx, forked); err != nil {
+		return nil, err
+	}
+	if fetchErr == nil && result.Feed != nil {
+		if _, err := c.AddSourceContents(ctx, forked, result.Feed.Items); err != nil {
+			log.Errorf("add forked source %d contents failed, %v", forked.ID, err)
+		}
+	}
+	if err := c.EnableSourceUpdate(ctx, forked.ID); err != nil {
+		log.Errorf("failed to enable source update: %v", err)
+	}
+	log.Infof("fork source %d as %d for %d, settings %v", source.ID, forked.ID, sub.UserID, settings)
+	if err := c.moveSubscription(ctx, sub, forked); err != nil {
+		return nil, err
+	}
+	c.kickFetch()
+	return forked, nil
+}
+
+// moveSubscription 把订阅移到另一个订阅源，原订阅源没有订阅者时删除
+func (c *Core) moveSubscription(ctx context.Context, sub *model.Subscribe, to *model.Source) error {
+	from := sub.SourceID
+	exist, err := c.subscriptionStorage.SubscriptionExist(ctx, sub.UserID, to.ID)
+	if err != nil {
+		return err
+	}
+	if exist {
+		// 已经订阅了目标订阅源，只需要移除原订阅
+		return c.Unsubscribe(ctx, sub.UserID, from)
+	}
+
+	now := time.Now()
+	sub.SourceID = to.ID
+	// 目标订阅源中已有的文章不再推送
+	sub.LastDeliveredAt = &now
+	if err := c.subscriptionStorage.UpdateSubscription(ctx, sub.UserID, from, sub); err != nil {
+		return err
+	}
+
+	count, err := c.subscriptionStorage.CountSourceSubscriptions(ctx, from)
+	if err != nil {
+		log.Errorf("count source %d subscriptions after move failed: %v", from, err)
+		return nil
+	}
+	if count == 0 {
+		if err := c.removeSource(ctx, from); err != nil {
+			log.Errorf("remove orphaned source %d failed: %v", from, err)
+		}
+	}
+	return nil
+}
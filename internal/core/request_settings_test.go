@@ -0,0 +1,125 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/feed"
+	"github.com/zintus/flowerss-bot/internal/model"
+	"github.com/zintus/flowerss-bot/pkg/client"
+)
+
+const testPrivateRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0"><channel><title>Private Feed</title></channel></rss>`
+
+func TestCore_SetSourceRequestSettings(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Cookie") != "session=1" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte(testPrivateRSS))
+	}))
+	defer ts.Close()
+
+	ctx := context.Background()
+	userID := int64(100)
+	settings := model.RequestSettings{Headers: map[string]string{"Cookie": "session=1"}}
+
+	t.Run(
+		"fork public source", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			c.feedParser = feed.NewFeedParser(client.NewHttpClient())
+			public := &model.Source{ID: 1, Title: "Feed", Link: ts.URL}
+			forkedID := uint(2)
+
+			s.Subscription.EXPECT().GetSubscription(ctx, userID, public.ID).Return(
+				&model.Subscribe{UserID: userID, SourceID: public.ID}, nil,
+			)
+			s.Source.EXPECT().GetSource(ctx, public.ID).Return(public, nil)
+			s.Source.EXPECT().AddSource(ctx, gomock.Any()).DoAndReturn(
+				func(ctx context.Context, source *model.Source) error {
+					assert.Equal(t, settings, source.RequestSettings)
+					assert.Equal(t, "Private Feed", source.Title)
+					assert.Equal(t, `"v1"`, source.ETag)
+					source.ID = forkedID
+					return nil
+				},
+			)
+			s.Source.EXPECT().GetSource(ctx, forkedID).Return(
+				&model.Source{ID: forkedID, Link: ts.URL, Paused: true, RequestSettings: settings}, nil,
+			)
+			s.Source.EXPECT().UpsertSource(ctx, forkedID, gomock.Any()).Return(nil)
+			s.Subscription.EXPECT().SubscriptionExist(ctx, userID, forkedID).Return(false, nil)
+			s.Subscription.EXPECT().UpdateSubscription(ctx, userID, public.ID, gomock.Any()).DoAndReturn(
+				func(ctx context.Context, userID int64, sourceID uint, sub *model.Subscribe) error {
+					assert.Equal(t, forkedID, sub.SourceID)
+					assert.NotNil(t, sub.LastDeliveredAt)
+					return nil
+				},
+			)
+			// 公共订阅源还有其他订阅者，不会被删除
+			s.Subscription.EXPECT().CountSourceSubscriptions(ctx, public.ID).Return(int64(1), nil)
+
+			source, err := c.SetSourceRequestSettings(ctx, userID, public.ID, settings)
+			assert.Nil(t, err)
+			assert.Equal(t, forkedID, source.ID)
+		},
+	)
+
+	t.Run(
+		"update private source in place", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			private := &model.Source{ID: 2, Link: ts.URL, ETag: `"v0"`, RequestSettings: settings}
+			newSettings := model.RequestSettings{UserAgent: "reader/1.0", Headers: settings.Headers}
+
+			s.Subscription.EXPECT().GetSubscription(ctx, userID, private.ID).Return(
+				&model.Subscribe{UserID: userID, SourceID: private.ID}, nil,
+			)
+			s.Source.EXPECT().GetSource(ctx, private.ID).Return(private, nil)
+			s.Source.EXPECT().UpsertSource(ctx, private.ID, gomock.Any()).DoAndReturn(
+				func(ctx context.Context, sourceID uint, source *model.Source) error {
+					assert.Equal(t, newSettings, source.RequestSettings)
+					assert.Empty(t, source.ETag)
+					return nil
+				},
+			)
+
+			source, err := c.SetSourceRequestSettings(ctx, userID, private.ID, newSettings)
+			assert.Nil(t, err)
+			assert.Equal(t, private.ID, source.ID)
+		},
+	)
+
+	t.Run(
+		"clear settings moves back to public source", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			private := &model.Source{ID: 2, Link: ts.URL, RequestSettings: settings}
+			public := &model.Source{ID: 1, Link: ts.URL}
+
+			s.Subscription.EXPECT().GetSubscription(ctx, userID, private.ID).Return(
+				&model.Subscribe{UserID: userID, SourceID: private.ID}, nil,
+			)
+			s.Source.EXPECT().GetSource(ctx, private.ID).Return(private, nil)
+			s.Source.EXPECT().GetSourceByURLKey(ctx, SourceURLKey(ts.URL)).Return(public, nil)
+			s.Subscription.EXPECT().SubscriptionExist(ctx, userID, public.ID).Return(false, nil)
+			s.Subscription.EXPECT().UpdateSubscription(ctx, userID, private.ID, gomock.Any()).Return(nil)
+			s.Subscription.EXPECT().CountSourceSubscriptions(ctx, private.ID).Return(int64(0), nil)
+			s.Source.EXPECT().Delete(ctx, private.ID).Return(nil)
+			s.Content.EXPECT().DeleteSourceContents(ctx, private.ID).Return(int64(0), nil)
+
+			source, err := c.SetSourceRequestSettings(ctx, userID, private.ID, model.RequestSettings{})
+			assert.Nil(t, err)
+			assert.Equal(t, public.ID, source.ID)
+		},
+	)
+}
@@ -0,0 +1,206 @@
+package core
+
+import (
+	"time"
+	"unicode/utf8"
+
+	"github.com/zintus/flowerss-bot/internal/config"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+// deliverySlack 调度器按分钟唤醒，判断订阅者是否到期时容忍一个调度周期的误差
+const deliverySlack = time.Minute
+
+// SubscriptionInterval 订阅者的更新间隔，未设置时使用全局 UpdateInterval
+func SubscriptionInterval(sub *model.Subscribe) time.Duration {
+	interval := sub.Interval
+	if interval <= 0 {
+		interval = config.UpdateInterval
+	}
+	return time.Duration(interval) * time.Minute
+}
+
+// SourceFetchInterval 订阅源的抓取间隔，取所有订阅者中最小的更新间隔
+func SourceFetchInterval(subs []*model.Subscribe) time.Duration {
+	var interval time.Duration
+	for _, sub := range subs {
+		subInterval := SubscriptionInterval(sub)
+		if interval == 0 || subInterval < interval {
+			interval = subInterval
+		}
+	}
+	if interval == 0 {
+		interval = time.Duration(config.UpdateInterval) * time.Minute
+	}
+	return interval
+}
+
+// IntervalReason 订阅源抓取间隔的来源
+type IntervalReason string
+
+const (
+	// IntervalSubscription 订阅者中最小的更新间隔
+	IntervalSubscription IntervalReason = "subscription"
+	// IntervalFeedTTL 订阅源声明的 <ttl>
+	IntervalFeedTTL IntervalReason = "ttl"
+	// IntervalFeedUpdatePeriod 订阅源声明的 sy:updatePeriod
+	IntervalFeedUpdatePeriod IntervalReason = "update_period"
+	// IntervalWebSub WebSub 订阅有效，轮询只作为兜底
+	IntervalWebSub IntervalReason = "websub"
+	// IntervalAdaptive 根据订阅源最近的发布频率估计
+	IntervalAdaptive IntervalReason = "adaptive"
+)
+
+const (
+	// adaptiveMinHistory 估计发布频率至少需要的发布记录数
+	adaptiveMinHistory = 3
+	// adaptiveWeight 加权平均发布间隔时最近一次间隔的权重
+	adaptiveWeight = 0.5
+)
+
+// SourceEffectiveInterval 订阅源的抓取间隔及其来源。从订阅者中最小的更新间隔开始，
+// 有足够的发布记录时改用自适应间隔；订阅源声明的 TTL 或更新周期作为下限，
+// WebSub 订阅有效且有效期不超过上限时不小于 WebSubFallbackInterval。
+// 最终的间隔不超过订阅者自己设置的间隔
+func SourceEffectiveInterval(
+	source *model.Source, subs []*model.Subscribe, now time.Time,
+) (time.Duration, IntervalReason) {
+	interval, reason := SourceFetchInterval(subs), IntervalSubscription
+	if adaptive := adaptiveInterval(source.PublishHistory, now); adaptive > 0 {
+		interval, reason = adaptive, IntervalAdaptive
+	}
+	if hint, hintReason := feedHintInterval(source.FeedHints); hint > interval {
+		interval, reason = hint, hintReason
+	}
+	fallback := time.Duration(config.WebSubFallbackInterval) * time.Minute
+	if source.HubActive(now) && hubLeaseValid(source, now) && interval < fallback {
+		interval, reason = fallback, IntervalWebSub
+	}
+	if limit := explicitSubscriptionInterval(subs); limit > 0 && limit < interval {
+		interval, reason = limit, IntervalSubscription
+	}
+	return interval, reason
+}
+
+// explicitSubscriptionInterval 订阅者自己设置的更新间隔中最小的一个，都没有设置时返回 0
+func explicitSubscriptionInterval(subs []*model.Subscribe) time.Duration {
+	var interval time.Duration
+	for _, sub := range subs {
+		if !sub.IntervalSet {
+			continue
+		}
+		if subInterval := SubscriptionInterval(sub); interval == 0 || subInterval < interval {
+			interval = subInterval
+		}
+	}
+	return interval
+}
+
+// adaptiveInterval 根据发布记录估计的抓取间隔，取发布间隔加权平均值（越近权重越大）的一半。
+// 距最近一次发布越久间隔越长，限制在 AdaptiveMinInterval 和 AdaptiveMaxInterval 之间，
+// 未开启或记录不足时返回 0
+func adaptiveInterval(history model.PublishHistory, now time.Time) time.Duration {
+	if !config.AdaptivePolling || len(history) < adaptiveMinHistory {
+		return 0
+	}
+	gap := history[1].Sub(history[0])
+	for i := 2; i < len(history); i++ {
+		gap = time.Duration(adaptiveWeight*float64(history[i].Sub(history[i-1])) + (1-adaptiveWeight)*float64(gap))
+	}
+	// 当前这次间隔还没结束，至少已经有 quiet 长
+	if quiet := now.Sub(history[len(history)-1]) / 2; quiet > gap {
+		gap = quiet
+	}
+
+	interval := gap / 2
+	if minInterval := time.Duration(config.AdaptiveMinInterval) * time.Minute; interval < minInterval {
+		interval = minInterval
+	}
+	if maxInterval := time.Duration(config.AdaptiveMaxInterval) * time.Minute; maxInterval > 0 && interval > maxInterval {
+		interval = maxInterval
+	}
+	return interval
+}
+
+// feedHintInterval 订阅源声明的最小抓取间隔，TTL 和更新周期取较大值，不超过 FeedHintsMaxInterval
+func feedHintInterval(hints model.FeedHints) (time.Duration, IntervalReason) {
+	if !config.HonorFeedHints {
+		return 0, ""
+	}
+	minutes, reason := hints.TTL, IntervalFeedTTL
+	if hints.UpdatePeriod > minutes {
+		minutes, reason = hints.UpdatePeriod, IntervalFeedUpdatePeriod
+	}
+	if config.FeedHintsMaxInterval > 0 && minutes > config.FeedHintsMaxInterval {
+		minutes = config.FeedHintsMaxInterval
+	}
+	return time.Duration(minutes) * time.Minute, reason
+}
+
+// SourceNextFetchAt 不早于 at 的下次抓取时间，跳过订阅源声明的 skipHours 和 skipDays
+func SourceNextFetchAt(source *model.Source, at time.Time) time.Time {
+	if !config.HonorFeedHints {
+		return at
+	}
+	return source.FeedHints.NextAllowed(at)
+}
+
+// SubscriptionDue 订阅者距上次推送是否已超过其更新间隔
+func SubscriptionDue(sub *model.Subscribe, now time.Time) bool {
+	if sub.LastDeliveredAt == nil {
+		return true
+	}
+	return !now.Add(deliverySlack).Before(sub.LastDeliveredAt.Add(SubscriptionInterval(sub)))
+}
+
+// NextSubscriptionDueAt 未到期的订阅者中最早到期的时间，所有订阅者都已到期时返回 nil
+func NextSubscriptionDueAt(subs []*model.Subscribe, now time.Time) *time.Time {
+	var next *time.Time
+	for _, sub := range subs {
+		if SubscriptionDue(sub, now) {
+			continue
+		}
+		due := sub.LastDeliveredAt.Add(SubscriptionInterval(sub) - deliverySlack)
+		if next == nil || due.Before(*next) {
+			next = &due
+		}
+	}
+	return next
+}
+
+// maxLastErrorLen 保存到订阅源的错误信息最大长度
+const maxLastErrorLen = 512
+
+// SourceRetryDelay 订阅源连续失败 errorCount 次后的重试间隔，
+// 从抓取间隔开始逐次翻倍，不超过 ErrorBackoffMax
+func SourceRetryDelay(interval time.Duration, errorCount uint) time.Duration {
+	maxDelay := time.Duration(config.ErrorBackoffMax) * time.Minute
+	if maxDelay <= 0 || maxDelay < interval {
+		maxDelay = interval
+	}
+
+	delay := interval
+	for i := uint(1); i < errorCount && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// truncateError 错误信息，过长时截断
+func truncateError(err error) string {
+	if err == nil {
+		return ""
+	}
+	msg := err.Error()
+	if len(msg) <= maxLastErrorLen {
+		return msg
+	}
+	msg = msg[:maxLastErrorLen]
+	for !utf8.ValidString(msg) {
+		msg = msg[:len(msg)-1]
+	}
+	return msg + "…"
+}
@@ -0,0 +1,301 @@
+package core
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/zintus/flowerss-bot/internal/config"
+	"github.com/zintus/flowerss-bot/internal/model"
+	"github.com/zintus/flowerss-bot/internal/storage"
+)
+
+func TestSourceFetchInterval(t *testing.T) {
+	defaultInterval := time.Duration(config.UpdateInterval) * time.Minute
+
+	t.Run(
+		"no subscriptions", func(t *testing.T) {
+			assert.Equal(t, defaultInterval, SourceFetchInterval(nil))
+		},
+	)
+
+	t.Run(
+		"smallest interval wins", func(t *testing.T) {
+			subs := []*model.Subscribe{{Interval: 60}, {Interval: 5}, {Interval: 30}}
+			assert.Equal(t, 5*time.Minute, SourceFetchInterval(subs))
+		},
+	)
+
+	t.Run(
+		"unset interval uses default", func(t *testing.T) {
+			subs := []*model.Subscribe{{Interval: 0}, {Interval: 600}}
+			assert.Equal(t, defaultInterval, SourceFetchInterval(subs))
+		},
+	)
+}
+
+func TestSubscriptionDue(t *testing.T) {
+	now := time.Now()
+
+	t.Run(
+		"never delivered", func(t *testing.T) {
+			assert.True(t, SubscriptionDue(&model.Subscribe{Interval: 60}, now))
+		},
+	)
+
+	t.Run(
+		"interval not elapsed", func(t *testing.T) {
+			last := now.Add(-30 * time.Minute)
+			assert.False(t, SubscriptionDue(&model.Subscribe{Interval: 60, LastDeliveredAt: &last}, now))
+		},
+	)
+
+	t.Run(
+		"interval elapsed", func(t *testing.T) {
+			last := now.Add(-61 * time.Minute)
+			assert.True(t, SubscriptionDue(&model.Subscribe{Interval: 60, LastDeliveredAt: &last}, now))
+		},
+	)
+
+	t.Run(
+		"within one scheduler tick", func(t *testing.T) {
+			last := now.Add(-60*time.Minute + 10*time.Second)
+			assert.True(t, SubscriptionDue(&model.Subscribe{Interval: 60, LastDeliveredAt: &last}, now))
+		},
+	)
+}
+
+func TestSourceRetryDelay(t *testing.T) {
+	backoffMax := config.ErrorBackoffMax
+	defer func() { config.ErrorBackoffMax = backoffMax }()
+	config.ErrorBackoffMax = 60
+
+	t.Run(
+		"doubles per failure", func(t *testing.T) {
+			assert.Equal(t, 10*time.Minute, SourceRetryDelay(10*time.Minute, 1))
+			assert.Equal(t, 20*time.Minute, SourceRetryDelay(10*time.Minute, 2))
+			assert.Equal(t, 40*time.Minute, SourceRetryDelay(10*time.Minute, 3))
+		},
+	)
+
+	t.Run(
+		"capped", func(t *testing.T) {
+			assert.Equal(t, 60*time.Minute, SourceRetryDelay(10*time.Minute, 4))
+			assert.Equal(t, 60*time.Minute, SourceRetryDelay(10*time.Minute, 1000))
+		},
+	)
+
+	t.Run(
+		"interval above cap", func(t *testing.T) {
+			assert.Equal(t, 2*time.Hour, SourceRetryDelay(2*time.Hour, 5))
+		},
+	)
+}
+
+func TestSourceEffectiveInterval(t *testing.T) {
+	fallback, honor, maxInterval := config.WebSubFallbackInterval, config.HonorFeedHints, config.FeedHintsMaxInterval
+	defer func() {
+		config.WebSubFallbackInterval, config.HonorFeedHints, config.FeedHintsMaxInterval = fallback, honor, maxInterval
+	}()
+	config.WebSubFallbackInterval = 60
+	config.HonorFeedHints = true
+	config.FeedHintsMaxInterval = 180
+	now := time.Now()
+	subs := []*model.Subscribe{{Interval: 10}}
+
+	tests := []struct {
+		name     string
+		source   *model.Source
+		interval time.Duration
+		reason   IntervalReason
+	}{
+		{"subscription", &model.Source{}, 10 * time.Minute, IntervalSubscription},
+		{"ttl lower bound", &model.Source{FeedHints: model.FeedHints{TTL: 30}}, 30 * time.Minute, IntervalFeedTTL},
+		{"ttl below subscription", &model.Source{FeedHints: model.FeedHints{TTL: 5}}, 10 * time.Minute, IntervalSubscription},
+		{
+			"update period wins", &model.Source{FeedHints: model.FeedHints{TTL: 30, UpdatePeriod: 120}},
+			120 * time.Minute, IntervalFeedUpdatePeriod,
+		},
+		{"capped", &model.Source{FeedHints: model.FeedHints{UpdatePeriod: 1440}}, 180 * time.Minute, IntervalFeedUpdatePeriod},
+	}
+	for _, tt := range tests {
+		interval, reason := SourceEffectiveInterval(tt.source, subs, now)
+		assert.Equal(t, tt.interval, interval, tt.name)
+		assert.Equal(t, tt.reason, reason, tt.name)
+	}
+
+	// 订阅者自己设置的间隔优先于订阅源声明的 TTL
+	explicit := []*model.Subscribe{{Interval: 15, IntervalSet: true}}
+	interval, reason := SourceEffectiveInterval(&model.Source{FeedHints: model.FeedHints{TTL: 60}}, explicit, now)
+	assert.Equal(t, 15*time.Minute, interval)
+	assert.Equal(t, IntervalSubscription, reason)
+
+	t.Run(
+		"websub", func(t *testing.T) {
+			leaseUntil := now.Add(time.Hour)
+			expired := now.Add(-time.Hour)
+			active := &model.Source{HubURL: "https://hub.example.com/", HubLeaseUntil: &leaseUntil}
+			interval, reason := SourceEffectiveInterval(active, subs, now)
+			assert.Equal(t, 60*time.Minute, interval)
+			assert.Equal(t, IntervalWebSub, reason)
+
+			active.FeedHints.TTL = 120
+			interval, reason = SourceEffectiveInterval(active, subs, now)
+			assert.Equal(t, 120*time.Minute, interval)
+			assert.Equal(t, IntervalFeedTTL, reason)
+
+			explicit := []*model.Subscribe{{Interval: 10}, {Interval: 30, IntervalSet: true}}
+			interval, reason = SourceEffectiveInterval(active, explicit, now)
+			assert.Equal(t, 30*time.Minute, interval, "explicit interval caps websub fallback")
+			assert.Equal(t, IntervalSubscription, reason)
+
+			expiredLease := &model.Source{HubURL: "https://hub.example.com/", HubLeaseUntil: &expired}
+			interval, _ = SourceEffectiveInterval(expiredLease, subs, now)
+			assert.Equal(t, 10*time.Minute, interval)
+		},
+	)
+
+	t.Run(
+		"hints disabled", func(t *testing.T) {
+			config.HonorFeedHints = false
+			defer func() { config.HonorFeedHints = true }()
+			interval, reason := SourceEffectiveInterval(&model.Source{FeedHints: model.FeedHints{TTL: 30}}, subs, now)
+			assert.Equal(t, 10*time.Minute, interval)
+			assert.Equal(t, IntervalSubscription, reason)
+		},
+	)
+}
+
+func TestSourceEffectiveInterval_MigratedSubscriptions(t *testing.T) {
+	honor, adaptive := config.HonorFeedHints, config.AdaptivePolling
+	defer func() { config.HonorFeedHints, config.AdaptivePolling = honor, adaptive }()
+	config.HonorFeedHints = true
+	config.AdaptivePolling = true
+
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	// 旧版本添加订阅时保存的是默认更新间隔
+	err = db.Exec(
+		`CREATE TABLE subscribes (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER, source_id INTEGER, interval INTEGER)`,
+	).Error
+	if err != nil {
+		t.Fatalf("Failed to create old schema: %v", err)
+	}
+	err = db.Exec(`INSERT INTO subscribes (user_id, source_id, interval) VALUES (1, 1, ?)`, config.UpdateInterval).Error
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+	if err := storage.NewSubscriptionStorageImpl(db).Init(context.Background()); err != nil {
+		t.Fatalf("Failed to run migration: %v", err)
+	}
+	var subs []*model.Subscribe
+	if err := db.Find(&subs).Error; err != nil {
+		t.Fatalf("Failed to query subscriptions: %v", err)
+	}
+
+	now := time.Now()
+	interval, reason := SourceEffectiveInterval(&model.Source{FeedHints: model.FeedHints{TTL: 120}}, subs, now)
+	assert.Equal(t, 120*time.Minute, interval)
+	assert.Equal(t, IntervalFeedTTL, reason)
+
+	source := &model.Source{PublishHistory: model.PublishHistory{}.Add(
+		now.Add(-72*time.Hour), now.Add(-48*time.Hour), now.Add(-24*time.Hour), now,
+	)}
+	interval, reason = SourceEffectiveInterval(source, subs, now)
+	assert.Equal(t, 12*time.Hour, interval)
+	assert.Equal(t, IntervalAdaptive, reason)
+}
+
+func TestAdaptiveInterval(t *testing.T) {
+	enabled, minInterval, maxInterval := config.AdaptivePolling, config.AdaptiveMinInterval, config.AdaptiveMaxInterval
+	defer func() {
+		config.AdaptivePolling, config.AdaptiveMinInterval, config.AdaptiveMaxInterval = enabled, minInterval, maxInterval
+	}()
+	config.AdaptivePolling = true
+	config.AdaptiveMinInterval = 5
+	config.AdaptiveMaxInterval = 720
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	every := func(gap time.Duration, n int) model.PublishHistory {
+		var history model.PublishHistory
+		for i := n - 1; i >= 0; i-- {
+			history = append(history, now.Add(-time.Duration(i)*gap))
+		}
+		return history
+	}
+
+	assert.Equal(t, time.Duration(0), adaptiveInterval(every(time.Hour, 2), now), "not enough history")
+	assert.Equal(t, 2*time.Hour, adaptiveInterval(every(4*time.Hour, 5), now))
+	assert.Equal(t, 720*time.Minute, adaptiveInterval(every(30*24*time.Hour, 5), now), "monthly")
+
+	// 一直按小时发布，随后 10 分钟内连发几篇
+	burst := every(time.Hour, 8).Add(now.Add(5*time.Minute), now.Add(10*time.Minute), now.Add(15*time.Minute))
+	burstEnd := now.Add(15 * time.Minute)
+	interval := adaptiveInterval(burst, burstEnd)
+	assert.Less(t, interval, 10*time.Minute)
+	assert.GreaterOrEqual(t, interval, 5*time.Minute)
+	// 沉寂之后逐渐放慢
+	assert.Greater(t, adaptiveInterval(burst, burstEnd.Add(4*time.Hour)), interval)
+	assert.Equal(t, time.Hour, adaptiveInterval(burst, burstEnd.Add(4*time.Hour)))
+
+	config.AdaptivePolling = false
+	assert.Equal(t, time.Duration(0), adaptiveInterval(every(4*time.Hour, 5), now))
+}
+
+func TestSourceEffectiveInterval_Adaptive(t *testing.T) {
+	enabled := config.AdaptivePolling
+	defer func() { config.AdaptivePolling = enabled }()
+	config.AdaptivePolling = true
+	now := time.Now()
+	source := &model.Source{PublishHistory: model.PublishHistory{}.Add(
+		now.Add(-72*time.Hour), now.Add(-48*time.Hour), now.Add(-24*time.Hour), now,
+	)}
+
+	interval, reason := SourceEffectiveInterval(source, []*model.Subscribe{{Interval: 10}}, now)
+	assert.Equal(t, 12*time.Hour, interval)
+	assert.Equal(t, IntervalAdaptive, reason)
+
+	subs := []*model.Subscribe{{Interval: 10}, {Interval: 60, IntervalSet: true}}
+	interval, reason = SourceEffectiveInterval(source, subs, now)
+	assert.Equal(t, time.Hour, interval, "explicit interval caps adaptive polling")
+	assert.Equal(t, IntervalSubscription, reason)
+}
+
+func TestSourceNextFetchAt(t *testing.T) {
+	honor := config.HonorFeedHints
+	defer func() { config.HonorFeedHints = honor }()
+	config.HonorFeedHints = true
+
+	// 2024-01-06 is a Saturday
+	at := time.Date(2024, 1, 5, 22, 30, 0, 0, time.UTC)
+	source := &model.Source{FeedHints: model.FeedHints{SkipHours: []int{22, 23}, SkipDays: []int{int(time.Saturday)}}}
+	assert.Equal(t, time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC), SourceNextFetchAt(source, at))
+	assert.Equal(t, at.Add(-2*time.Hour), SourceNextFetchAt(source, at.Add(-2*time.Hour)))
+
+	config.HonorFeedHints = false
+	assert.Equal(t, at, SourceNextFetchAt(source, at))
+}
+
+func TestNextSubscriptionDueAt(t *testing.T) {
+	now := time.Now()
+	recent := now.Add(-10 * time.Minute)
+	old := now.Add(-2 * time.Hour)
+
+	assert.Nil(t, NextSubscriptionDueAt([]*model.Subscribe{{Interval: 60}, {Interval: 60, LastDeliveredAt: &old}}, now))
+
+	next := NextSubscriptionDueAt(
+		[]*model.Subscribe{
+			{Interval: 60, LastDeliveredAt: &recent},
+			{Interval: 30, LastDeliveredAt: &recent},
+			{Interval: 60, LastDeliveredAt: &old},
+		}, now,
+	)
+	if assert.NotNil(t, next) {
+		assert.Equal(t, recent.Add(30*time.Minute-deliverySlack), *next)
+	}
+}
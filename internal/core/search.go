@@ -0,0 +1,40 @@
+package core
+
+import (
+	"context"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+// SearchResult 一页搜索结果
+type SearchResult struct {
+	Contents []*model.Content
+	Total    int64
+	Sources  map[uint]*model.Source // 用户订阅的订阅源，按 ID 索引
+}
+
+// SearchContents 在用户订阅的订阅源中搜索文章，不会返回其他订阅源的文章
+func (c *Core) SearchContents(
+	ctx context.Context, userID int64, query string, offset, limit int,
+) (*SearchResult, error) {
+	sources, err := c.GetUserSubscribedSources(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SearchResult{Sources: make(map[uint]*model.Source, len(sources))}
+	sourceIDs := make([]uint, 0, len(sources))
+	for _, source := range sources {
+		result.Sources[source.ID] = source
+		sourceIDs = append(sourceIDs, source.ID)
+	}
+	if len(sourceIDs) == 0 {
+		return result, nil
+	}
+
+	result.Contents, result.Total, err = c.contentSearch.SearchContents(ctx, sourceIDs, query, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
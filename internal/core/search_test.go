@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+	"github.com/zintus/flowerss-bot/internal/storage"
+)
+
+func TestCore_SearchContents(t *testing.T) {
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	ctx := context.Background()
+	userID := int64(1)
+
+	t.Run(
+		"no subscriptions", func(t *testing.T) {
+			s.Subscription.EXPECT().GetSubscriptionsByUserID(ctx, userID, gomock.Any()).Return(
+				&storage.GetSubscriptionsResult{}, nil,
+			).Times(1)
+
+			result, err := c.SearchContents(ctx, userID, "go", 0, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(0), result.Total)
+			assert.Nil(t, result.Contents)
+		},
+	)
+
+	t.Run(
+		"scoped to subscriptions", func(t *testing.T) {
+			s.Subscription.EXPECT().GetSubscriptionsByUserID(ctx, userID, gomock.Any()).Return(
+				&storage.GetSubscriptionsResult{
+					Subscriptions: []*model.Subscribe{{SourceID: 101}, {SourceID: 102}},
+				}, nil,
+			).Times(1)
+			s.Source.EXPECT().GetSource(ctx, uint(101)).Return(&model.Source{ID: 101, Title: "a"}, nil).Times(1)
+			s.Source.EXPECT().GetSource(ctx, uint(102)).Return(&model.Source{ID: 102, Title: "b"}, nil).Times(1)
+			s.Search.EXPECT().SearchContents(ctx, []uint{101, 102}, "go", 10, 10).Return(
+				[]*model.Content{{HashID: "x", SourceID: 102}}, int64(11), nil,
+			).Times(1)
+
+			result, err := c.SearchContents(ctx, userID, "go", 10, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(11), result.Total)
+			assert.Equal(t, 1, len(result.Contents))
+			assert.Equal(t, "b", result.Sources[102].Title)
+		},
+	)
+
+	t.Run(
+		"search error", func(t *testing.T) {
+			s.Subscription.EXPECT().GetSubscriptionsByUserID(ctx, userID, gomock.Any()).Return(
+				&storage.GetSubscriptionsResult{Subscriptions: []*model.Subscribe{{SourceID: 101}}}, nil,
+			).Times(1)
+			s.Source.EXPECT().GetSource(ctx, uint(101)).Return(&model.Source{ID: 101}, nil).Times(1)
+			s.Search.EXPECT().SearchContents(ctx, []uint{101}, "go", 0, 10).Return(
+				nil, int64(0), errors.New("err"),
+			).Times(1)
+
+			_, err := c.SearchContents(ctx, userID, "go", 0, 10)
+			assert.Error(t, err)
+		},
+	)
+}
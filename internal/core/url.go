@@ -0,0 +1,87 @@
+package core
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// trackingParams 不影响订阅源内容的跟踪参数，utm_ 开头的参数也会被去掉
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"gbraid":  true,
+	"wbraid":  true,
+	"msclkid": true,
+	"yclid":   true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+	"mkt_tok": true,
+}
+
+// NormalizeSourceURL 订阅时保存的链接：host 转小写，去掉默认端口、fragment 和跟踪参数，
+// 其余查询参数保持原样。不是 http(s) 链接时原样返回
+func NormalizeSourceURL(link string) string {
+	link = strings.TrimSpace(link)
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return link
+	}
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+	u.Fragment, u.RawFragment = "", ""
+	u.RawQuery = strings.Join(queryParams(u.RawQuery), "&")
+	u.ForceQuery = false
+	return u.String()
+}
+
+// SourceURLKey 链接的规范形式，规范形式相同的链接视为同一个订阅源。在 NormalizeSourceURL 的基础上
+// 不区分 http 和 https、host 的 www. 前缀、路径末尾的 / 以及查询参数的顺序
+func SourceURLKey(link string) string {
+	normalized := NormalizeSourceURL(link)
+	u, err := url.Parse(normalized)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return normalized
+	}
+
+	var b strings.Builder
+	b.WriteString("//")
+	if u.User != nil {
+		b.WriteString(u.User.String())
+		b.WriteByte('@')
+	}
+	b.WriteString(strings.TrimPrefix(u.Host, "www."))
+	b.WriteString(strings.TrimRight(u.EscapedPath(), "/"))
+	if params := queryParams(u.RawQuery); len(params) > 0 {
+		sort.Strings(params)
+		b.WriteByte('?')
+		b.WriteString(strings.Join(params, "&"))
+	}
+	return b.String()
+}
+
+// queryParams 去掉跟踪参数和空参数后的查询参数，保持原来的编码和顺序
+func queryParams(rawQuery string) []string {
+	var params []string
+	for _, param := range strings.Split(rawQuery, "&") {
+		if param == "" {
+			continue
+		}
+		name, _, _ := strings.Cut(param, "=")
+		if unescaped, err := url.QueryUnescape(name); err == nil {
+			name = unescaped
+		}
+		name = strings.ToLower(name)
+		if trackingParams[name] || strings.HasPrefix(name, "utm_") {
+			continue
+		}
+		params = append(params, param)
+	}
+	return params
+}
@@ -0,0 +1,257 @@
+package core
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/zintus/flowerss-bot/internal/config"
+	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+const (
+	// hubLeaseSeconds 向 hub 请求的订阅有效期
+	hubLeaseSeconds = 7 * 24 * 60 * 60
+	// hubRenewBefore 订阅到期前多久续订
+	hubRenewBefore = 24 * time.Hour
+	// hubPendingTimeout 发出订阅请求后等待 hub 确认的时间，超时后续订时重新请求
+	hubPendingTimeout = time.Hour
+)
+
+// pendingHubRequest 已向 hub 发出、还未确认的订阅请求
+type pendingHubRequest struct {
+	topic  string
+	sentAt time.Time
+}
+
+// ErrHubIntentMismatch hub 确认的订阅与订阅源不符，拒绝确认
+var ErrHubIntentMismatch = errors.New("hub intent does not match the source")
+
+// HubCallbackURL 订阅源的 WebSub 回调地址
+func HubCallbackURL(sourceID uint) string {
+	return config.WebSubCallbackURL + "/" + strconv.FormatUint(uint64(sourceID), 10)
+}
+
+// hubEnabled 是否为订阅源使用 WebSub，有自定义请求设置的订阅源不会由 hub 推送。
+// 签名密钥只能通过 https 发送给 hub，不是 https 的 hub 无法校验推送内容，不使用
+func hubEnabled(source *model.Source) bool {
+	return config.WebSubCallbackURL != "" && isHTTPS(source.HubURL) && source.HubTopic != "" &&
+		source.RequestSettings.IsEmpty()
+}
+
+// hubMaxLease hub 确认的订阅有效期上限
+func hubMaxLease() time.Duration {
+	return time.Duration(config.WebSubMaxLease) * time.Hour
+}
+
+// hubLeaseValid 订阅有效期不超过上限，超过上限的有效期不是我们请求的，需要重新订阅
+func hubLeaseValid(source *model.Source, now time.Time) bool {
+	return source.HubLeaseUntil == nil || config.WebSubMaxLease <= 0 || !source.HubLeaseUntil.After(now.Add(hubMaxLease()))
+}
+
+// newHubSecret 生成 hub 签名推送内容的密钥
+func newHubSecret() (string, error) {
+	buf := make([]byte, 20)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// setSourceHub 设置订阅源的 hub 和 topic，hub 是 https 时生成签名密钥
+func setSourceHub(source *model.Source, hub, topic string) error {
+	source.HubURL = hub
+	source.HubTopic = topic
+	source.HubLeaseUntil = nil
+	if !isHTTPS(hub) || source.HubSecret != "" {
+		return nil
+	}
+	secret, err := newHubSecret()
+	if err != nil {
+		return err
+	}
+	source.HubSecret = secret
+	return nil
+}
+
+// SubscribeHub 向订阅源的 hub 发送订阅请求，hub 随后请求回调地址确认订阅
+func (c *Core) SubscribeHub(ctx context.Context, source *model.Source) error {
+	if !hubEnabled(source) {
+		return nil
+	}
+	// hub 可能在响应订阅请求之前就来确认，先记录请求
+	c.setHubPending(source.ID, source.HubTopic)
+	err := c.hubRequest(
+		ctx, source, url.Values{
+			"hub.mode":          {"subscribe"},
+			"hub.secret":        {source.HubSecret},
+			"hub.lease_seconds": {strconv.Itoa(hubLeaseSeconds)},
+		},
+	)
+	if err != nil {
+		c.takeHubPending(source.ID, source.HubTopic, time.Now())
+	}
+	return err
+}
+
+func (c *Core) setHubPending(sourceID uint, topic string) {
+	c.hubPendingMu.Lock()
+	defer c.hubPendingMu.Unlock()
+	c.hubPending[sourceID] = pendingHubRequest{topic: topic, sentAt: time.Now()}
+}
+
+// takeHubPending 取出订阅源还未确认的订阅请求，没有 topic 相同且未超时的请求时返回 false
+func (c *Core) takeHubPending(sourceID uint, topic string, now time.Time) bool {
+	c.hubPendingMu.Lock()
+	defer c.hubPendingMu.Unlock()
+	pending, ok := c.hubPending[sourceID]
+	if !ok || pending.topic != topic {
+		return false
+	}
+	delete(c.hubPending, sourceID)
+	return now.Sub(pending.sentAt) <= hubPendingTimeout
+}
+
+// subscribeHubAsync 在后台向 hub 订阅
+func (c *Core) subscribeHubAsync(source *model.Source) {
+	if !hubEnabled(source) {
+		return
+	}
+	copied := *source
+	c.goBackground(func(ctx context.Context) {
+		if err := c.SubscribeHub(ctx, &copied); err != nil {
+			log.Warnf("subscribe source %d to hub %s failed, %v", copied.ID, copied.HubURL, err)
+		}
+	})
+}
+
+// unsubscribeHub 在后台取消订阅源的 hub 订阅，只在订阅有效时发送
+func (c *Core) unsubscribeHub(source *model.Source) {
+	if !hubEnabled(source) || !source.HubActive(time.Now()) {
+		return
+	}
+	c.goBackground(func(ctx context.Context) {
+		if err := c.hubRequest(ctx, source, url.Values{"hub.mode": {"unsubscribe"}}); err != nil {
+			log.Warnf("unsubscribe source %d from hub %s failed, %v", source.ID, source.HubURL, err)
+		}
+	})
+}
+
+func (c *Core) hubRequest(ctx context.Context, source *model.Source, form url.Values) error {
+	form.Set("hub.topic", source.HubTopic)
+	form.Set("hub.callback", HubCallbackURL(source.ID))
+	// hub 地址来自订阅源，使用抓取订阅源的 client
+	resp, err := c.feedParser.Client().PostFormWithContext(ctx, source.HubURL, form)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("hub responded %s", resp.Status)
+	}
+	log.Infof("sent %s request of source %d to hub %s", form.Get("hub.mode"), source.ID, source.HubURL)
+	return nil
+}
+
+// VerifyHubIntent 处理 hub 对订阅请求的确认，mode 为 subscribe 或 unsubscribe，
+// 返回 nil 时确认。只确认我们发出且还未确认的订阅请求，确认后记录有效期，有效期不超过 WebSubMaxLease；
+// mode 为 denied 时 hub 拒绝了订阅
+func (c *Core) VerifyHubIntent(
+	ctx context.Context, sourceID uint, mode, topic string, leaseSeconds int,
+) error {
+	source, err := c.GetSource(ctx, sourceID)
+	switch mode {
+	case "unsubscribe":
+		if errors.Is(err, ErrSourceNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hubEnabled(source) && source.HubTopic == topic {
+			// 仍需要该订阅
+			return ErrHubIntentMismatch
+		}
+		return nil
+	case "subscribe", "denied":
+		if err != nil {
+			return err
+		}
+		if !hubEnabled(source) || source.HubTopic != topic {
+			return ErrHubIntentMismatch
+		}
+		if mode == "subscribe" && !c.takeHubPending(sourceID, topic, time.Now()) {
+			return ErrHubIntentMismatch
+		}
+	default:
+		return ErrHubIntentMismatch
+	}
+
+	if mode == "denied" {
+		log.Warnf("hub %s denied subscription of source %d", source.HubURL, sourceID)
+		source.HubLeaseUntil = nil
+		return c.sourceStorage.UpsertSource(ctx, sourceID, source)
+	}
+	lease := time.Duration(leaseSeconds) * time.Second
+	if leaseSeconds <= 0 {
+		lease = hubLeaseSeconds * time.Second
+	}
+	if config.WebSubMaxLease > 0 && lease > hubMaxLease() {
+		lease = hubMaxLease()
+	}
+	until := time.Now().Add(lease)
+	source.HubLeaseUntil = &until
+	log.Infof("hub %s verified subscription of source %d until %s", source.HubURL, sourceID, until.Format(time.RFC3339))
+	return c.sourceStorage.UpsertSource(ctx, sourceID, source)
+}
+
+// UpdateSourceHub 订阅源的 hub 或 topic 有变化时保存并重新订阅，hub 为空表示订阅源不再使用 WebSub
+func (c *Core) UpdateSourceHub(ctx context.Context, sourceID uint, hub, topic string) error {
+	source, err := c.GetSource(ctx, sourceID)
+	if err != nil {
+		return err
+	}
+	if hub == "" {
+		topic = ""
+	} else if topic == "" {
+		topic = source.Link
+	}
+	if source.HubURL == hub && source.HubTopic == topic {
+		return nil
+	}
+	if err := setSourceHub(source, hub, topic); err != nil {
+		return err
+	}
+	if err := c.sourceStorage.UpsertSource(ctx, sourceID, source); err != nil {
+		return err
+	}
+	return c.SubscribeHub(ctx, source)
+}
+
+// RenewHubSubscriptions 订阅还未确认、即将到期或有效期超过上限的订阅源重新向 hub 订阅，暂停更新的订阅源不续订
+func (c *Core) RenewHubSubscriptions(ctx context.Context, now time.Time) {
+	if config.WebSubCallbackURL == "" {
+		return
+	}
+	sources, err := c.GetSources(ctx)
+	if err != nil {
+		log.Errorf("get sources for hub renewal failed, %v", err)
+		return
+	}
+	for _, source := range sources {
+		if source.Paused || !hubEnabled(source) ||
+			(source.HubActive(now.Add(hubRenewBefore)) && hubLeaseValid(source, now)) {
+			continue
+		}
+		if err := c.SubscribeHub(ctx, source); err != nil {
+			log.Warnf("subscribe source %d to hub %s failed, %v", source.ID, source.HubURL, err)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/config"
+	"github.com/zintus/flowerss-bot/internal/feed"
+	"github.com/zintus/flowerss-bot/internal/model"
+	"github.com/zintus/flowerss-bot/internal/storage"
+	"github.com/zintus/flowerss-bot/pkg/client"
+)
+
+func setTestCallbackURL(t *testing.T) {
+	callbackURL := config.WebSubCallbackURL
+	t.Cleanup(func() { config.WebSubCallbackURL = callbackURL })
+	config.WebSubCallbackURL = "https://bot.example.com/websub"
+}
+
+func TestCore_SubscribeHub(t *testing.T) {
+	setTestCallbackURL(t)
+	var form url.Values
+	hub := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		form = r.PostForm
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer hub.Close()
+
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	c.feedParser = feed.NewFeedParser(client.NewHttpClient())
+	c.feedParser.Client().Client().Transport = hub.Client().Transport
+	ctx := context.Background()
+
+	source := &model.Source{ID: 3, Link: "https://example.com/feed"}
+	assert.Nil(t, c.SubscribeHub(ctx, source))
+	assert.Nil(t, form, "source without hub")
+
+	assert.Nil(t, setSourceHub(source, hub.URL, "https://example.com/self"))
+	assert.Len(t, source.HubSecret, 40)
+	assert.Nil(t, c.SubscribeHub(ctx, source))
+	assert.Equal(t, "subscribe", form.Get("hub.mode"))
+	assert.Equal(t, "https://example.com/self", form.Get("hub.topic"))
+	assert.Equal(t, "https://bot.example.com/websub/3", form.Get("hub.callback"))
+	assert.Equal(t, source.HubSecret, form.Get("hub.secret"))
+	assert.True(t, c.takeHubPending(3, "https://example.com/self", time.Now()))
+
+	// 不是 https 的 hub 不发送签名密钥，不使用
+	form = nil
+	insecure := &model.Source{ID: 4, Link: "https://example.com/feed"}
+	assert.Nil(t, setSourceHub(insecure, "http://hub.example.com/", "https://example.com/feed"))
+	assert.Empty(t, insecure.HubSecret)
+	assert.Nil(t, c.SubscribeHub(ctx, insecure))
+	assert.Nil(t, form, "insecure hub")
+
+	form = nil
+	private := *source
+	private.RequestSettings = model.RequestSettings{UserAgent: "reader"}
+	assert.Nil(t, c.SubscribeHub(ctx, &private))
+	assert.Nil(t, form, "private source")
+}
+
+func TestCore_HubRequestsStopOnShutdown(t *testing.T) {
+	setTestCallbackURL(t)
+	received := make(chan struct{}, 1)
+	stop := make(chan struct{})
+	hub := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		select {
+		case <-r.Context().Done():
+		case <-stop:
+		}
+	}))
+	defer hub.Close()
+	defer close(stop)
+
+	c, s := getTestCore(t)
+	defer s.Ctrl.Finish()
+	c.feedParser = feed.NewFeedParser(client.NewHttpClient(client.WithTimeout(time.Minute)))
+	c.feedParser.Client().Client().Transport = hub.Client().Transport
+	ctx, cancel := context.WithCancel(context.Background())
+	c.ctx = ctx
+
+	leaseUntil := time.Now().Add(time.Hour)
+	source := &model.Source{ID: 3, HubURL: hub.URL, HubTopic: "https://example.com/feed", HubLeaseUntil: &leaseUntil}
+	c.unsubscribeHub(source)
+	<-received
+
+	waitCtx, waitCancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer waitCancel()
+	assert.ErrorIs(t, c.Wait(waitCtx), context.DeadlineExceeded, "hub request still running")
+
+	// 退出时取消进行中的请求，之后不再发出新的请求
+	cancel()
+	assert.Nil(t, c.Wait(context.Background()))
+	c.unsubscribeHub(source)
+	assert.Nil(t, c.Wait(context.Background()))
+	assert.Len(t, received, 0)
+}
+
+func TestCore_VerifyHubIntent(t *testing.T) {
+	setTestCallbackURL(t)
+	ctx := context.Background()
+	topic := "https://example.com/feed"
+	newSource := func() *model.Source {
+		return &model.Source{ID: 1, Link: topic, HubURL: "https://hub.example.com/", HubTopic: topic, HubSecret: "s"}
+	}
+
+	t.Run(
+		"subscribe", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			c.setHubPending(1, topic)
+			s.Source.EXPECT().GetSource(ctx, uint(1)).Return(newSource(), nil).Times(2)
+			s.Source.EXPECT().UpsertSource(ctx, uint(1), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, id uint, source *model.Source) error {
+					assert.True(t, source.HubActive(time.Now().Add(time.Hour-time.Minute)))
+					assert.False(t, source.HubActive(time.Now().Add(time.Hour+time.Minute)))
+					return nil
+				},
+			)
+			assert.Nil(t, c.VerifyHubIntent(ctx, 1, "subscribe", topic, 3600))
+			// 同一个请求只确认一次
+			assert.ErrorIs(t, c.VerifyHubIntent(ctx, 1, "subscribe", topic, 3600), ErrHubIntentMismatch)
+		},
+	)
+
+	t.Run(
+		"not requested", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			s.Source.EXPECT().GetSource(ctx, uint(1)).Return(newSource(), nil).Times(2)
+			assert.ErrorIs(t, c.VerifyHubIntent(ctx, 1, "subscribe", topic, 3600), ErrHubIntentMismatch)
+
+			c.hubPending[1] = pendingHubRequest{topic: topic, sentAt: time.Now().Add(-2 * hubPendingTimeout)}
+			assert.ErrorIs(t, c.VerifyHubIntent(ctx, 1, "subscribe", topic, 3600), ErrHubIntentMismatch)
+		},
+	)
+
+	t.Run(
+		"lease capped", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			c.setHubPending(1, topic)
+			s.Source.EXPECT().GetSource(ctx, uint(1)).Return(newSource(), nil)
+			s.Source.EXPECT().UpsertSource(ctx, uint(1), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, id uint, source *model.Source) error {
+					assert.True(t, source.HubActive(time.Now().Add(hubMaxLease()-time.Minute)))
+					assert.False(t, source.HubActive(time.Now().Add(hubMaxLease()+time.Minute)))
+					return nil
+				},
+			)
+			assert.Nil(t, c.VerifyHubIntent(ctx, 1, "subscribe", topic, 10*365*24*3600))
+		},
+	)
+
+	t.Run(
+		"topic mismatch", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			c.setHubPending(1, topic)
+			s.Source.EXPECT().GetSource(ctx, uint(1)).Return(newSource(), nil)
+			assert.ErrorIs(t, c.VerifyHubIntent(ctx, 1, "subscribe", "https://other.example.com/", 3600), ErrHubIntentMismatch)
+		},
+	)
+
+	t.Run(
+		"unsubscribe", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			s.Source.EXPECT().GetSource(ctx, uint(2)).Return(nil, storage.ErrRecordNotFound)
+			assert.Nil(t, c.VerifyHubIntent(ctx, 2, "unsubscribe", topic, 0))
+
+			s.Source.EXPECT().GetSource(ctx, uint(1)).Return(newSource(), nil)
+			assert.ErrorIs(t, c.VerifyHubIntent(ctx, 1, "unsubscribe", topic, 0), ErrHubIntentMismatch)
+		},
+	)
+
+	t.Run(
+		"denied", func(t *testing.T) {
+			c, s := getTestCore(t)
+			defer s.Ctrl.Finish()
+			source := newSource()
+			leaseUntil := time.Now().Add(time.Hour)
+			source.HubLeaseUntil = &leaseUntil
+			s.Source.EXPECT().GetSource(ctx, uint(1)).Return(source, nil)
+			s.Source.EXPECT().UpsertSource(ctx, uint(1), gomock.Any()).DoAndReturn(
+				func(ctx context.Context, id uint, source *model.Source) error {
+					assert.Nil(t, source.HubLeaseUntil)
+					return nil
+				},
+			)
+			assert.Nil(t, c.VerifyHubIntent(ctx, 1, "denied", topic, 0))
+		},
+	)
+}
+
+func TestHubLeaseValid(t *testing.T) {
+	now := time.Now()
+	source := &model.Source{HubURL: "https://hub.example.com/"}
+	assert.True(t, hubLeaseValid(source, now))
+
+	until := now.Add(hubMaxLease() - time.Hour)
+	source.HubLeaseUntil = &until
+	assert.True(t, hubLeaseValid(source, now))
+
+	// 超过上限的有效期不是我们请求的，需要重新订阅
+	until = now.Add(5 * 365 * 24 * time.Hour)
+	assert.False(t, hubLeaseValid(source, now))
+}
@@ -0,0 +1,82 @@
+package feed
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// DefaultMaxBodySize default limit of a feed response body
+const DefaultMaxBodySize int64 = 10 << 20
+
+var (
+	// ErrFeedTooLarge the response body exceeds the configured limit
+	ErrFeedTooLarge = errors.New("feed is too large")
+	// ErrHTMLPage the response is a web page, feeds may be discovered from it
+	ErrHTMLPage = fmt.Errorf("%w: this is an HTML page, not a feed", ErrNotFeed)
+)
+
+// xmlEncodingDecl encoding declaration in the XML prolog
+var xmlEncodingDecl = regexp.MustCompile(`^(\s*<\?xml[^>]*?encoding\s*=\s*)("[^"]*"|'[^']*')`)
+
+// readBody reads the response body, failing with ErrFeedTooLarge when it is larger than limit, limit <= 0 means unlimited
+func readBody(resp *http.Response, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		return io.ReadAll(resp.Body)
+	}
+	if resp.ContentLength > limit {
+		return nil, fmt.Errorf("%w: %d bytes, limit is %d bytes", ErrFeedTooLarge, resp.ContentLength, limit)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, fmt.Errorf("%w: limit is %d bytes", ErrFeedTooLarge, limit)
+	}
+	return body, nil
+}
+
+// decodeBody converts the body to UTF-8 using the charset of the Content-Type header. The header takes
+// precedence over the XML prolog, so the prolog is rewritten to match; without a header charset the
+// prolog encoding is left to the feed parser
+func decodeBody(body []byte, contentType string) []byte {
+	_, params, err := mime.ParseMediaType(contentType)
+	if err != nil || params["charset"] == "" {
+		return body
+	}
+	label := strings.ToLower(strings.TrimSpace(params["charset"]))
+	if label != "utf-8" && label != "utf8" && label != "us-ascii" {
+		r, err := charset.NewReaderLabel(label, bytes.NewReader(body))
+		if err != nil {
+			// 未知编码交给解析器处理
+			return body
+		}
+		decoded, err := io.ReadAll(r)
+		if err != nil {
+			return body
+		}
+		body = decoded
+	}
+	return xmlEncodingDecl.ReplaceAll(body, []byte(`${1}"utf-8"`))
+}
+
+// notFeedError describes a response that is not a feed by its content type
+func notFeedError(body []byte, contentType string) error {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(body))
+	if mediaType == "text/html" || mediaType == "application/xhtml+xml" || sniffed == "text/html" {
+		return ErrHTMLPage
+	}
+	if mediaType == "" {
+		mediaType = sniffed
+	}
+	return fmt.Errorf("%w: content type is %s", ErrNotFeed, mediaType)
+}
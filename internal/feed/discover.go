@@ -0,0 +1,127 @@
+package feed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// ErrNotFeed the response is not a feed, usually a web page
+var ErrNotFeed = errors.New("response is not a feed")
+
+// DiscoveredFeed feed found on a web page
+type DiscoveredFeed struct {
+	URL   string
+	Title string
+}
+
+// feedLinkTypes types of <link rel="alternate"> pointing to a feed
+var feedLinkTypes = map[string]bool{
+	"application/rss+xml":   true,
+	"application/atom+xml":  true,
+	"application/feed+json": true,
+}
+
+// commonFeedPaths paths probed when the page does not advertise any feed
+var commonFeedPaths = []string{"/feed", "/rss", "/rss.xml", "/atom.xml", "/feed.xml", "/index.xml"}
+
+// Discover finds the feeds of a web page, from its <link rel="alternate"> tags
+// or, when it has none, by probing common feed paths of the site
+func (p *FeedParser) Discover(ctx context.Context, pageURL string) ([]DiscoveredFeed, error) {
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := p.client.GetWithContext(ctx, pageURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return nil, errors.New(resp.Status)
+	}
+	// 跟随跳转后以最终地址解析相对链接
+	if resp.Request != nil && resp.Request.URL != nil {
+		base = resp.Request.URL
+	}
+
+	body, err := readBody(resp, p.maxBodySize)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if feeds := feedLinks(doc, base); len(feeds) > 0 {
+		return feeds, nil
+	}
+	return p.probeFeedPaths(ctx, base), nil
+}
+
+// feedLinks returns the feeds advertised in the page head, relative links are resolved against base
+func feedLinks(doc *goquery.Document, base *url.URL) []DiscoveredFeed {
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := base.Parse(strings.TrimSpace(href)); err == nil {
+			base = u
+		}
+	}
+
+	var feeds []DiscoveredFeed
+	seen := make(map[string]bool)
+	doc.Find("link[rel][href]").Each(
+		func(_ int, s *goquery.Selection) {
+			if !hasToken(s.AttrOr("rel", ""), "alternate") {
+				return
+			}
+			mediaType, _, err := mime.ParseMediaType(s.AttrOr("type", ""))
+			if err != nil || !feedLinkTypes[mediaType] {
+				return
+			}
+			u, err := base.Parse(strings.TrimSpace(s.AttrOr("href", "")))
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+				return
+			}
+			link := u.String()
+			if seen[link] {
+				return
+			}
+			seen[link] = true
+			feeds = append(feeds, DiscoveredFeed{URL: link, Title: strings.TrimSpace(s.AttrOr("title", ""))})
+		},
+	)
+	return feeds
+}
+
+// probeFeedPaths tries common feed paths of the site and returns the first one that is a feed
+func (p *FeedParser) probeFeedPaths(ctx context.Context, base *url.URL) []DiscoveredFeed {
+	for _, path := range commonFeedPaths {
+		if ctx.Err() != nil {
+			return nil
+		}
+		u := url.URL{Scheme: base.Scheme, Host: base.Host, Path: path}
+		result, err := p.Fetch(ctx, u.String(), nil)
+		if err != nil || result.Feed == nil {
+			continue
+		}
+		return []DiscoveredFeed{{URL: u.String(), Title: result.Feed.Title}}
+	}
+	return nil
+}
+
+// hasToken reports whether the space separated list contains token, ignoring case
+func hasToken(list, token string) bool {
+	for _, f := range strings.Fields(list) {
+		if strings.EqualFold(f, token) {
+			return true
+		}
+	}
+	return false
+}
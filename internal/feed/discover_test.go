@@ -0,0 +1,82 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/pkg/client"
+)
+
+const testPageWithLinks = `<!DOCTYPE html>
+<html><head>
+<title>Blog</title>
+<link rel="alternate" type="application/rss+xml" title="Posts" href="/posts.xml">
+<link rel="Alternate" type="application/atom+xml; charset=utf-8" href="https://other.example.com/atom">
+<link rel="alternate" type="application/feed+json" title="JSON" href="feed.json">
+<link rel="alternate" type="application/rss+xml" href="/posts.xml">
+<link rel="alternate" hreflang="de" href="/de/">
+<link rel="stylesheet" type="text/css" href="/style.css">
+</head><body></body></html>`
+
+const testPageWithoutLinks = `<!DOCTYPE html><html><head><title>Site</title></head><body>hello</body></html>`
+
+func TestFeedParser_Discover(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/blog/":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(testPageWithLinks))
+		case "/site":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write([]byte(testPageWithoutLinks))
+		case "/rss.xml":
+			_, _ = w.Write([]byte(testRSS))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	p := NewFeedParser(client.NewHttpClient())
+	ctx := context.Background()
+
+	t.Run(
+		"fetch html page", func(t *testing.T) {
+			_, err := p.Fetch(ctx, ts.URL+"/site", nil)
+			assert.ErrorIs(t, err, ErrNotFeed)
+		},
+	)
+
+	t.Run(
+		"alternate links", func(t *testing.T) {
+			feeds, err := p.Discover(ctx, ts.URL+"/blog/")
+			assert.Nil(t, err)
+			assert.Equal(
+				t, []DiscoveredFeed{
+					{URL: ts.URL + "/posts.xml", Title: "Posts"},
+					{URL: "https://other.example.com/atom"},
+					{URL: ts.URL + "/blog/feed.json", Title: "JSON"},
+				}, feeds,
+			)
+		},
+	)
+
+	t.Run(
+		"probe common paths", func(t *testing.T) {
+			feeds, err := p.Discover(ctx, ts.URL+"/site")
+			assert.Nil(t, err)
+			assert.Equal(t, []DiscoveredFeed{{URL: ts.URL + "/rss.xml", Title: "Test Feed"}}, feeds)
+		},
+	)
+
+	t.Run(
+		"error status", func(t *testing.T) {
+			_, err := p.Discover(ctx, ts.URL+"/missing")
+			assert.Error(t, err)
+		},
+	)
+}
@@ -0,0 +1,123 @@
+package feed
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	"github.com/mmcdole/gofeed/rss"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+// custom keys the RSS channel hints are kept under, the universal feed has no fields for them
+const (
+	customTTL       = "ttl"
+	customSkipHours = "skipHours"
+	customSkipDays  = "skipDays"
+)
+
+// syUpdatePeriods minutes of the sy:updatePeriod values
+var syUpdatePeriods = map[string]int{
+	"hourly":  60,
+	"daily":   24 * 60,
+	"weekly":  7 * 24 * 60,
+	"monthly": 30 * 24 * 60,
+	"yearly":  365 * 24 * 60,
+}
+
+// hintsRSSTranslator keeps <ttl>, <skipHours> and <skipDays> of RSS channels in Feed.Custom
+type hintsRSSTranslator struct {
+	gofeed.DefaultRSSTranslator
+}
+
+func (t *hintsRSSTranslator) Translate(feed interface{}) (*gofeed.Feed, error) {
+	result, err := t.DefaultRSSTranslator.Translate(feed)
+	if err != nil {
+		return nil, err
+	}
+	channel := feed.(*rss.Feed)
+	custom := map[string]string{}
+	if channel.TTL != "" {
+		custom[customTTL] = channel.TTL
+	}
+	if len(channel.SkipHours) > 0 {
+		custom[customSkipHours] = strings.Join(channel.SkipHours, ",")
+	}
+	if len(channel.SkipDays) > 0 {
+		custom[customSkipDays] = strings.Join(channel.SkipDays, ",")
+	}
+	if len(custom) > 0 {
+		result.Custom = custom
+	}
+	return result, nil
+}
+
+// feedHints reads the polling hints a feed declares, invalid values are ignored
+func feedHints(f *gofeed.Feed) model.FeedHints {
+	var hints model.FeedHints
+	if ttl, err := strconv.Atoi(strings.TrimSpace(f.Custom[customTTL])); err == nil && ttl > 0 {
+		hints.TTL = ttl
+	}
+	hints.UpdatePeriod = syUpdatePeriod(f)
+
+	seenHours := map[int]bool{}
+	for _, s := range strings.Split(f.Custom[customSkipHours], ",") {
+		hour, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || hour < 0 || hour > 24 {
+			continue
+		}
+		// some feeds use 24 for midnight
+		hour %= 24
+		if !seenHours[hour] {
+			seenHours[hour] = true
+			hints.SkipHours = append(hints.SkipHours, hour)
+		}
+	}
+	if len(hints.SkipHours) == 24 {
+		hints.SkipHours = nil
+	}
+
+	seenDays := map[int]bool{}
+	for _, s := range strings.Split(f.Custom[customSkipDays], ",") {
+		day, ok := parseWeekday(s)
+		if ok && !seenDays[day] {
+			seenDays[day] = true
+			hints.SkipDays = append(hints.SkipDays, day)
+		}
+	}
+	if len(hints.SkipDays) == 7 {
+		hints.SkipDays = nil
+	}
+	return hints
+}
+
+// syUpdatePeriod minutes between updates declared by sy:updatePeriod and sy:updateFrequency
+func syUpdatePeriod(f *gofeed.Feed) int {
+	sy := f.Extensions["sy"]
+	if sy == nil || len(sy["updatePeriod"]) == 0 {
+		return 0
+	}
+	period, ok := syUpdatePeriods[strings.ToLower(strings.TrimSpace(sy["updatePeriod"][0].Value))]
+	if !ok {
+		return 0
+	}
+	frequency := 1
+	if len(sy["updateFrequency"]) > 0 {
+		if n, err := strconv.Atoi(strings.TrimSpace(sy["updateFrequency"][0].Value)); err == nil && n > 0 {
+			frequency = n
+		}
+	}
+	return period / frequency
+}
+
+func parseWeekday(s string) (int, bool) {
+	s = strings.TrimSpace(s)
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if strings.EqualFold(s, day.String()) {
+			return int(day), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,141 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// hubLinks finds the WebSub hub and the topic (self link) of a feed, from the Link response
+// headers first and then from the feed itself, relative links are resolved against base
+func hubLinks(header http.Header, body []byte, base *url.URL) (hub, self string) {
+	hub, self = headerHubLinks(header)
+	if hub == "" || self == "" {
+		var bodyHub, bodySelf string
+		if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '{' {
+			bodyHub, bodySelf = jsonHubLinks(trimmed)
+		} else {
+			bodyHub, bodySelf = xmlHubLinks(body)
+		}
+		if hub == "" {
+			hub = bodyHub
+		}
+		if self == "" {
+			self = bodySelf
+		}
+	}
+	return resolveLink(base, hub), resolveLink(base, self)
+}
+
+// headerHubLinks parses Link headers such as `<https://hub.example.com/>; rel="hub"`
+func headerHubLinks(header http.Header) (hub, self string) {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			target, params, ok := strings.Cut(link, ";")
+			target = strings.TrimSpace(target)
+			if !ok || !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+			target = strings.Trim(target, "<>")
+			for _, param := range strings.Split(params, ";") {
+				name, rel, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(strings.TrimSpace(name), "rel") {
+					continue
+				}
+				rel = strings.Trim(strings.TrimSpace(rel), `"`)
+				if hub == "" && hasToken(rel, "hub") {
+					hub = target
+				}
+				if self == "" && hasToken(rel, "self") {
+					self = target
+				}
+			}
+		}
+	}
+	return hub, self
+}
+
+// xmlHubLinks reads the <link rel="hub"> and <link rel="self"> elements of an RSS or Atom feed,
+// RSS feeds declare them as atom:link. Only the feed header is read, it stops at the first item
+func xmlHubLinks(body []byte) (hub, self string) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.Strict = false
+	decoder.CharsetReader = charset.NewReaderLabel
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return hub, self
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch strings.ToLower(start.Name.Local) {
+		case "item", "entry":
+			return hub, self
+		case "link":
+			var rel, href string
+			for _, attr := range start.Attr {
+				switch strings.ToLower(attr.Name.Local) {
+				case "rel":
+					rel = attr.Value
+				case "href":
+					href = strings.TrimSpace(attr.Value)
+				}
+			}
+			if href == "" {
+				continue
+			}
+			if hub == "" && hasToken(rel, "hub") {
+				hub = href
+			}
+			if self == "" && hasToken(rel, "self") {
+				self = href
+			}
+		}
+	}
+}
+
+// jsonHubLinks reads the hubs and feed_url of a JSON Feed
+func jsonHubLinks(body []byte) (hub, self string) {
+	var jsonFeed struct {
+		FeedURL string `json:"feed_url"`
+		Hubs    []struct {
+			Type string `json:"type"`
+			URL  string `json:"url"`
+		} `json:"hubs"`
+	}
+	if err := json.Unmarshal(body, &jsonFeed); err != nil {
+		return "", ""
+	}
+	for _, h := range jsonFeed.Hubs {
+		if strings.EqualFold(h.Type, "websub") || strings.EqualFold(h.Type, "pubsubhubbub") {
+			hub = h.URL
+			break
+		}
+	}
+	return hub, jsonFeed.FeedURL
+}
+
+// resolveLink resolves link against base, returning "" for links that are not http(s)
+func resolveLink(base *url.URL, link string) string {
+	if link == "" {
+		return ""
+	}
+	var u *url.URL
+	var err error
+	if base != nil {
+		u, err = base.Parse(link)
+	} else {
+		u, err = url.Parse(link)
+	}
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return u.String()
+}
@@ -1,29 +1,109 @@
 package feed
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"net/http"
 
+	"github.com/zintus/flowerss-bot/internal/model"
 	"github.com/zintus/flowerss-bot/pkg/client"
 
 	"github.com/mmcdole/gofeed"
 )
 
 type FeedParser struct {
-	client *client.HttpClient
-	parser *gofeed.Parser
+	client      *client.HttpClient
+	parser      *gofeed.Parser
+	maxBodySize int64
 }
 
-func NewFeedParser(httpClient *client.HttpClient) *FeedParser {
-	return &FeedParser{
-		client: httpClient,
-		parser: gofeed.NewParser(),
+type FeedParserOption func(p *FeedParser)
+
+// WithMaxBodySize limits the size of response bodies, 0 means unlimited
+func WithMaxBodySize(size int64) FeedParserOption {
+	return func(p *FeedParser) {
+		p.maxBodySize = size
+	}
+}
+
+// FetchOptions options of a single feed request
+type FetchOptions struct {
+	// ETag of the previous response, sent as If-None-Match
+	ETag string
+	// LastModified of the previous response, sent as If-Modified-Since
+	LastModified string
+	// Headers extra request headers
+	Headers map[string]string
+	// BasicAuthUser and BasicAuthPassword are sent as basic auth when either is set
+	BasicAuthUser     string
+	BasicAuthPassword string
+	// UserAgent overrides the default user agent when set
+	UserAgent string
+}
+
+// FetchResult result of a single feed request
+type FetchResult struct {
+	// Feed is nil when NotModified is true
+	Feed         *gofeed.Feed
+	ETag         string
+	LastModified string
+	// NotModified the server answered 304, the feed has no new content
+	NotModified bool
+	// Hub WebSub hub advertised by the feed, empty when it has none
+	Hub string
+	// Self topic URL of the feed, the URL to subscribe at the hub
+	Self string
+	// Hints polling hints declared by the feed
+	Hints model.FeedHints
+	// PermanentRedirect the URL the feed has permanently moved to, empty when it was not redirected with 301 or 308
+	PermanentRedirect string
+}
+
+func NewFeedParser(httpClient *client.HttpClient, opts ...FeedParserOption) *FeedParser {
+	parser := gofeed.NewParser()
+	parser.RSSTranslator = &hintsRSSTranslator{}
+	p := &FeedParser{
+		client:      httpClient,
+		parser:      parser,
+		maxBodySize: DefaultMaxBodySize,
+	}
+	for _, opt := range opts {
+		opt(p)
 	}
+	return p
 }
 
 func (p *FeedParser) ParseFromURL(ctx context.Context, URL string) (*gofeed.Feed, error) {
-	resp, err := p.client.GetWithContext(ctx, URL)
+	result, err := p.Fetch(ctx, URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return result.Feed, nil
+}
+
+// Fetch requests and parses the feed, sending conditional request headers when opts carries cache validators
+func (p *FeedParser) Fetch(ctx context.Context, URL string, opts *FetchOptions) (*FetchResult, error) {
+	var clientOpts []client.HttpClientOption
+	if opts != nil {
+		if opts.ETag != "" {
+			clientOpts = append(clientOpts, client.WithHeader("If-None-Match", opts.ETag))
+		}
+		if opts.LastModified != "" {
+			clientOpts = append(clientOpts, client.WithHeader("If-Modified-Since", opts.LastModified))
+		}
+		for name, value := range opts.Headers {
+			clientOpts = append(clientOpts, client.WithHeader(name, value))
+		}
+		if opts.BasicAuthUser != "" || opts.BasicAuthPassword != "" {
+			clientOpts = append(clientOpts, client.WithBasicAuth(opts.BasicAuthUser, opts.BasicAuthPassword))
+		}
+		if opts.UserAgent != "" {
+			clientOpts = append(clientOpts, client.WithUserAgent(opts.UserAgent))
+		}
+	}
+
+	resp, err := p.client.GetWithContext(ctx, URL, clientOpts...)
 	if err != nil {
 		return nil, err
 	}
@@ -37,19 +117,59 @@ func (p *FeedParser) ParseFromURL(ctx context.Context, URL string) (*gofeed.Feed
 		}()
 	}
 
+	result := &FetchResult{
+		ETag:              resp.Header.Get("ETag"),
+		LastModified:      resp.Header.Get("Last-Modified"),
+		PermanentRedirect: permanentRedirect(resp),
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		if opts != nil {
+			// 304 may omit the validators, keep the ones we sent
+			if result.ETag == "" {
+				result.ETag = opts.ETag
+			}
+			if result.LastModified == "" {
+				result.LastModified = opts.LastModified
+			}
+		}
+		return result, nil
+	}
+
 	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
 		return nil, errors.New(resp.Status)
 	}
-	feed, err := p.parser.Parse(resp.Body)
+	body, err := readBody(resp, p.maxBodySize)
 	if err != nil {
 		return nil, err
 	}
-	// Use UpdatedParsed if available, otherwise PublishedParsed
-	if feed.UpdatedParsed != nil {
-		return feed, nil
+	feed, err := p.Parse(body, resp.Header.Get("Content-Type"))
+	if err != nil {
+		return nil, err
+	}
+	result.Feed = feed
+	result.Hub, result.Self = hubLinks(resp.Header, body, resp.Request.URL)
+	result.Hints = feedHints(feed)
+	return result, nil
+}
+
+// Parse parses a feed body, contentType is the Content-Type header it was served with
+func (p *FeedParser) Parse(body []byte, contentType string) (*gofeed.Feed, error) {
+	feed, err := p.parser.Parse(bytes.NewReader(decodeBody(body, contentType)))
+	if errors.Is(err, gofeed.ErrFeedTypeNotDetected) {
+		return nil, notFeedError(body, contentType)
+	}
+	if err != nil {
+		return nil, err
 	}
-	if feed.PublishedParsed != nil {
+	// Use UpdatedParsed if available, otherwise PublishedParsed
+	if feed.UpdatedParsed == nil && feed.PublishedParsed != nil {
 		feed.UpdatedParsed = feed.PublishedParsed
 	}
 	return feed, nil
 }
+
+// Client the http client used to request feeds, requests to addresses taken from feeds should use it as well
+func (p *FeedParser) Client() *client.HttpClient {
+	return p.client
+}
@@ -0,0 +1,333 @@
+package feed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+	"github.com/zintus/flowerss-bot/pkg/client"
+)
+
+const testRSS = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+<title>Test Feed</title>
+<link>https://example.com/</link>
+<item><title>Item 1</title><link>https://example.com/1</link><guid>1</guid></item>
+</channel>
+</rss>`
+
+func TestFeedParser_Fetch(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/feed":
+			if r.Header.Get("If-None-Match") == etag || r.Header.Get("If-Modified-Since") == lastModified {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Last-Modified", lastModified)
+			_, _ = w.Write([]byte(testRSS))
+		case "/private":
+			user, password, ok := r.BasicAuth()
+			if !ok || user != "user" || password != "pass" ||
+				r.Header.Get("X-Feed-Key") != "key" || r.UserAgent() != "reader/1.0" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(testRSS))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	p := NewFeedParser(client.NewHttpClient())
+	ctx := context.Background()
+
+	t.Run(
+		"full response", func(t *testing.T) {
+			result, err := p.Fetch(ctx, ts.URL+"/feed", nil)
+			assert.Nil(t, err)
+			assert.False(t, result.NotModified)
+			assert.Equal(t, "Test Feed", result.Feed.Title)
+			assert.Equal(t, etag, result.ETag)
+			assert.Equal(t, lastModified, result.LastModified)
+		},
+	)
+
+	t.Run(
+		"not modified by etag", func(t *testing.T) {
+			result, err := p.Fetch(ctx, ts.URL+"/feed", &FetchOptions{ETag: etag})
+			assert.Nil(t, err)
+			assert.True(t, result.NotModified)
+			assert.Nil(t, result.Feed)
+			assert.Equal(t, etag, result.ETag)
+		},
+	)
+
+	t.Run(
+		"not modified by last modified", func(t *testing.T) {
+			result, err := p.Fetch(ctx, ts.URL+"/feed", &FetchOptions{LastModified: lastModified})
+			assert.Nil(t, err)
+			assert.True(t, result.NotModified)
+			assert.Equal(t, lastModified, result.LastModified)
+		},
+	)
+
+	t.Run(
+		"stale validators", func(t *testing.T) {
+			result, err := p.Fetch(ctx, ts.URL+"/feed", &FetchOptions{ETag: `"v0"`})
+			assert.Nil(t, err)
+			assert.False(t, result.NotModified)
+			assert.NotNil(t, result.Feed)
+		},
+	)
+
+	t.Run(
+		"request settings", func(t *testing.T) {
+			_, err := p.Fetch(ctx, ts.URL+"/private", nil)
+			assert.Error(t, err)
+
+			result, err := p.Fetch(
+				ctx, ts.URL+"/private", &FetchOptions{
+					Headers:           map[string]string{"X-Feed-Key": "key"},
+					BasicAuthUser:     "user",
+					BasicAuthPassword: "pass",
+					UserAgent:         "reader/1.0",
+				},
+			)
+			assert.Nil(t, err)
+			assert.Equal(t, "Test Feed", result.Feed.Title)
+		},
+	)
+
+	t.Run(
+		"error status", func(t *testing.T) {
+			_, err := p.Fetch(ctx, ts.URL+"/missing", nil)
+			assert.Error(t, err)
+		},
+	)
+}
+
+func TestFeedParser_FetchBody(t *testing.T) {
+	// "Тест" in windows-1251
+	cp1251Title := string([]byte{0xd2, 0xe5, 0xf1, 0xf2})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/large":
+			_, _ = w.Write([]byte(testRSS + strings.Repeat(" ", 1024)))
+		case "/html":
+			w.Header().Set("Content-Type", "text/plain")
+			_, _ = w.Write([]byte(testPageWithoutLinks))
+		case "/image":
+			w.Header().Set("Content-Type", "image/png")
+			_, _ = w.Write([]byte("\x89PNG\r\n\x1a\n"))
+		case "/header-charset":
+			// 请求头的编码优先于 XML 声明
+			w.Header().Set("Content-Type", "application/rss+xml; charset=windows-1251")
+			_, _ = w.Write([]byte(strings.Replace(testRSS, "Test Feed", cp1251Title, 1)))
+		case "/prolog-charset":
+			w.Header().Set("Content-Type", "application/xml")
+			body := strings.Replace(testRSS, "UTF-8", "ISO-8859-1", 1)
+			_, _ = w.Write([]byte(strings.Replace(body, "Test Feed", "Caf\xe9", 1)))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	p := NewFeedParser(client.NewHttpClient(), WithMaxBodySize(int64(len(testRSS)+512)))
+	ctx := context.Background()
+
+	t.Run(
+		"too large", func(t *testing.T) {
+			_, err := p.Fetch(ctx, ts.URL+"/large", nil)
+			assert.ErrorIs(t, err, ErrFeedTooLarge)
+
+			unlimited := NewFeedParser(client.NewHttpClient(), WithMaxBodySize(0))
+			result, err := unlimited.Fetch(ctx, ts.URL+"/large", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, "Test Feed", result.Feed.Title)
+		},
+	)
+
+	t.Run(
+		"not feed", func(t *testing.T) {
+			_, err := p.Fetch(ctx, ts.URL+"/html", nil)
+			assert.ErrorIs(t, err, ErrHTMLPage)
+
+			_, err = p.Fetch(ctx, ts.URL+"/image", nil)
+			assert.ErrorIs(t, err, ErrNotFeed)
+			assert.NotErrorIs(t, err, ErrHTMLPage)
+			assert.Contains(t, err.Error(), "image/png")
+		},
+	)
+
+	t.Run(
+		"charset", func(t *testing.T) {
+			result, err := p.Fetch(ctx, ts.URL+"/header-charset", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, "Тест", result.Feed.Title)
+
+			result, err = p.Fetch(ctx, ts.URL+"/prolog-charset", nil)
+			assert.Nil(t, err)
+			assert.Equal(t, "Café", result.Feed.Title)
+		},
+	)
+}
+
+func TestFeedParser_Hub(t *testing.T) {
+	const atomWithHub = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+<title>Atom</title>
+<link rel="self" href="/atom.xml"/>
+<link rel="hub" href="https://hub.example.com/"/>
+<entry><title>1</title><id>1</id><link rel="hub" href="https://entry.example.com/"/></entry>
+</feed>`
+	const rssWithHub = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+<title>RSS</title>
+<link>https://example.com/</link>
+<atom:link rel="hub" href="https://pubsubhubbub.appspot.com/"/>
+<atom:link rel="self" type="application/rss+xml" href="https://example.com/rss"/>
+</channel>
+</rss>`
+	const jsonWithHub = `{"version": "https://jsonfeed.org/version/1.1", "title": "JSON",
+"feed_url": "https://example.com/feed.json", "hubs": [{"type": "WebSub", "url": "https://hub.example.com/json"}], "items": []}`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/atom":
+			_, _ = w.Write([]byte(atomWithHub))
+		case "/rss":
+			_, _ = w.Write([]byte(rssWithHub))
+		case "/json":
+			_, _ = w.Write([]byte(jsonWithHub))
+		case "/header":
+			w.Header().Add("Link", `<https://hub.example.com/header>; rel="hub", <https://example.com/topic>; rel="self"`)
+			_, _ = w.Write([]byte(rssWithHub))
+		case "/none":
+			_, _ = w.Write([]byte(testRSS))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	p := NewFeedParser(client.NewHttpClient())
+	ctx := context.Background()
+	tests := []struct {
+		path string
+		hub  string
+		self string
+	}{
+		{"/atom", "https://hub.example.com/", ts.URL + "/atom.xml"},
+		{"/rss", "https://pubsubhubbub.appspot.com/", "https://example.com/rss"},
+		{"/json", "https://hub.example.com/json", "https://example.com/feed.json"},
+		{"/header", "https://hub.example.com/header", "https://example.com/topic"},
+		{"/none", "", ""},
+	}
+	for _, tt := range tests {
+		result, err := p.Fetch(ctx, ts.URL+tt.path, nil)
+		if assert.Nil(t, err, tt.path) {
+			assert.Equal(t, tt.hub, result.Hub, tt.path)
+			assert.Equal(t, tt.self, result.Self, tt.path)
+		}
+	}
+}
+
+func TestFeedParser_Hints(t *testing.T) {
+	const rssWithHints = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+<channel>
+<title>RSS</title>
+<link>https://example.com/</link>
+<ttl>90</ttl>
+<skipHours><hour>24</hour><hour>1</hour><hour>25</hour><hour>1</hour></skipHours>
+<skipDays><day>Saturday</day><day>sunday</day><day>Someday</day></skipDays>
+<item><title>1</title><guid>1</guid></item>
+</channel>
+</rss>`
+	const rssWithSy = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/">
+<channel>
+<title>RSS</title>
+<link>https://example.com/</link>
+<ttl>none</ttl>
+<sy:updatePeriod>daily</sy:updatePeriod>
+<sy:updateFrequency>4</sy:updateFrequency>
+</channel>
+</rss>`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hints":
+			_, _ = w.Write([]byte(rssWithHints))
+		case "/sy":
+			_, _ = w.Write([]byte(rssWithSy))
+		default:
+			_, _ = w.Write([]byte(testRSS))
+		}
+	}))
+	defer ts.Close()
+
+	p := NewFeedParser(client.NewHttpClient())
+	ctx := context.Background()
+	tests := []struct {
+		path  string
+		hints model.FeedHints
+	}{
+		{"/hints", model.FeedHints{TTL: 90, SkipHours: []int{0, 1}, SkipDays: []int{6, 0}}},
+		{"/sy", model.FeedHints{UpdatePeriod: 360}},
+		{"/none", model.FeedHints{}},
+	}
+	for _, tt := range tests {
+		result, err := p.Fetch(ctx, ts.URL+tt.path, nil)
+		if assert.Nil(t, err, tt.path) {
+			assert.Equal(t, tt.hints, result.Hints, tt.path)
+		}
+	}
+}
+
+func TestFeedParser_Redirect(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/mirror", http.StatusMovedPermanently)
+		case "/mirror":
+			http.Redirect(w, r, "/feed", http.StatusFound)
+		case "/permanent":
+			http.Redirect(w, r, "/moved", http.StatusPermanentRedirect)
+		case "/temporary":
+			http.Redirect(w, r, "/moved", http.StatusTemporaryRedirect)
+		default:
+			_, _ = w.Write([]byte(testRSS))
+		}
+	}))
+	defer ts.Close()
+
+	p := NewFeedParser(client.NewHttpClient())
+	ctx := context.Background()
+	tests := []struct {
+		path   string
+		target string
+	}{
+		{"/feed", ""},
+		{"/moved", ts.URL + "/mirror"},
+		{"/permanent", ts.URL + "/mirror"},
+		{"/temporary", ""},
+	}
+	for _, tt := range tests {
+		result, err := p.Fetch(ctx, ts.URL+tt.path, nil)
+		if assert.Nil(t, err, tt.path) {
+			assert.Equal(t, tt.target, result.PermanentRedirect, tt.path)
+		}
+	}
+}
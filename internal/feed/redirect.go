@@ -0,0 +1,30 @@
+package feed
+
+import (
+	"net/http"
+)
+
+// permanentRedirect returns the URL reached by following the permanent redirects (301 and 308) at the start
+// of the redirect chain of resp, temporary redirects after them are not part of the move.
+// Empty when the first response was not a permanent redirect.
+func permanentRedirect(resp *http.Response) string {
+	// resp.Request is the last request, each request keeps the redirect response that caused it
+	var chain []*http.Request
+	for req := resp.Request; req != nil; {
+		chain = append(chain, req)
+		if req.Response == nil {
+			break
+		}
+		req = req.Response.Request
+	}
+
+	target := ""
+	for i := len(chain) - 2; i >= 0; i-- {
+		status := chain[i].Response.StatusCode
+		if status != http.StatusMovedPermanently && status != http.StatusPermanentRedirect {
+			break
+		}
+		target = chain[i].URL.String()
+	}
+	return target
+}
@@ -0,0 +1,61 @@
+// Package metrics 以 Prometheus 文本格式导出 bot 的运行指标
+package metrics
+
+import (
+	"io"
+	"net/http"
+)
+
+// Default 默认指标集合，下面的指标都注册在这里
+var Default = &Registry{}
+
+var (
+	// FetchDuration 订阅源抓取耗时，按主机和结果（ok、not_modified、error）区分
+	FetchDuration = Default.NewHistogramVec(
+		"flowerss_fetch_duration_seconds", "Feed fetch latency by source host and outcome.",
+		[]float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}, "host", "outcome",
+	)
+	// ItemsDiscovered 抓取到的新文章数量
+	ItemsDiscovered = Default.NewCounterVec(
+		"flowerss_items_discovered_total", "New feed items stored, by source host.", "host",
+	)
+	// MessagesSent 成功发送的 telegram 消息数量
+	MessagesSent = Default.NewCounterVec(
+		"flowerss_messages_sent_total", "Messages sent to telegram.",
+	)
+	// MessagesFailed 发送失败的 telegram 消息数量，按错误类型区分
+	MessagesFailed = Default.NewCounterVec(
+		"flowerss_messages_failed_total", "Messages that telegram rejected, by error class.", "class",
+	)
+	// FloodWaits 因 telegram 限流而等待的次数
+	FloodWaits = Default.NewCounterVec(
+		"flowerss_flood_wait_sleeps_total", "Sleeps caused by telegram flood control.",
+	)
+	// FloodWaitSeconds 因 telegram 限流而等待的总时间
+	FloodWaitSeconds = Default.NewCounterVec(
+		"flowerss_flood_wait_seconds_total", "Seconds slept because of telegram flood control.",
+	)
+	// TelegraphPublishes telegraph 发布次数，按结果（ok、too_big、error）区分
+	TelegraphPublishes = Default.NewCounterVec(
+		"flowerss_telegraph_publishes_total", "Telegraph page publishes by outcome.", "outcome",
+	)
+	// SchedulerCycleDuration 调度器每轮抓取的耗时
+	SchedulerCycleDuration = Default.NewHistogramVec(
+		"flowerss_scheduler_cycle_duration_seconds", "Duration of one scheduler cycle.",
+		[]float64{1, 5, 10, 30, 60, 120, 300, 600},
+	)
+	// SchedulerLastSuccess 调度器上一轮成功完成的时间
+	SchedulerLastSuccess = Default.NewGauge(
+		"flowerss_scheduler_last_success_timestamp_seconds", "Unix time of the last successful scheduler cycle.",
+	)
+)
+
+// WriteText 以 Prometheus 文本格式输出默认指标集合
+func WriteText(w io.Writer) error {
+	return Default.WriteText(w)
+}
+
+// Handler 输出默认指标集合的 http handler
+func Handler() http.Handler {
+	return Default.Handler()
+}
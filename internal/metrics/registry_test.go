@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	r := &Registry{}
+	counter := r.NewCounterVec("test_total", "A counter.", "class")
+	gauge := r.NewGauge("test_gauge", "A gauge.")
+	histogram := r.NewHistogramVec("test_seconds", "A histogram.", []float64{0.5, 1}, "host")
+
+	counter.Inc("b")
+	counter.Add(2, "a\"\n")
+	gauge.Set(1.5)
+	histogram.Observe(0.2, "example.com")
+	histogram.Observe(0.7, "example.com")
+	histogram.Observe(3, "example.com")
+
+	buf := &bytes.Buffer{}
+	assert.NoError(t, r.WriteText(buf))
+	want := `# HELP test_total A counter.
+# TYPE test_total counter
+test_total{class="a\"\n"} 2
+test_total{class="b"} 1
+# HELP test_gauge A gauge.
+# TYPE test_gauge gauge
+test_gauge 1.5
+# HELP test_seconds A histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{host="example.com",le="0.5"} 1
+test_seconds_bucket{host="example.com",le="1"} 2
+test_seconds_bucket{host="example.com",le="+Inf"} 3
+test_seconds_sum{host="example.com"} 3.9
+test_seconds_count{host="example.com"} 3
+`
+	assert.Equal(t, want, buf.String())
+	assert.Equal(t, float64(1), counter.Value("b"))
+}
+
+func TestCounterVecLabelCount(t *testing.T) {
+	r := &Registry{}
+	counter := r.NewCounterVec("test_total", "A counter.", "class")
+	assert.Panics(t, func() { counter.Inc() })
+	assert.Panics(t, func() { counter.Add(-1, "a") })
+}
@@ -1,5 +1,12 @@
 package model
 
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
 // Content fetcher content
 type Content struct {
 	SourceID     uint
@@ -7,7 +14,53 @@ type Content struct {
 	RawID        string
 	RawLink      string
 	Title        string
-	Description  string `gorm:"-"` //ignore to db
+	Description  string // 正文，超过保留时间后清空
+	Summary      string // 摘要，超过保留时间后清空
+	Author       string
+	Categories   string     // 以换行分隔
+	PublishedAt  *time.Time // 源中的发布时间，没有时为 nil
+	Enclosures   Enclosures `gorm:"type:text"`
 	TelegraphURL string
 	EditTime
 }
+
+// Enclosure 文章附件，如图片、音频和视频
+type Enclosure struct {
+	URL    string `json:"url"`
+	Type   string `json:"type,omitempty"`
+	Length string `json:"length,omitempty"`
+}
+
+// Enclosures 附件列表，以 JSON 保存
+type Enclosures []Enclosure
+
+// Value implements driver.Valuer
+func (e Enclosures) Value() (driver.Value, error) {
+	if len(e) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (e *Enclosures) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported enclosures value %T", value)
+	}
+	if len(data) == 0 {
+		*e = nil
+		return nil
+	}
+	return json.Unmarshal(data, e)
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// DeliveryStatus 推送状态
+type DeliveryStatus string
+
+const (
+	// DeliveryStatusPending 等待发送，包括等待重试
+	DeliveryStatusPending DeliveryStatus = "pending"
+	// DeliveryStatusSent 已发送
+	DeliveryStatusSent DeliveryStatus = "sent"
+	// DeliveryStatusFailed 放弃发送
+	DeliveryStatusFailed DeliveryStatus = "failed"
+	// DeliveryStatusFiltered 未通过订阅的过滤规则，不发送
+	DeliveryStatusFiltered DeliveryStatus = "filtered"
+)
+
+// Delivery 一条文章对一个订阅者的推送记录，发送前先持久化，保证重启后继续推送
+type Delivery struct {
+	ID            uint           `gorm:"primary_key;AUTO_INCREMENT"`
+	ContentHashID string         `gorm:"size:64;uniqueIndex:idx_delivery_content_user"`
+	UserID        int64          `gorm:"uniqueIndex:idx_delivery_content_user"`
+	SourceID      uint           `gorm:"index"`
+	Status        DeliveryStatus `gorm:"size:16;index:idx_delivery_due,priority:1"`
+	Digest        bool           // 合并到摘要中，在 NextAttemptAt 时与同一用户的其他摘要内容一起发送
+	Attempts      uint
+	LastError     string
+	NextAttemptAt time.Time `gorm:"index:idx_delivery_due,priority:2"`
+	SentAt        *time.Time
+	EditTime
+}
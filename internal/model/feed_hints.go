@@ -0,0 +1,76 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// FeedHints 订阅源在 feed 中声明的抓取提示，以 JSON 保存，为空时保存为 NULL
+type FeedHints struct {
+	TTL          int   `json:"ttl,omitempty"`           // RSS <ttl>，单位分钟
+	UpdatePeriod int   `json:"update_period,omitempty"` // sy:updatePeriod 除以 sy:updateFrequency，单位分钟
+	SkipHours    []int `json:"skip_hours,omitempty"`    // RSS <skipHours>，不需要抓取的小时，GMT 0-23
+	SkipDays     []int `json:"skip_days,omitempty"`     // RSS <skipDays>，不需要抓取的日期，time.Weekday
+}
+
+// IsEmpty 没有任何抓取提示
+func (h FeedHints) IsEmpty() bool {
+	return h.TTL == 0 && h.UpdatePeriod == 0 && len(h.SkipHours) == 0 && len(h.SkipDays) == 0
+}
+
+// Skipped t 所在的小时或日期是否被声明为不需要抓取
+func (h FeedHints) Skipped(t time.Time) bool {
+	t = t.UTC()
+	for _, hour := range h.SkipHours {
+		if t.Hour() == hour {
+			return true
+		}
+	}
+	for _, day := range h.SkipDays {
+		if int(t.Weekday()) == day {
+			return true
+		}
+	}
+	return false
+}
+
+// NextAllowed 不早于 t 且不在跳过的小时和日期中的时间
+func (h FeedHints) NextAllowed(t time.Time) time.Time {
+	for i := 0; i < 7*24 && h.Skipped(t); i++ {
+		t = t.Truncate(time.Hour).Add(time.Hour)
+	}
+	return t
+}
+
+// Value implements driver.Valuer
+func (h FeedHints) Value() (driver.Value, error) {
+	if h.IsEmpty() {
+		return nil, nil
+	}
+	data, err := json.Marshal(h)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (h *FeedHints) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported feed hints value %T", value)
+	}
+	*h = FeedHints{}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, h)
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFeedHints(t *testing.T) {
+	hints := FeedHints{TTL: 60, SkipHours: []int{23, 0}, SkipDays: []int{int(time.Monday)}}
+
+	// 2024-01-07 是星期日
+	sunday := time.Date(2024, 1, 7, 22, 15, 0, 0, time.UTC)
+	if hints.Skipped(sunday) {
+		t.Errorf("%v should not be skipped", sunday)
+	}
+	if !hints.Skipped(sunday.Add(time.Hour)) {
+		t.Errorf("%v should be skipped", sunday.Add(time.Hour))
+	}
+	if !hints.Skipped(sunday.In(time.FixedZone("UTC+8", 8*3600)).Add(time.Hour)) {
+		t.Errorf("skip hours should be compared in UTC")
+	}
+	if got := hints.NextAllowed(sunday); !got.Equal(sunday) {
+		t.Errorf("NextAllowed(%v) = %v", sunday, got)
+	}
+	want := time.Date(2024, 1, 9, 1, 0, 0, 0, time.UTC)
+	if got := hints.NextAllowed(sunday.Add(time.Hour)); !got.Equal(want) {
+		t.Errorf("NextAllowed(%v) = %v, want %v", sunday.Add(time.Hour), got, want)
+	}
+
+	value, err := hints.Value()
+	if err != nil {
+		t.Fatalf("value: %v", err)
+	}
+	var scanned FeedHints
+	if err := scanned.Scan(value); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if scanned.TTL != 60 || len(scanned.SkipHours) != 2 || len(scanned.SkipDays) != 1 {
+		t.Errorf("round trip lost data: %#v", scanned)
+	}
+
+	if value, _ := (FeedHints{}).Value(); value != nil {
+		t.Errorf("empty hints should be stored as NULL, got %v", value)
+	}
+	if err := scanned.Scan(nil); err != nil || !scanned.IsEmpty() {
+		t.Errorf("scan NULL: %#v, %v", scanned, err)
+	}
+}
@@ -0,0 +1,75 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// MaxPublishHistory 每个订阅源最多保留的发布记录数
+const MaxPublishHistory = 32
+
+// PublishHistory 订阅源最近出现新文章的时间，按时间升序，以 unix 秒的 JSON 数组保存，为空时保存为 NULL
+type PublishHistory []time.Time
+
+// Add 加入新的发布时间，精确到分钟并去重，只保留最近的 MaxPublishHistory 条
+func (h PublishHistory) Add(times ...time.Time) PublishHistory {
+	seen := make(map[int64]bool, len(h)+len(times))
+	merged := make(PublishHistory, 0, len(h)+len(times))
+	for _, t := range append(append([]time.Time{}, h...), times...) {
+		t = t.Truncate(time.Minute).UTC()
+		if !seen[t.Unix()] {
+			seen[t.Unix()] = true
+			merged = append(merged, t)
+		}
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Before(merged[j]) })
+	if len(merged) > MaxPublishHistory {
+		merged = merged[len(merged)-MaxPublishHistory:]
+	}
+	return merged
+}
+
+// Value implements driver.Valuer
+func (h PublishHistory) Value() (driver.Value, error) {
+	if len(h) == 0 {
+		return nil, nil
+	}
+	seconds := make([]int64, len(h))
+	for i, t := range h {
+		seconds[i] = t.Unix()
+	}
+	data, err := json.Marshal(seconds)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (h *PublishHistory) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported publish history value %T", value)
+	}
+	*h = nil
+	if len(data) == 0 {
+		return nil
+	}
+	var seconds []int64
+	if err := json.Unmarshal(data, &seconds); err != nil {
+		return err
+	}
+	for _, s := range seconds {
+		*h = append(*h, time.Unix(s, 0).UTC())
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPublishHistory(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var history PublishHistory
+	for i := 0; i < MaxPublishHistory; i++ {
+		history = history.Add(base.Add(time.Duration(i) * time.Hour))
+	}
+	// 同一分钟内的发布只记一次，较早的记录被挤出
+	history = history.Add(base.Add(-time.Hour), base.Add(40*time.Hour), base.Add(40*time.Hour+30*time.Second))
+	if len(history) != MaxPublishHistory {
+		t.Fatalf("expected %d entries, got %d", MaxPublishHistory, len(history))
+	}
+	if !history[0].Equal(base.Add(time.Hour)) || !history[len(history)-1].Equal(base.Add(40*time.Hour)) {
+		t.Errorf("unexpected range %v - %v", history[0], history[len(history)-1])
+	}
+
+	value, err := history.Value()
+	if err != nil {
+		t.Fatalf("value: %v", err)
+	}
+	var scanned PublishHistory
+	if err := scanned.Scan(value); err != nil {
+		t.Fatalf("scan: %v", err)
+	}
+	if len(scanned) != len(history) || !scanned[0].Equal(history[0]) {
+		t.Errorf("round trip lost data: %v", scanned)
+	}
+
+	if value, _ := PublishHistory(nil).Value(); value != nil {
+		t.Errorf("empty history should be stored as NULL, got %v", value)
+	}
+}
@@ -0,0 +1,117 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maskedValue 日志和消息中代替凭据展示的文本
+const maskedValue = "******"
+
+// sensitiveHeaders 值需要打码的请求头，小写
+var sensitiveHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"x-api-key":           true,
+	"x-auth-token":        true,
+}
+
+// RequestSettings 订阅源的自定义请求设置，以 JSON 保存，为空时保存为 NULL
+type RequestSettings struct {
+	Headers           map[string]string `json:"headers,omitempty"`
+	BasicAuthUser     string            `json:"basic_auth_user,omitempty"`
+	BasicAuthPassword string            `json:"basic_auth_password,omitempty"`
+	UserAgent         string            `json:"user_agent,omitempty"`
+}
+
+// IsEmpty 没有任何自定义设置
+func (r RequestSettings) IsEmpty() bool {
+	return len(r.Headers) == 0 && r.BasicAuthUser == "" && r.BasicAuthPassword == "" && r.UserAgent == ""
+}
+
+// Masked 返回凭据打码后的副本，用于展示
+func (r RequestSettings) Masked() RequestSettings {
+	masked := RequestSettings{UserAgent: r.UserAgent, BasicAuthUser: r.BasicAuthUser}
+	if r.BasicAuthPassword != "" {
+		masked.BasicAuthPassword = maskedValue
+	}
+	if len(r.Headers) > 0 {
+		masked.Headers = make(map[string]string, len(r.Headers))
+		for name, value := range r.Headers {
+			if sensitiveHeaders[strings.ToLower(name)] {
+				value = maskedValue
+			}
+			masked.Headers[name] = value
+		}
+	}
+	return masked
+}
+
+// HeaderNames 按字母顺序返回请求头名称
+func (r RequestSettings) HeaderNames() []string {
+	names := make([]string, 0, len(r.Headers))
+	for name := range r.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// String 实现 fmt.Stringer，避免凭据出现在日志中
+func (r RequestSettings) String() string {
+	if r.IsEmpty() {
+		return "{}"
+	}
+	m := r.Masked()
+	var parts []string
+	for _, name := range m.HeaderNames() {
+		parts = append(parts, name+": "+m.Headers[name])
+	}
+	if m.BasicAuthUser != "" || m.BasicAuthPassword != "" {
+		parts = append(parts, "basic auth "+m.BasicAuthUser+":"+m.BasicAuthPassword)
+	}
+	if m.UserAgent != "" {
+		parts = append(parts, "user agent "+m.UserAgent)
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+// GoString 实现 fmt.GoStringer，%#v 打印订阅源时同样打码
+func (r RequestSettings) GoString() string {
+	return "model.RequestSettings" + r.String()
+}
+
+// Value implements driver.Valuer
+func (r RequestSettings) Value() (driver.Value, error) {
+	if r.IsEmpty() {
+		return nil, nil
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// Scan implements sql.Scanner
+func (r *RequestSettings) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported request settings value %T", value)
+	}
+	*r = RequestSettings{}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, r)
+}
@@ -1,14 +1,72 @@
 package model
 
-import "time"
+import (
+	"net/url"
+	"strconv"
+	"time"
+)
 
 type Source struct {
 	ID              uint `gorm:"primary_key;AUTO_INCREMENT"`
 	Link            string
+	URLKey          string `gorm:"index"` // Canonical form of Link, variants of the same URL share it
+	OriginLink      string // Link the source was created with, kept for content hash ids once Link followed a redirect
+	RedirectURL     string // Permanent redirect target seen on the latest fetches
+	RedirectCount   int    // Consecutive fetches permanently redirected to RedirectURL
 	Title           string
-	ErrorCount      uint
+	ErrorCount      uint       // Consecutive failed fetches, reset on success
+	Paused          bool       // Updates turned off by the user, failing sources are retried with backoff instead
+	LastError       string     // Error of the last failed fetch, kept after recovery for reference
+	LastErrorAt     *time.Time // When the last failed fetch happened
 	LastPublishedAt *time.Time
 	LastContentAt   *time.Time // When we last received content locally (our timestamp, not from feed)
+	LastFetchedAt   *time.Time // When the last successful fetch happened
+	NextFetchAt     *time.Time // When the scheduler should fetch this source next, nil means as soon as possible
+	ETag            string     // ETag header of the last full response, sent back as If-None-Match
+	LastModified    string     // Last-Modified header of the last full response, sent back as If-Modified-Since
+	HubURL          string     // WebSub hub advertised by the feed, empty when it has none
+	HubTopic        string     // Topic URL subscribed at the hub
+	HubSecret       string     // Secret the hub signs pushed content with
+	HubLeaseUntil   *time.Time // When the hub subscription expires, nil until the hub verified it
+	// Polling hints declared by the feed, ttl and update period are lower bounds of the fetch interval
+	FeedHints FeedHints `gorm:"type:text"`
+	// When new items appeared recently, used to adapt the fetch interval to how often the feed publishes
+	PublishHistory PublishHistory `gorm:"type:text"`
+	// Custom headers, credentials and user agent sent with every request, a source with settings
+	// belongs to the subscriber who set them and is never shared with other subscribers of the URL
+	RequestSettings RequestSettings `gorm:"type:text"`
 	Content         []Content
 	EditTime
 }
+
+// DisplayLink 链接中的密码打码后的文本，用于展示、导出和日志
+func (s *Source) DisplayLink() string {
+	return RedactLink(s.Link)
+}
+
+// RedactLink 将链接中的密码打码，用于展示和记录还未保存为订阅源的链接
+func RedactLink(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.User == nil {
+		return link
+	}
+	return u.Redacted()
+}
+
+// HubActive 订阅源的 WebSub 订阅是否在有效期内，有效期内 hub 会推送更新
+func (s *Source) HubActive(now time.Time) bool {
+	return s.HubURL != "" && s.HubLeaseUntil != nil && s.HubLeaseUntil.After(now)
+}
+
+// ContentKey 生成文章 hash id 时代表订阅源的字符串。链接因重定向更新后仍使用原链接，避免文章被重复推送；
+// 有自定义请求设置的订阅源与相同链接的其他订阅源的文章不能混在一起，需要加上订阅源 ID
+func (s *Source) ContentKey() string {
+	link := s.Link
+	if s.OriginLink != "" {
+		link = s.OriginLink
+	}
+	if s.RequestSettings.IsEmpty() {
+		return link
+	}
+	return link + "#" + strconv.FormatUint(uint64(s.ID), 10)
+}
@@ -1,13 +1,21 @@
 package model
 
+import "time"
+
 type Subscribe struct {
 	ID                 uint `gorm:"primary_key;AUTO_INCREMENT"`
 	UserID             int64
 	SourceID           uint
 	EnableNotification int
 	EnableTelegraph    int
+	EnableMedia        int // 1 sends enclosures as native telegram photo, audio or video
 	Tag                string
 	Interval           int
+	IntervalSet        bool // Interval was set by the subscriber, adaptive polling never fetches less often
 	WaitTime           int
+	LastDeliveredAt    *time.Time // When new contents were last pushed to this subscriber
+	IncludeKeywords    string     // Newline separated rules, when set only items matching one of them are pushed
+	ExcludeKeywords    string     // Newline separated rules, items matching any of them are not pushed
+	Digest             string     // Digest schedule such as "daily 09:00", empty means one message per item
 	EditTime
 }
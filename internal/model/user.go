@@ -2,7 +2,12 @@ package model
 
 // User subscriber
 type User struct {
-	ID           int64 `gorm:"primary_key"`
+	ID           int64  `gorm:"primary_key"`
 	LanguageCode string `gorm:"size:10;default:'en'"` // Added field
+	Timezone     string `gorm:"size:64"`              // IANA 时区，空表示使用服务器时区
+	QuietStart   string `gorm:"size:5"`               // 免打扰开始时间 HH:MM，空表示未开启
+	QuietEnd     string `gorm:"size:5"`               // 免打扰结束时间 HH:MM
+	QuietMode    string `gorm:"size:10"`              // 免打扰期间的处理方式：hold 或 silent
+	Digest       string `gorm:"size:32"`              // 用 /digest all 设置的摘要计划，新订阅同样使用，空表示不使用摘要
 	EditTime
 }
@@ -119,7 +119,7 @@ func ToOPML(sources []*model.Source) (string, error) {
 		outline := Outline{}
 		outline.Text = s.Title
 		outline.Type = "rss"
-		outline.XMLURL = s.Link
+		outline.XMLURL = s.DisplayLink()
 		O.Body.Outlines = append(O.Body.Outlines, outline)
 	}
 	return O.XML()
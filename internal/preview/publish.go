@@ -11,6 +11,7 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/metrics"
 )
 
 const (
@@ -54,6 +55,7 @@ func PublishHtml(sourceTitle string, title string, rawLink string, htmlContent s
 		)
 		if err == nil {
 			zap.S().Infof("Created telegraph page url: %s", page.URL)
+			metrics.TelegraphPublishes.Inc("ok")
 			return page.URL, nil
 		}
 
@@ -62,6 +64,7 @@ func PublishHtml(sourceTitle string, title string, rawLink string, htmlContent s
 		// Handle CONTENT_TOO_BIG - skip without retry
 		if isContentTooBig(err) {
 			log.Warnf("Create telegraph page failed: content too big, skipping (title: %s)", title)
+			metrics.TelegraphPublishes.Inc("too_big")
 			return "", nil
 		}
 
@@ -83,5 +86,6 @@ func PublishHtml(sourceTitle string, title string, rawLink string, htmlContent s
 	}
 
 	log.Warnf("Create telegraph page failed after %d attempts, last error: %s", maxRetries, lastErr)
+	metrics.TelegraphPublishes.Inc("error")
 	return "", nil
 }
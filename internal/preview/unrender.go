@@ -2,6 +2,7 @@ package tgraph
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,15 +12,22 @@ import (
 
 	"github.com/yuin/goldmark"
 	"github.com/zintus/flowerss-bot/internal/config"
+	"github.com/zintus/flowerss-bot/pkg/client"
 )
 
 // FetchHTML fetches content from the unrender service and returns it as HTML
 // suitable for Telegraph's CreatePageWithHTML. The unrender service returns
-// markdown, which is converted to HTML via goldmark.
-func FetchHTML(rawLink string) (string, error) {
+// markdown, which is converted to HTML via goldmark. The link comes from a feed
+// and is fetched by the unrender service, so it is checked with guard first.
+func FetchHTML(guard *client.AddressGuard, rawLink string) (string, error) {
 	if config.UnrenderURL == "" || config.UnrenderToken == "" {
 		return "", fmt.Errorf("unrender not configured")
 	}
+	if guard != nil {
+		if err := guard.CheckURL(context.Background(), rawLink); err != nil {
+			return "", fmt.Errorf("unrender link rejected: %w", err)
+		}
+	}
 
 	requestURL := strings.TrimRight(config.UnrenderURL, "/") + "/" + url.PathEscape(rawLink)
 
@@ -0,0 +1,46 @@
+package tgraph
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/config"
+	"github.com/zintus/flowerss-bot/pkg/client"
+)
+
+func TestFetchHTML_AddressGuard(t *testing.T) {
+	var requested []string
+	unrender := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.URL.EscapedPath())
+		_, _ = w.Write([]byte("# Title"))
+	}))
+	defer unrender.Close()
+
+	unrenderURL, unrenderToken := config.UnrenderURL, config.UnrenderToken
+	defer func() { config.UnrenderURL, config.UnrenderToken = unrenderURL, unrenderToken }()
+	config.UnrenderURL, config.UnrenderToken = unrender.URL, "token"
+
+	guard, err := client.NewAddressGuard(nil)
+	assert.Nil(t, err)
+
+	for _, link := range []string{
+		"http://127.0.0.1/admin",
+		"http://localhost:8080/",
+		"http://10.0.0.1/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://[::1]/",
+		"file:///etc/passwd",
+	} {
+		_, err := FetchHTML(guard, link)
+		assert.ErrorIs(t, err, client.ErrBlockedAddress, link)
+	}
+	assert.Empty(t, requested, "blocked links must not reach the unrender service")
+
+	html, err := FetchHTML(guard, "http://93.184.216.34/post")
+	assert.Nil(t, err)
+	assert.Contains(t, html, "<h1>Title</h1>")
+	assert.Len(t, requested, 1)
+}
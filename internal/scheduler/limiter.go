@@ -0,0 +1,89 @@
+package scheduler
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// hostLimiter 限制对同一主机的并发抓取数
+type hostLimiter struct {
+	mu    sync.Mutex
+	limit int
+	slots map[string]chan struct{}
+}
+
+// newHostLimiter limit 小于等于 0 时不限制
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{
+		limit: limit,
+		slots: map[string]chan struct{}{},
+	}
+}
+
+// acquire 占用主机的一个抓取名额，返回释放函数
+func (l *hostLimiter) acquire(host string) func() {
+	if l.limit <= 0 {
+		return func() {}
+	}
+
+	l.mu.Lock()
+	slot, ok := l.slots[host]
+	if !ok {
+		slot = make(chan struct{}, l.limit)
+		l.slots[host] = slot
+	}
+	l.mu.Unlock()
+
+	slot <- struct{}{}
+	return func() {
+		<-slot
+	}
+}
+
+// sourceLocker 让同一订阅源的抓取和 hub 推送依次保存和推送内容
+type sourceLocker struct {
+	mu    sync.Mutex
+	locks map[uint]*sourceLock
+}
+
+type sourceLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newSourceLocker() *sourceLocker {
+	return &sourceLocker{locks: map[uint]*sourceLock{}}
+}
+
+// lock 锁定订阅源，返回解锁函数
+func (l *sourceLocker) lock(sourceID uint) func() {
+	l.mu.Lock()
+	lock, ok := l.locks[sourceID]
+	if !ok {
+		lock = &sourceLock{}
+		l.locks[sourceID] = lock
+	}
+	lock.refs++
+	l.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+		l.mu.Lock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(l.locks, sourceID)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// linkHost 订阅源链接的主机名，解析失败时返回原链接
+func linkHost(link string) string {
+	u, err := url.Parse(link)
+	if err != nil || u.Host == "" {
+		return link
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,98 @@
+package scheduler
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostLimiter(t *testing.T) {
+	t.Run(
+		"caps concurrency per host", func(t *testing.T) {
+			l := newHostLimiter(2)
+			var running, maxRunning atomic.Int32
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					release := l.acquire("example.com")
+					defer release()
+					n := running.Add(1)
+					for {
+						old := maxRunning.Load()
+						if n <= old || maxRunning.CompareAndSwap(old, n) {
+							break
+						}
+					}
+					time.Sleep(5 * time.Millisecond)
+					running.Add(-1)
+				}()
+			}
+			wg.Wait()
+			assert.Equal(t, int32(2), maxRunning.Load())
+		},
+	)
+
+	t.Run(
+		"hosts are independent", func(t *testing.T) {
+			l := newHostLimiter(1)
+			release := l.acquire("a.example.com")
+			defer release()
+
+			done := make(chan struct{})
+			go func() {
+				l.acquire("b.example.com")()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("acquire on another host blocked")
+			}
+		},
+	)
+
+	t.Run(
+		"no limit", func(t *testing.T) {
+			l := newHostLimiter(0)
+			for i := 0; i < 10; i++ {
+				l.acquire("example.com")
+			}
+		},
+	)
+}
+
+func TestSourceLocker(t *testing.T) {
+	l := newSourceLocker()
+	unlock := l.lock(1)
+
+	locked := make(chan struct{})
+	go func() {
+		l.lock(1)()
+		close(locked)
+	}()
+	// 其他订阅源不受影响
+	l.lock(2)()
+	select {
+	case <-locked:
+		t.Fatal("lock on the same source did not block")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-locked:
+	case <-time.After(time.Second):
+		t.Fatal("lock not released")
+	}
+	assert.Empty(t, l.locks)
+}
+
+func TestLinkHost(t *testing.T) {
+	assert.Equal(t, "example.com", linkHost("https://Example.com:8443/feed"))
+	assert.Equal(t, "not a url", linkHost("not a url"))
+}
@@ -2,24 +2,37 @@ package scheduler
 
 import (
 	"context"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mmcdole/gofeed"
-	"go.uber.org/atomic"
 
 	"github.com/zintus/flowerss-bot/internal/config"
 	"github.com/zintus/flowerss-bot/internal/core"
 	"github.com/zintus/flowerss-bot/internal/feed"
 	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/metrics"
 	"github.com/zintus/flowerss-bot/internal/model"
 	"github.com/zintus/flowerss-bot/pkg/client"
 )
 
+const (
+	// scheduleTick 调度器检查到期订阅源的周期
+	scheduleTick = time.Minute
+	// contentPurgeInterval 清理过期文章正文的周期
+	contentPurgeInterval = time.Hour
+)
+
 // RssUpdateObserver Rss Update observer
 type RssUpdateObserver interface {
-	SourceUpdate(*model.Source, []*model.Content, []*model.Subscribe)
-	SourceUpdateError(*model.Source)
+	SourceUpdate(context.Context, *model.Source, []*model.Content, []*model.Subscribe)
+	SourceUpdateError(context.Context, *model.Source)
+	// SourceMoved 订阅源因永久重定向更新了链接
+	SourceMoved(context.Context, *core.SourceMove)
 }
 
 // NewRssTask new RssUpdateTask
@@ -29,16 +42,25 @@ func NewRssTask(appCore *core.Core) *RssUpdateTask {
 		core:         appCore,
 		feedParser:   appCore.FeedParser(),
 		httpClient:   appCore.HttpClient(),
+		hostLimiter:  newHostLimiter(config.FetchHostConcurrency),
+		sourceLocks:  newSourceLocker(),
 	}
 }
 
 // RssUpdateTask rss更新任务
 type RssUpdateTask struct {
 	observerList []RssUpdateObserver
-	isStop       atomic.Bool
+	done         chan struct{}
+	lastCycleAt  atomic.Int64 // 上一轮成功完成的时间，unix 纳秒
 	core         *core.Core
 	feedParser   *feed.FeedParser
 	httpClient   *client.HttpClient
+	hostLimiter  *hostLimiter
+	// sourceLocks 同一订阅源的抓取和 hub 推送从保存内容到更新推送时间都不交错，
+	// 否则一方更新的推送时间可能晚于另一方还未入库的内容的入库时间，这些内容不会再推送
+	sourceLocks *sourceLocker
+	// deliverMu 抓取结果和 hub 推送依次写入 outbox
+	deliverMu sync.Mutex
 }
 
 // Register 注册rss更新订阅者
@@ -46,98 +68,451 @@ func (t *RssUpdateTask) Register(observer RssUpdateObserver) {
 	t.observerList = append(t.observerList, observer)
 }
 
-// Stop scheduler
-func (t *RssUpdateTask) Stop() {
-	t.isStop.Store(true)
-}
-
-// Start run scheduler
-func (t *RssUpdateTask) Start() {
+// Start run scheduler，ctx 结束后不再开始新的抓取，已开始的抓取保存完后退出
+func (t *RssUpdateTask) Start(ctx context.Context) {
+	t.done = make(chan struct{})
 	if config.RunMode == config.TestMode {
+		close(t.done)
 		return
 	}
 
-	t.isStop.Store(false)
 	go func() {
+		defer close(t.done)
+		var lastPurge time.Time
 		for {
-			if t.isStop.Load() {
+			start := time.Now()
+			if t.updateDueSources(ctx, start) {
+				t.lastCycleAt.Store(time.Now().UnixNano())
+				metrics.SchedulerLastSuccess.Set(float64(time.Now().Unix()))
+			}
+			metrics.SchedulerCycleDuration.Observe(time.Since(start).Seconds())
+			if ctx.Err() == nil && time.Since(lastPurge) >= contentPurgeInterval {
+				lastPurge = time.Now()
+				t.purgeContents(ctx, lastPurge)
+				t.core.RenewHubSubscriptions(ctx, lastPurge)
+			}
+
+			select {
+			case <-ctx.Done():
 				log.Info("RssUpdateTask stopped")
 				return
+			case <-t.core.FetchRequests():
+			case <-time.After(scheduleTick):
 			}
+		}
+	}()
+}
 
-			sources, err := t.core.GetSources(context.Background())
-			if err != nil {
-				log.Errorf("get sources failed, %v", err)
-				time.Sleep(time.Duration(config.UpdateInterval) * time.Minute)
-				continue
-			}
-			for _, source := range sources {
-				if source.ErrorCount >= config.ErrorThreshold {
-					continue
-				}
+// LastCycleAt 调度器上一轮成功完成的时间，还未完成过时返回零值
+func (t *RssUpdateTask) LastCycleAt() time.Time {
+	n := t.lastCycleAt.Load()
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
 
-				newContents, err := t.getSourceNewContents(source)
-				if err != nil {
-					if source.ErrorCount >= config.ErrorThreshold {
-						t.notifyAllObserverErrorUpdate(source)
-					}
-					continue
-				}
+// Wait 等待调度器退出，ctx 结束时不再等待并返回 ctx 的错误
+func (t *RssUpdateTask) Wait(ctx context.Context) error {
+	if t.done == nil {
+		return nil
+	}
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// purgeContents 清空超过保留天数的文章正文
+func (t *RssUpdateTask) purgeContents(ctx context.Context, now time.Time) {
+	if config.ContentRetentionDays <= 0 {
+		return
+	}
+	before := now.AddDate(0, 0, -config.ContentRetentionDays)
+	n, err := t.core.PurgeContents(ctx, before)
+	if err != nil {
+		log.Errorf("purge contents failed, %v", err)
+		return
+	}
+	if n > 0 {
+		log.Infof("stripped %d contents older than %d days", n, config.ContentRetentionDays)
+	}
+}
+
+// fetchResult 单个订阅源的抓取结果
+type fetchResult struct {
+	source      *model.Source
+	subs        []*model.Subscribe
+	newContents []*model.Content
+	// redirect 本次抓取观察到的永久重定向地址
+	redirect string
+	err      error
+	// unlock 释放订阅源的锁，通知订阅者后调用
+	unlock func()
+}
 
-				if len(newContents) > 0 {
-					subs, err := t.core.GetSourceAllSubscriptions(
-						context.Background(), source.ID,
-					)
-					if err != nil {
-						log.Errorf("get subscriptions failed, %v", err)
-						continue
-					}
-					t.notifyAllObserverUpdate(source, newContents, subs)
+// updateDueSources 并发抓取所有已到抓取时间的订阅源，并按订阅源 ID 顺序依次通知订阅者，
+// ctx 结束后不再开始新的抓取，已完成抓取的结果仍会写入 outbox，获取订阅源失败时返回 false
+func (t *RssUpdateTask) updateDueSources(ctx context.Context, now time.Time) bool {
+	sources, err := t.core.GetSources(ctx)
+	if err != nil {
+		log.Errorf("get sources failed, %v", err)
+		return false
+	}
+
+	var dueSources []*model.Source
+	for _, source := range sources {
+		if source.Paused {
+			continue
+		}
+		if source.NextFetchAt != nil && source.NextFetchAt.After(now) {
+			continue
+		}
+		dueSources = append(dueSources, source)
+	}
+	sort.Slice(dueSources, func(i, j int) bool { return dueSources[i].ID < dueSources[j].ID })
+
+	results := make([]chan *fetchResult, len(dueSources))
+	for i := range results {
+		results[i] = make(chan *fetchResult, 1)
+	}
+
+	jobs := make(chan int)
+	go func() {
+		defer close(jobs)
+		for i := range dueSources {
+			jobs <- i
+		}
+	}()
+
+	workers := config.FetchWorkers
+	if workers <= 0 || workers > len(dueSources) {
+		workers = len(dueSources)
+	}
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				if ctx.Err() != nil {
+					results[i] <- nil
+					continue
 				}
+				source := dueSources[i]
+				release := t.hostLimiter.acquire(linkHost(source.Link))
+				result := t.fetchSource(ctx, source, now)
+				release()
+				results[i] <- result
 			}
+		}()
+	}
 
-			time.Sleep(time.Duration(config.UpdateInterval) * time.Minute)
+	storeCtx := context.WithoutCancel(ctx)
+	for i := range results {
+		t.handleFetchResult(storeCtx, <-results[i], now)
+	}
+	return true
+}
+
+// fetchSource 抓取订阅源并保存新内容，按订阅者中最小的更新间隔安排下次抓取，
+// 抓取失败时按连续失败次数退避，获取订阅者失败或抓取因 ctx 结束中断时返回 nil。
+// 返回的结果持有订阅源的锁，由 handleFetchResult 释放
+func (t *RssUpdateTask) fetchSource(ctx context.Context, source *model.Source, now time.Time) *fetchResult {
+	unlock := t.sourceLocks.lock(source.ID)
+	subs, err := t.core.GetSourceAllSubscriptions(ctx, source.ID)
+	if err != nil {
+		unlock()
+		log.Errorf("get subscriptions failed, %v", err)
+		return nil
+	}
+	result := &fetchResult{source: source, subs: subs, unlock: unlock}
+	result.newContents, result.redirect, result.err = t.getSourceNewContents(ctx, source)
+	if result.err != nil && ctx.Err() != nil {
+		// 退出时中断的抓取不计入失败，也不推迟下次抓取
+		unlock()
+		return nil
+	}
+	ctx = context.WithoutCancel(ctx)
+
+	delay, _ := core.SourceEffectiveInterval(source, subs, now)
+	if source.ErrorCount > 0 {
+		delay = core.SourceRetryDelay(delay, source.ErrorCount)
+	}
+	next := core.SourceNextFetchAt(source, now.Add(delay))
+	source.NextFetchAt = &next
+	if err := t.core.ScheduleSourceFetch(ctx, source.ID, next); err != nil {
+		log.Errorf("schedule source %d next fetch failed, %v", source.ID, err)
+	}
+	return result
+}
+
+// handleFetchResult 通知订阅者抓取结果，同一时间只在一个 goroutine 中调用
+func (t *RssUpdateTask) handleFetchResult(ctx context.Context, result *fetchResult, now time.Time) {
+	if result == nil {
+		return
+	}
+	defer result.unlock()
+	t.deliverMu.Lock()
+	defer t.deliverMu.Unlock()
+	if result.err != nil {
+		// 只在连续失败次数刚达到阈值时通知一次，之后继续退避重试
+		if result.source.ErrorCount == config.ErrorThreshold {
+			t.notifyAllObserverErrorUpdate(ctx, result.source)
 		}
-	}()
+		return
+	}
+	t.deliverContents(ctx, result.source, result.subs, result.newContents, now)
+	t.followRedirect(ctx, result.source, result.redirect)
 }
 
-// getSourceNewContents 获取rss新内容
-func (t *RssUpdateTask) getSourceNewContents(source *model.Source) ([]*model.Content, error) {
-	log.Debugf("fetch source [%d]%s update", source.ID, source.Link)
+// followRedirect 记录订阅源的永久重定向，链接更新后通知原订阅者。新内容推送之后再处理，
+// 订阅源被合并时本次的内容已写入原订阅者的 outbox
+func (t *RssUpdateTask) followRedirect(ctx context.Context, source *model.Source, redirect string) {
+	if redirect == "" && source.RedirectURL == "" {
+		return
+	}
+	move, err := t.core.RecordSourceRedirect(ctx, source.ID, redirect)
+	if err != nil {
+		log.Errorf("record source %d redirect failed, %v", source.ID, err)
+		return
+	}
+	if move == nil {
+		return
+	}
+	for _, observer := range t.observerList {
+		observer.SourceMoved(ctx, move)
+	}
+}
+
+// deliveryGroup 收到相同内容的一组订阅者
+type deliveryGroup struct {
+	contents []*model.Content
+	subs     []*model.Subscribe
+}
+
+// deliverContents 将上次推送后积攒的内容写入更新间隔已到的订阅者的 outbox
+func (t *RssUpdateTask) deliverContents(
+	ctx context.Context, source *model.Source, subs []*model.Subscribe, newContents []*model.Content, now time.Time,
+) {
+	groups := map[string]*deliveryGroup{}
+	var order []string
+	for _, sub := range subs {
+		if !core.SubscriptionDue(sub, now) {
+			continue
+		}
+
+		contents, err := t.pendingContents(ctx, source, sub, newContents)
+		if err != nil {
+			log.Errorf("get pending contents of source %d for user %d failed, %v", source.ID, sub.UserID, err)
+			continue
+		}
+		if len(contents) == 0 {
+			continue
+		}
+
+		key := contentsKey(contents)
+		group, ok := groups[key]
+		if !ok {
+			group = &deliveryGroup{contents: contents}
+			groups[key] = group
+			order = append(order, key)
+		}
+		group.subs = append(group.subs, sub)
+	}
 
-	rssFeed, err := t.feedParser.ParseFromURL(context.Background(), source.Link)
+	for _, key := range order {
+		group := groups[key]
+		// 先写入 outbox 再更新推送时间，写入失败时下次到期会重新入队
+		deliveredAt := time.Now()
+		if err := t.core.EnqueueDeliveries(ctx, group.contents, group.subs, deliveredAt); err != nil {
+			log.Errorf("enqueue deliveries of source %d failed, %v", source.ID, err)
+			continue
+		}
+		for _, sub := range group.subs {
+			if err := t.core.MarkSubscriptionDelivered(ctx, sub, deliveredAt); err != nil {
+				log.Errorf("mark user %d source %d delivered failed, %v", sub.UserID, sub.SourceID, err)
+			}
+		}
+		t.notifyAllObserverUpdate(ctx, source, group.contents, group.subs)
+	}
+}
+
+// pendingContents 订阅者上次推送之后入库的内容，从未推送过的订阅者只收到本次抓取的新内容
+func (t *RssUpdateTask) pendingContents(
+	ctx context.Context, source *model.Source, sub *model.Subscribe, newContents []*model.Content,
+) ([]*model.Content, error) {
+	if sub.LastDeliveredAt == nil {
+		return newContents, nil
+	}
+	return t.core.GetSourceContentsSince(ctx, source.ID, *sub.LastDeliveredAt)
+}
+
+// contentsKey 内容列表的标识，用于合并收到相同内容的订阅者
+func contentsKey(contents []*model.Content) string {
+	hashIDs := make([]string, 0, len(contents))
+	for _, content := range contents {
+		hashIDs = append(hashIDs, content.HashID)
+	}
+	return strings.Join(hashIDs, ",")
+}
+
+// getSourceNewContents 获取rss新内容及抓取时观察到的永久重定向地址，ctx 只用于抓取，抓取完成后的保存不会被 ctx 中断
+func (t *RssUpdateTask) getSourceNewContents(
+	ctx context.Context, source *model.Source,
+) ([]*model.Content, string, error) {
+	log.Debugf("fetch source [%d]%s update", source.ID, source.DisplayLink())
+
+	host := linkHost(source.Link)
+	fetchStart := time.Now()
+	result, err := t.feedParser.Fetch(ctx, source.Link, core.SourceFetchOptions(source))
+	outcome := "ok"
+	switch {
+	case err != nil:
+		outcome = "error"
+	case result.NotModified:
+		outcome = "not_modified"
+	}
+	metrics.FetchDuration.Observe(time.Since(fetchStart).Seconds(), host, outcome)
+	if err != nil && ctx.Err() != nil {
+		return nil, "", err
+	}
+	ctx = context.WithoutCancel(ctx)
 	if err != nil {
-		log.Errorf("unable to fetch feed, source %#v, err %v", source, err)
-		if incrErr := t.core.SourceErrorCountIncr(context.Background(), source.ID); incrErr != nil {
+		log.Errorf("unable to fetch feed, source [%d]%s, err %v", source.ID, source.DisplayLink(), err)
+		updated, incrErr := t.core.SourceErrorCountIncr(ctx, source.ID, err, time.Now())
+		if incrErr != nil {
 			log.Errorf("failed to increment source error count: %v", incrErr)
+			return nil, "", err
 		}
-		return nil, err
+		source.ErrorCount = updated.ErrorCount
+		source.LastError = updated.LastError
+		source.LastErrorAt = updated.LastErrorAt
+		return nil, "", err
 	}
-	if clearErr := t.core.ClearSourceErrorCount(context.Background(), source.ID); clearErr != nil {
-		log.Errorf("failed to clear source error count: %v", clearErr)
+	fetchedAt := time.Now()
+	if markErr := t.core.MarkSourceFetched(ctx, source.ID, fetchedAt); markErr != nil {
+		log.Errorf("failed to mark source fetched: %v", markErr)
+	}
+	source.ErrorCount = 0
+	source.LastFetchedAt = &fetchedAt
+
+	if result.NotModified {
+		log.Debugf("source [%d]%s not modified", source.ID, source.DisplayLink())
+		t.updateCacheValidators(ctx, source, result)
+		return nil, result.PermanentRedirect, nil
+	}
+	rssFeed := result.Feed
+	t.updateSourceHub(ctx, source, result)
+	if !reflect.DeepEqual(result.Hints, source.FeedHints) {
+		if err := t.core.UpdateSourceFeedHints(ctx, source.ID, result.Hints); err != nil {
+			log.Errorf("failed to update source feed hints: %v", err)
+		}
+		source.FeedHints = result.Hints
 	}
 
 	if rssFeed.UpdatedParsed != nil {
-		if err := t.core.UpdateSourceLastPublishedAt(context.Background(), source.ID, rssFeed.UpdatedParsed); err != nil {
+		if err := t.core.UpdateSourceLastPublishedAt(ctx, source.ID, rssFeed.UpdatedParsed); err != nil {
 			log.Errorf("failed to update source LastPublishedAt: %v", err)
 		}
 	}
 
-	newContents, err := t.saveNewContents(source, rssFeed.Items)
+	newContents, err := t.saveNewContents(ctx, source, rssFeed.Items)
 	if err != nil {
-		return nil, err
+		// 不保存新的缓存校验值，下次抓取重新获取完整内容，入库失败的文章不会因 304 丢失
+		return nil, "", err
+	}
+	t.updateCacheValidators(ctx, source, result)
+	if len(newContents) > 0 {
+		metrics.ItemsDiscovered.Add(float64(len(newContents)), host)
+	}
+	return newContents, result.PermanentRedirect, nil
+}
+
+// updateCacheValidators 保存条件请求使用的 ETag 和 Last-Modified，只在抓取的内容都已入库后调用
+func (t *RssUpdateTask) updateCacheValidators(ctx context.Context, source *model.Source, result *feed.FetchResult) {
+	if result.ETag == source.ETag && result.LastModified == source.LastModified {
+		return
+	}
+	if err := t.core.UpdateSourceCacheValidators(ctx, source.ID, result.ETag, result.LastModified); err != nil {
+		log.Errorf("failed to update source cache validators: %v", err)
+		return
+	}
+	source.ETag = result.ETag
+	source.LastModified = result.LastModified
+}
+
+// updateSourceHub 订阅源的 WebSub hub 有变化时保存并重新订阅
+func (t *RssUpdateTask) updateSourceHub(ctx context.Context, source *model.Source, result *feed.FetchResult) {
+	if !source.RequestSettings.IsEmpty() {
+		return
+	}
+	topic := ""
+	if result.Hub != "" {
+		topic = result.Self
+		if topic == "" {
+			topic = source.Link
+		}
+	}
+	if result.Hub == source.HubURL && topic == source.HubTopic {
+		return
+	}
+	log.Infof("source [%d]%s hub changed to %q", source.ID, source.DisplayLink(), result.Hub)
+	if err := t.core.UpdateSourceHub(ctx, source.ID, result.Hub, topic); err != nil {
+		log.Errorf("update source %d hub failed, %v", source.ID, err)
+	}
+}
+
+// HandleHubPush 保存 hub 推送的内容并通知订阅者，与轮询使用相同的保存和推送流程，
+// 同一订阅源的抓取进行中时等待抓取的内容推送完成
+func (t *RssUpdateTask) HandleHubPush(ctx context.Context, source *model.Source, body []byte, contentType string) error {
+	if source.Paused {
+		return nil
+	}
+	rssFeed, err := t.feedParser.Parse(body, contentType)
+	if err != nil {
+		return err
+	}
+	unlock := t.sourceLocks.lock(source.ID)
+	defer unlock()
+	subs, err := t.core.GetSourceAllSubscriptions(ctx, source.ID)
+	if err != nil {
+		return err
+	}
+	newContents, err := t.saveNewContents(ctx, source, rssFeed.Items)
+	if err != nil {
+		return err
+	}
+	log.Debugf("hub pushed %d new contents of source [%d]%s", len(newContents), source.ID, source.DisplayLink())
+	if len(newContents) > 0 {
+		metrics.ItemsDiscovered.Add(float64(len(newContents)), linkHost(source.Link))
+	}
+
+	now := time.Now()
+	t.deliverMu.Lock()
+	t.deliverContents(ctx, source, subs, newContents, now)
+	t.deliverMu.Unlock()
+
+	// 还未到更新间隔的订阅者在下次抓取时推送，提前下次抓取的时间
+	if len(newContents) == 0 {
+		return nil
+	}
+	next := core.NextSubscriptionDueAt(subs, now)
+	if next != nil && (source.NextFetchAt == nil || next.Before(*source.NextFetchAt)) {
+		if err := t.core.ScheduleSourceFetch(ctx, source.ID, *next); err != nil {
+			log.Errorf("schedule source %d next fetch failed, %v", source.ID, err)
+		}
 	}
-	return newContents, nil
+	return nil
 }
 
 // saveNewContents generate content by fetcher item
 func (t *RssUpdateTask) saveNewContents(
-	s *model.Source, items []*gofeed.Item,
+	ctx context.Context, s *model.Source, items []*gofeed.Item,
 ) ([]*model.Content, error) {
 	var newItems []*gofeed.Item
 	for _, item := range items {
-		hashID := model.GenHashID(s.Link, item.GUID, item.Link)
-		exist, err := t.core.ContentHashIDExist(context.Background(), hashID)
+		hashID := model.GenHashID(s.ContentKey(), item.GUID, item.Link)
+		exist, err := t.core.ContentHashIDExist(ctx, hashID)
 		if err != nil {
 			log.Errorf("check item hash id failed, %v", err)
 		}
@@ -148,32 +523,32 @@ func (t *RssUpdateTask) saveNewContents(
 		}
 		newItems = append(newItems, item)
 	}
-	return t.core.AddSourceContents(context.Background(), s, newItems)
+	return t.core.AddSourceContents(ctx, s, newItems)
 }
 
 // notifyAllObserverUpdate notify all rss SourceUpdate observer
 func (t *RssUpdateTask) notifyAllObserverUpdate(
-	source *model.Source, newContents []*model.Content, subscribes []*model.Subscribe,
+	ctx context.Context, source *model.Source, newContents []*model.Content, subscribes []*model.Subscribe,
 ) {
 	wg := sync.WaitGroup{}
 	for _, observer := range t.observerList {
 		wg.Add(1)
 		go func(o RssUpdateObserver) {
 			defer wg.Done()
-			o.SourceUpdate(source, newContents, subscribes)
+			o.SourceUpdate(ctx, source, newContents, subscribes)
 		}(observer)
 	}
 	wg.Wait()
 }
 
 // notifyAllObserverErrorUpdate notify all rss error SourceUpdate observer
-func (t *RssUpdateTask) notifyAllObserverErrorUpdate(source *model.Source) {
+func (t *RssUpdateTask) notifyAllObserverErrorUpdate(ctx context.Context, source *model.Source) {
 	wg := sync.WaitGroup{}
 	for _, observer := range t.observerList {
 		wg.Add(1)
 		go func(o RssUpdateObserver) {
 			defer wg.Done()
-			o.SourceUpdateError(source)
+			o.SourceUpdateError(ctx, source)
 		}(observer)
 	}
 	wg.Wait()
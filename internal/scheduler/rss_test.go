@@ -0,0 +1,243 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/feed"
+	"github.com/zintus/flowerss-bot/internal/model"
+	"github.com/zintus/flowerss-bot/internal/storage"
+	"github.com/zintus/flowerss-bot/internal/storage/mock"
+	"github.com/zintus/flowerss-bot/pkg/client"
+)
+
+func TestRssUpdateTaskWait(t *testing.T) {
+	t.Run("not started", func(t *testing.T) {
+		task := &RssUpdateTask{}
+		assert.NoError(t, task.Wait(context.Background()))
+	})
+
+	t.Run("still running", func(t *testing.T) {
+		task := &RssUpdateTask{done: make(chan struct{})}
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.ErrorIs(t, task.Wait(ctx), context.Canceled)
+	})
+
+	t.Run("stopped", func(t *testing.T) {
+		task := &RssUpdateTask{done: make(chan struct{})}
+		close(task.done)
+		assert.NoError(t, task.Wait(context.Background()))
+	})
+}
+
+func TestRssUpdateTask_CacheValidators(t *testing.T) {
+	const rss = `<?xml version="1.0"?><rss version="2.0"><channel><title>t</title>` +
+		`<item><title>a</title><guid>a</guid><link>https://example.com/a</link></item></channel></rss>`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v2"`)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(rss))
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	newTask := func(t *testing.T, addErr error) (*RssUpdateTask, *[]string) {
+		ctrl := gomock.NewController(t)
+		sourceStorage := mock.NewMockSource(ctrl)
+		contentStorage := mock.NewMockContent(ctrl)
+		stored := &model.Source{ID: 1, Link: server.URL, ETag: `"v1"`}
+		var etags []string
+		sourceStorage.EXPECT().GetSource(gomock.Any(), uint(1)).DoAndReturn(
+			func(ctx context.Context, id uint) (*model.Source, error) {
+				source := *stored
+				return &source, nil
+			},
+		).AnyTimes()
+		sourceStorage.EXPECT().UpsertSource(gomock.Any(), uint(1), gomock.Any()).DoAndReturn(
+			func(ctx context.Context, id uint, source *model.Source) error {
+				etags = append(etags, source.ETag)
+				*stored = *source
+				return nil
+			},
+		).AnyTimes()
+		contentStorage.EXPECT().HashIDExist(gomock.Any(), gomock.Any()).Return(false, nil)
+		contentStorage.EXPECT().AddContent(gomock.Any(), gomock.Any()).Return(addErr)
+		appCore := core.NewCore(
+			mock.NewMockUser(ctrl), contentStorage, sourceStorage, mock.NewMockSubscription(ctrl),
+			mock.NewMockDelivery(ctrl), mock.NewMockContentSearch(ctrl),
+			feed.NewFeedParser(client.NewHttpClient()), client.NewHttpClient(),
+		)
+		return NewRssTask(appCore), &etags
+	}
+
+	t.Run(
+		"save failed", func(t *testing.T) {
+			task, etags := newTask(t, errors.New("db error"))
+			source := &model.Source{ID: 1, Link: server.URL, ETag: `"v1"`}
+			_, _, err := task.getSourceNewContents(ctx, source)
+			assert.Error(t, err)
+			// 保留旧的 ETag，下次抓取不会因 304 漏掉入库失败的文章
+			assert.NotContains(t, *etags, `"v2"`)
+			assert.Equal(t, `"v1"`, source.ETag)
+		},
+	)
+
+	t.Run(
+		"saved", func(t *testing.T) {
+			task, etags := newTask(t, nil)
+			source := &model.Source{ID: 1, Link: server.URL, ETag: `"v1"`}
+			contents, _, err := task.getSourceNewContents(ctx, source)
+			assert.Nil(t, err)
+			assert.Len(t, contents, 1)
+			assert.Contains(t, *etags, `"v2"`)
+			assert.Equal(t, `"v2"`, source.ETag)
+		},
+	)
+}
+
+func TestRssUpdateTask_HubPushDuringPoll(t *testing.T) {
+	const pollRSS = `<?xml version="1.0"?><rss version="2.0"><channel><title>t</title>` +
+		`<item><title>a</title><guid>a</guid><link>https://example.com/a</link></item></channel></rss>`
+	const pushRSS = `<?xml version="1.0"?><rss version="2.0"><channel><title>t</title>` +
+		`<item><title>h</title><guid>h</guid><link>https://example.com/h</link></item></channel></rss>`
+	fetching := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(fetching)
+		<-release
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(pollRSS))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	ctrl := gomock.NewController(t)
+	sourceStorage := mock.NewMockSource(ctrl)
+	contentStorage := mock.NewMockContent(ctrl)
+	subscriptionStorage := mock.NewMockSubscription(ctrl)
+	deliveryStorage := mock.NewMockDelivery(ctrl)
+
+	var mu sync.Mutex
+	var events []string
+	var contents []*model.Content
+	delivered := map[string]bool{}
+	stored := &model.Source{ID: 1, Link: server.URL}
+	lastDeliveredAt := time.Now().Add(-20 * time.Minute)
+	sub := &model.Subscribe{UserID: 1, SourceID: 1, Interval: 10, LastDeliveredAt: &lastDeliveredAt}
+	record := func(event string) {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+	}
+
+	sourceStorage.EXPECT().GetSource(gomock.Any(), uint(1)).DoAndReturn(
+		func(ctx context.Context, id uint) (*model.Source, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			source := *stored
+			return &source, nil
+		},
+	).AnyTimes()
+	sourceStorage.EXPECT().UpsertSource(gomock.Any(), uint(1), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, id uint, source *model.Source) error {
+			mu.Lock()
+			defer mu.Unlock()
+			*stored = *source
+			return nil
+		},
+	).AnyTimes()
+	subscriptionStorage.EXPECT().GetSubscriptionsBySourceID(gomock.Any(), uint(1), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, sourceID uint, opts *storage.GetSubscriptionsOptions) (*storage.GetSubscriptionsResult, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			copied := *sub
+			return &storage.GetSubscriptionsResult{Subscriptions: []*model.Subscribe{&copied}}, nil
+		},
+	).AnyTimes()
+	subscriptionStorage.EXPECT().UpdateSubscription(gomock.Any(), int64(1), uint(1), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, userID int64, sourceID uint, newSubscription *model.Subscribe) error {
+			record("mark")
+			mu.Lock()
+			defer mu.Unlock()
+			*sub = *newSubscription
+			return nil
+		},
+	).AnyTimes()
+	contentStorage.EXPECT().HashIDExist(gomock.Any(), gomock.Any()).Return(false, nil).AnyTimes()
+	contentStorage.EXPECT().AddContent(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, content *model.Content) error {
+			record("save " + content.Title)
+			mu.Lock()
+			defer mu.Unlock()
+			contents = append(contents, content)
+			return nil
+		},
+	).AnyTimes()
+	contentStorage.EXPECT().GetSourceContentsSince(gomock.Any(), uint(1), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, sourceID uint, since time.Time) ([]*model.Content, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			var matched []*model.Content
+			for _, content := range contents {
+				if content.CreatedAt.After(since) {
+					matched = append(matched, content)
+				}
+			}
+			return matched, nil
+		},
+	).AnyTimes()
+	deliveryStorage.EXPECT().AddDeliveries(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, deliveries []*model.Delivery) error {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, delivery := range deliveries {
+				delivered[delivery.ContentHashID] = true
+			}
+			return nil
+		},
+	).AnyTimes()
+
+	appCore := core.NewCore(
+		mock.NewMockUser(ctrl), contentStorage, sourceStorage, subscriptionStorage,
+		deliveryStorage, mock.NewMockContentSearch(ctrl),
+		feed.NewFeedParser(client.NewHttpClient(client.WithTimeout(5*time.Second))), client.NewHttpClient(),
+	)
+	task := NewRssTask(appCore)
+
+	now := time.Now()
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		source := &model.Source{ID: 1, Link: server.URL}
+		task.handleFetchResult(ctx, task.fetchSource(ctx, source, now), now)
+	}()
+	<-fetching
+
+	// 抓取进行中收到 hub 推送，推送的内容要等抓取推送完成后才入库
+	pushed := make(chan error, 1)
+	go func() {
+		pushed <- task.HandleHubPush(ctx, &model.Source{ID: 1, Link: server.URL}, []byte(pushRSS), "application/rss+xml")
+	}()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	<-polled
+	assert.Nil(t, <-pushed)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"save a", "mark", "save h"}, events)
+	// hub 推送的内容在下次到期时推送给订阅者
+	for _, content := range contents {
+		assert.True(t, delivered[content.HashID] || content.CreatedAt.After(*sub.LastDeliveredAt), content.Title)
+	}
+}
@@ -0,0 +1,129 @@
+// Package server 提供指标、健康检查和 WebSub 回调的 http 服务
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/metrics"
+)
+
+const (
+	// pingTimeout 健康检查中数据库 ping 的超时时间
+	pingTimeout = 2 * time.Second
+	// schedulerStaleAfter 调度器超过该时间没有成功完成一轮时视为不健康
+	schedulerStaleAfter = 15 * time.Minute
+)
+
+// Pinger 检查数据库连接
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Scheduler 提供调度器上一轮成功完成的时间
+type Scheduler interface {
+	LastCycleAt() time.Time
+}
+
+// Server 提供 /metrics、/healthz 和 /readyz，或者 WebSub 回调
+type Server struct {
+	name      string
+	db        Pinger
+	scheduler Scheduler
+	startedAt time.Time
+	now       func() time.Time
+
+	httpServer *http.Server
+}
+
+// NewServer 创建监听 addr 的服务
+func NewServer(addr string, db Pinger, scheduler Scheduler) *Server {
+	s := &Server{name: "metrics", db: db, scheduler: scheduler, startedAt: time.Now(), now: time.Now}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/healthz", s.healthz)
+	mux.HandleFunc("/readyz", s.readyz)
+	s.httpServer = &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	return s
+}
+
+// Start 监听地址并在后台处理请求，监听失败时返回错误
+func (s *Server) Start() error {
+	listener, err := net.Listen("tcp", s.httpServer.Addr)
+	if err != nil {
+		return err
+	}
+	log.Infof("%s server listening on %s", s.name, s.httpServer.Addr)
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("%s server exited, %v", s.name, err)
+		}
+	}()
+	return nil
+}
+
+// Shutdown 停止服务
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.httpServer.Shutdown(ctx)
+}
+
+// healthz 数据库可用且调度器没有停滞时返回 200，调度器还未完成过一轮时从启动时间开始计算
+func (s *Server) healthz(w http.ResponseWriter, r *http.Request) {
+	if err := s.pingDB(r.Context()); err != nil {
+		writeStatus(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	last := s.scheduler.LastCycleAt()
+	if last.IsZero() {
+		last = s.startedAt
+	}
+	if s.now().Sub(last) > schedulerStaleAfter {
+		writeStatus(
+			w, http.StatusServiceUnavailable,
+			fmt.Sprintf("scheduler stale, last successful cycle at %s", last.Format(time.RFC3339)),
+		)
+		return
+	}
+	writeStatus(w, http.StatusOK, "ok")
+}
+
+// readyz 数据库可用且调度器最近成功完成过一轮时返回 200
+func (s *Server) readyz(w http.ResponseWriter, r *http.Request) {
+	if err := s.pingDB(r.Context()); err != nil {
+		writeStatus(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	last := s.scheduler.LastCycleAt()
+	if last.IsZero() {
+		writeStatus(w, http.StatusServiceUnavailable, "scheduler has not completed a cycle yet")
+		return
+	}
+	if s.now().Sub(last) > schedulerStaleAfter {
+		writeStatus(
+			w, http.StatusServiceUnavailable,
+			fmt.Sprintf("scheduler stale, last successful cycle at %s", last.Format(time.RFC3339)),
+		)
+		return
+	}
+	writeStatus(w, http.StatusOK, "ok")
+}
+
+func (s *Server) pingDB(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, pingTimeout)
+	defer cancel()
+	if err := s.db.Ping(ctx); err != nil {
+		return fmt.Errorf("db ping failed: %w", err)
+	}
+	return nil
+}
+
+func writeStatus(w http.ResponseWriter, code int, text string) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(code)
+	_, _ = fmt.Fprintln(w, text)
+}
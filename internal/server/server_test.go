@@ -0,0 +1,75 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakePinger struct {
+	err error
+}
+
+func (p *fakePinger) Ping(ctx context.Context) error {
+	return p.err
+}
+
+type fakeScheduler struct {
+	last time.Time
+}
+
+func (s *fakeScheduler) LastCycleAt() time.Time {
+	return s.last
+}
+
+func TestServerHealth(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		pingErr    error
+		last       time.Time
+		startedAt  time.Time
+		wantHealth int
+		wantReady  int
+	}{
+		{"healthy", nil, now.Add(-time.Minute), now.Add(-time.Hour), http.StatusOK, http.StatusOK},
+		{"db down", errors.New("closed"), now.Add(-time.Minute), now.Add(-time.Hour),
+			http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+		{"starting", nil, time.Time{}, now.Add(-time.Minute), http.StatusOK, http.StatusServiceUnavailable},
+		{"never ran", nil, time.Time{}, now.Add(-time.Hour),
+			http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+		{"stale", nil, now.Add(-time.Hour), now.Add(-2 * time.Hour),
+			http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				s := NewServer(":0", &fakePinger{err: tt.pingErr}, &fakeScheduler{last: tt.last})
+				s.startedAt = tt.startedAt
+				s.now = func() time.Time { return now }
+
+				rec := httptest.NewRecorder()
+				s.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+				assert.Equal(t, tt.wantHealth, rec.Code, rec.Body.String())
+
+				rec = httptest.NewRecorder()
+				s.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+				assert.Equal(t, tt.wantReady, rec.Code, rec.Body.String())
+			},
+		)
+	}
+}
+
+func TestServerMetrics(t *testing.T) {
+	s := NewServer(":0", &fakePinger{}, &fakeScheduler{})
+	rec := httptest.NewRecorder()
+	s.httpServer.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "# TYPE flowerss_fetch_duration_seconds histogram")
+	assert.Contains(t, rec.Body.String(), "flowerss_scheduler_last_success_timestamp_seconds")
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+// HubStore 提供订阅源并处理 hub 对订阅请求的确认
+type HubStore interface {
+	GetSource(ctx context.Context, id uint) (*model.Source, error)
+	VerifyHubIntent(ctx context.Context, sourceID uint, mode, topic string, leaseSeconds int) error
+}
+
+// PushReceiver 处理 hub 推送的内容
+type PushReceiver interface {
+	HandleHubPush(ctx context.Context, source *model.Source, body []byte, contentType string) error
+}
+
+type webSubHandler struct {
+	store       HubStore
+	receiver    PushReceiver
+	maxBodySize int64
+}
+
+// NewWebSubServer 创建监听 addr 的 WebSub 回调服务，回调地址的最后一段为订阅源 ID，
+// maxBodySize 为推送内容的最大大小，0 为不限制
+func NewWebSubServer(addr string, store HubStore, receiver PushReceiver, maxBodySize int64) *Server {
+	h := &webSubHandler{store: store, receiver: receiver, maxBodySize: maxBodySize}
+	return &Server{
+		name:       "websub",
+		httpServer: &http.Server{Addr: addr, Handler: h, ReadHeaderTimeout: 10 * time.Second},
+	}
+}
+
+func (h *webSubHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	sourceID, err := strconv.ParseUint(path.Base(r.URL.Path), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		h.verify(w, r, uint(sourceID))
+	case http.MethodPost:
+		h.push(w, r, uint(sourceID))
+	default:
+		writeStatus(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+// verify 确认订阅请求时原样返回 hub.challenge，不是我们发出的请求时返回 404
+func (h *webSubHandler) verify(w http.ResponseWriter, r *http.Request, sourceID uint) {
+	query := r.URL.Query()
+	mode := query.Get("hub.mode")
+	leaseSeconds, _ := strconv.Atoi(query.Get("hub.lease_seconds"))
+	if err := h.store.VerifyHubIntent(r.Context(), sourceID, mode, query.Get("hub.topic"), leaseSeconds); err != nil {
+		log.Warnf("reject hub %s of source %d, %v", mode, sourceID, err)
+		http.NotFound(w, r)
+		return
+	}
+	if mode == "denied" {
+		writeStatus(w, http.StatusOK, "ok")
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.WriteString(w, query.Get("hub.challenge"))
+}
+
+// push 校验签名后交给 PushReceiver 处理，签名不符时按规范返回 2xx 但忽略内容
+func (h *webSubHandler) push(w http.ResponseWriter, r *http.Request, sourceID uint) {
+	source, err := h.store.GetSource(r.Context(), sourceID)
+	if errors.Is(err, core.ErrSourceNotExist) {
+		writeStatus(w, http.StatusGone, "gone")
+		return
+	}
+	if err != nil {
+		log.Errorf("get source %d for hub push failed, %v", sourceID, err)
+		writeStatus(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+
+	reader := r.Body
+	if h.maxBodySize > 0 {
+		reader = http.MaxBytesReader(w, r.Body, h.maxBodySize)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		writeStatus(w, http.StatusRequestEntityTooLarge, "request body too large")
+		return
+	}
+
+	if source.HubSecret == "" || !validHubSignature(r.Header.Get("X-Hub-Signature"), source.HubSecret, body) {
+		log.Warnf("ignore hub push of source %d with invalid signature", sourceID)
+		writeStatus(w, http.StatusAccepted, "accepted")
+		return
+	}
+
+	// 处理完再响应，退出时 http 服务会等待进行中的推送
+	ctx := context.WithoutCancel(r.Context())
+	if err := h.receiver.HandleHubPush(ctx, source, body, r.Header.Get("Content-Type")); err != nil {
+		log.Errorf("handle hub push of source %d failed, %v", sourceID, err)
+	}
+	writeStatus(w, http.StatusAccepted, "accepted")
+}
+
+// validHubSignature 校验 X-Hub-Signature，格式为 method=hex，method 为 sha1、sha256、sha384 或 sha512
+func validHubSignature(signature, secret string, body []byte) bool {
+	method, digest, ok := strings.Cut(signature, "=")
+	if !ok {
+		return false
+	}
+	var newHash func() hash.Hash
+	switch strings.ToLower(method) {
+	case "sha1":
+		newHash = sha1.New
+	case "sha256":
+		newHash = sha256.New
+	case "sha384":
+		newHash = sha512.New384
+	case "sha512":
+		newHash = sha512.New
+	default:
+		return false
+	}
+	expected, err := hex.DecodeString(digest)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(newHash, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(mac.Sum(nil), expected)
+}
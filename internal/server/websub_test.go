@@ -0,0 +1,123 @@
+package server
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/core"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+type fakeHubStore struct {
+	sources map[uint]*model.Source
+	topic   string
+}
+
+func (s *fakeHubStore) GetSource(ctx context.Context, id uint) (*model.Source, error) {
+	source, ok := s.sources[id]
+	if !ok {
+		return nil, core.ErrSourceNotExist
+	}
+	return source, nil
+}
+
+func (s *fakeHubStore) VerifyHubIntent(ctx context.Context, sourceID uint, mode, topic string, leaseSeconds int) error {
+	if _, ok := s.sources[sourceID]; !ok || topic != s.topic {
+		return core.ErrHubIntentMismatch
+	}
+	return nil
+}
+
+type fakePushReceiver struct {
+	bodies []string
+}
+
+func (r *fakePushReceiver) HandleHubPush(ctx context.Context, source *model.Source, body []byte, contentType string) error {
+	r.bodies = append(r.bodies, string(body))
+	return nil
+}
+
+func TestWebSubServer(t *testing.T) {
+	const secret = "secret"
+	store := &fakeHubStore{
+		sources: map[uint]*model.Source{1: {ID: 1, HubSecret: secret}},
+		topic:   "https://example.com/feed",
+	}
+	receiver := &fakePushReceiver{}
+	handler := NewWebSubServer(":0", store, receiver, 1024).httpServer.Handler
+
+	sign := func(body string) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+	push := func(path, body, signature string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("X-Hub-Signature", signature)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run(
+		"verify intent", func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(
+				rec, httptest.NewRequest(
+					http.MethodGet,
+					"/websub/1?hub.mode=subscribe&hub.topic=https%3A%2F%2Fexample.com%2Ffeed&hub.challenge=abc&hub.lease_seconds=60",
+					nil,
+				),
+			)
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "abc", rec.Body.String())
+
+			rec = httptest.NewRecorder()
+			handler.ServeHTTP(
+				rec, httptest.NewRequest(
+					http.MethodGet, "/websub/1?hub.mode=subscribe&hub.topic=https%3A%2F%2Fother.com&hub.challenge=abc", nil,
+				),
+			)
+			assert.Equal(t, http.StatusNotFound, rec.Code)
+		},
+	)
+
+	t.Run(
+		"push", func(t *testing.T) {
+			assert.Equal(t, http.StatusAccepted, push("/websub/1", "<rss/>", sign("<rss/>")))
+			assert.Equal(t, []string{"<rss/>"}, receiver.bodies)
+
+			// 签名不符时忽略内容
+			assert.Equal(t, http.StatusAccepted, push("/websub/1", "<rss>forged</rss>", sign("<rss/>")))
+			assert.Equal(t, http.StatusAccepted, push("/websub/1", "<rss/>", ""))
+			assert.Len(t, receiver.bodies, 1)
+
+			assert.Equal(t, http.StatusGone, push("/websub/2", "<rss/>", sign("<rss/>")))
+			assert.Equal(t, http.StatusNotFound, push("/websub/x", "<rss/>", sign("<rss/>")))
+			body := strings.Repeat("a", 2048)
+			assert.Equal(t, http.StatusRequestEntityTooLarge, push("/websub/1", body, sign(body)))
+		},
+	)
+}
+
+func TestValidHubSignature(t *testing.T) {
+	body := []byte("body")
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write(body)
+	digest := hex.EncodeToString(mac.Sum(nil))
+
+	assert.True(t, validHubSignature("sha256="+digest, "key", body))
+	assert.True(t, validHubSignature("SHA256="+digest, "key", body))
+	assert.False(t, validHubSignature("sha256="+digest, "other", body))
+	assert.False(t, validHubSignature("sha1="+digest, "key", body))
+	assert.False(t, validHubSignature("md5="+digest, "key", body))
+	assert.False(t, validHubSignature(digest, "key", body))
+}
@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"gorm.io/gorm"
 
@@ -28,6 +29,14 @@ func (s *ContentStorageImpl) DeleteSourceContents(ctx context.Context, sourceID
 	return result.RowsAffected, nil
 }
 
+func (s *ContentStorageImpl) MoveSourceContents(ctx context.Context, fromSourceID, toSourceID uint) (int64, error) {
+	result := s.db.WithContext(ctx).Where("source_id = ?", fromSourceID).Update("source_id", toSourceID)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
+
 func (s *ContentStorageImpl) AddContent(ctx context.Context, content *model.Content) error {
 	result := s.db.WithContext(ctx).Create(content)
 	if result.Error != nil {
@@ -44,3 +53,56 @@ func (s *ContentStorageImpl) HashIDExist(ctx context.Context, hashID string) (bo
 	}
 	return (count > 0), nil
 }
+
+func (s *ContentStorageImpl) GetSourceContentsSince(
+	ctx context.Context, sourceID uint, since time.Time,
+) ([]*model.Content, error) {
+	var contents []*model.Content
+	result := s.db.WithContext(ctx).Where(
+		"source_id = ? and created_at > ?", sourceID, since,
+	).Order("created_at asc").Find(&contents)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return contents, nil
+}
+
+func (s *ContentStorageImpl) GetContentsByHashIDs(ctx context.Context, hashIDs []string) ([]*model.Content, error) {
+	var contents []*model.Content
+	if len(hashIDs) == 0 {
+		return contents, nil
+	}
+	result := s.db.WithContext(ctx).Where("hash_id in ?", hashIDs).Find(&contents)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return contents, nil
+}
+
+func (s *ContentStorageImpl) GetSourceLatestContents(
+	ctx context.Context, sourceID uint, limit int,
+) ([]*model.Content, error) {
+	var contents []*model.Content
+	result := s.db.WithContext(ctx).Where("source_id = ?", sourceID).
+		Order("created_at desc").Order("published_at desc").Limit(limit).Find(&contents)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return contents, nil
+}
+
+func (s *ContentStorageImpl) StripContentsBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Where(
+		"created_at < ? and (description <> '' or summary <> '' or enclosures is not null)", before,
+	).Updates(
+		map[string]interface{}{
+			"description": "",
+			"summary":     "",
+			"enclosures":  nil,
+		},
+	)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -43,6 +44,101 @@ func TestContentStorageImpl(t *testing.T) {
 		},
 	)
 
+	t.Run(
+		"get source contents since", func(t *testing.T) {
+			got, err := s.GetSourceContentsSince(ctx, content.SourceID, time.Now().Add(-time.Hour))
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(got))
+			assert.Equal(t, content.HashID, got[0].HashID)
+
+			got, err = s.GetSourceContentsSince(ctx, content.SourceID, time.Now().Add(time.Hour))
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(got))
+
+			got, err = s.GetSourceContentsSince(ctx, 2, time.Now().Add(-time.Hour))
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(got))
+		},
+	)
+
+	t.Run(
+		"get contents by hash ids", func(t *testing.T) {
+			got, err := s.GetContentsByHashIDs(ctx, []string{content2.HashID, "missing"})
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(got))
+			assert.Equal(t, content2.HashID, got[0].HashID)
+
+			got, err = s.GetContentsByHashIDs(ctx, nil)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(got))
+		},
+	)
+
+	t.Run(
+		"get source latest contents", func(t *testing.T) {
+			got, err := s.GetSourceLatestContents(ctx, content.SourceID, 1)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(got))
+			assert.Equal(t, content2.HashID, got[0].HashID)
+
+			got, err = s.GetSourceLatestContents(ctx, content.SourceID, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(got))
+		},
+	)
+
+	t.Run(
+		"strip contents", func(t *testing.T) {
+			published := time.Date(2024, 1, 3, 10, 0, 0, 0, time.UTC)
+			old := &model.Content{
+				SourceID:    3,
+				HashID:      "old",
+				Description: "<p>body</p>",
+				Summary:     "summary",
+				PublishedAt: &published,
+				Enclosures:  model.Enclosures{{URL: "https://example.com/a.mp3", Type: "audio/mpeg"}},
+				EditTime:    model.EditTime{CreatedAt: time.Now().Add(-48 * time.Hour)},
+			}
+			assert.Nil(t, s.AddContent(ctx, old))
+
+			got, err := s.GetContentsByHashIDs(ctx, []string{old.HashID})
+			assert.Nil(t, err)
+			assert.Equal(t, old.Enclosures, got[0].Enclosures)
+			assert.True(t, published.Equal(*got[0].PublishedAt))
+
+			n, err := s.StripContentsBefore(ctx, time.Now().Add(-24*time.Hour))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(1), n)
+
+			got, err = s.GetContentsByHashIDs(ctx, []string{old.HashID})
+			assert.Nil(t, err)
+			assert.Equal(t, "", got[0].Description)
+			assert.Equal(t, "", got[0].Summary)
+			assert.Nil(t, got[0].Enclosures)
+			assert.NotNil(t, got[0].PublishedAt)
+
+			// 已清理的文章不会重复计数
+			n, err = s.StripContentsBefore(ctx, time.Now().Add(-24*time.Hour))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(0), n)
+		},
+	)
+
+	t.Run(
+		"move source contents", func(t *testing.T) {
+			n, err := s.MoveSourceContents(ctx, content.SourceID, 5)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(2), n)
+			got, err := s.GetSourceLatestContents(ctx, 5, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(got))
+
+			n, err = s.MoveSourceContents(ctx, 5, content.SourceID)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(2), n)
+		},
+	)
+
 	t.Run(
 		"del content", func(t *testing.T) {
 			got, err := s.DeleteSourceContents(ctx, content.SourceID)
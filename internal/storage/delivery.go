@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+type DeliveryStorageImpl struct {
+	db *gorm.DB
+}
+
+func NewDeliveryStorageImpl(db *gorm.DB) *DeliveryStorageImpl {
+	return &DeliveryStorageImpl{db: db}
+}
+
+func (s *DeliveryStorageImpl) Init(ctx context.Context) error {
+	return s.db.Migrator().AutoMigrate(&model.Delivery{})
+}
+
+func (s *DeliveryStorageImpl) AddDeliveries(ctx context.Context, deliveries []*model.Delivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (s *DeliveryStorageImpl) GetDueDeliveries(
+	ctx context.Context, now time.Time, limit int,
+) ([]*model.Delivery, error) {
+	var deliveries []*model.Delivery
+	result := s.db.WithContext(ctx).Where(
+		"status = ? and next_attempt_at <= ?", model.DeliveryStatusPending, now,
+	).Order("id asc").Limit(limit).Find(&deliveries)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return deliveries, nil
+}
+
+func (s *DeliveryStorageImpl) UpdateDelivery(ctx context.Context, delivery *model.Delivery) error {
+	result := s.db.WithContext(ctx).Save(delivery)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (s *DeliveryStorageImpl) RescheduleDigestDeliveries(
+	ctx context.Context, userID int64, sourceID uint, nextAttemptAt time.Time,
+) error {
+	result := s.db.WithContext(ctx).Model(&model.Delivery{}).Where(
+		"user_id = ? and source_id = ? and status = ? and digest = ?",
+		userID, sourceID, model.DeliveryStatusPending, true,
+	).Update("next_attempt_at", nextAttemptAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (s *DeliveryStorageImpl) MoveSourceDeliveries(ctx context.Context, fromSourceID, toSourceID uint) error {
+	result := s.db.WithContext(ctx).Model(&model.Delivery{}).Where("source_id = ?", fromSourceID).
+		Update("source_id", toSourceID)
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+func (s *DeliveryStorageImpl) DeleteFinishedDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Where(
+		"status <> ? and updated_at < ?", model.DeliveryStatusPending, before,
+	).Delete(&model.Delivery{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return result.RowsAffected, nil
+}
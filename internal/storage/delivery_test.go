@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+func TestDeliveryStorageImpl(t *testing.T) {
+	db := GetTestDB(t)
+	s := NewDeliveryStorageImpl(db)
+	ctx := context.Background()
+	if err := s.Init(ctx); err != nil {
+		t.Fatalf("init storage failed: %v", err)
+	}
+
+	now := time.Now()
+	newDeliveries := func() []*model.Delivery {
+		return []*model.Delivery{
+			{ContentHashID: "a", UserID: 1, SourceID: 1, Status: model.DeliveryStatusPending, NextAttemptAt: now},
+			{ContentHashID: "b", UserID: 1, SourceID: 1, Status: model.DeliveryStatusPending, NextAttemptAt: now},
+			{ContentHashID: "a", UserID: 2, SourceID: 1, Status: model.DeliveryStatusPending, NextAttemptAt: now},
+		}
+	}
+
+	t.Run(
+		"add deliveries", func(t *testing.T) {
+			err := s.AddDeliveries(ctx, newDeliveries())
+			assert.Nil(t, err)
+			// 重复入队不会产生重复记录
+			err = s.AddDeliveries(ctx, newDeliveries())
+			assert.Nil(t, err)
+			err = s.AddDeliveries(ctx, nil)
+			assert.Nil(t, err)
+
+			got, err := s.GetDueDeliveries(ctx, now, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, 3, len(got))
+		},
+	)
+
+	t.Run(
+		"get due deliveries", func(t *testing.T) {
+			got, err := s.GetDueDeliveries(ctx, now, 2)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(got))
+			assert.Equal(t, "a", got[0].ContentHashID)
+			assert.Equal(t, "b", got[1].ContentHashID)
+
+			got, err = s.GetDueDeliveries(ctx, now.Add(-time.Minute), 10)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(got))
+		},
+	)
+
+	t.Run(
+		"update delivery", func(t *testing.T) {
+			got, err := s.GetDueDeliveries(ctx, now, 10)
+			assert.Nil(t, err)
+
+			got[0].Status = model.DeliveryStatusSent
+			got[0].SentAt = &now
+			assert.Nil(t, s.UpdateDelivery(ctx, got[0]))
+			got[1].NextAttemptAt = now.Add(time.Minute)
+			got[1].Attempts = 1
+			assert.Nil(t, s.UpdateDelivery(ctx, got[1]))
+
+			due, err := s.GetDueDeliveries(ctx, now, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(due))
+			assert.Equal(t, got[2].ID, due[0].ID)
+
+			// 已发送的记录不会被重新入队
+			err = s.AddDeliveries(ctx, newDeliveries())
+			assert.Nil(t, err)
+			due, err = s.GetDueDeliveries(ctx, now.Add(time.Minute), 10)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(due))
+		},
+	)
+
+	t.Run(
+		"delete finished deliveries", func(t *testing.T) {
+			n, err := s.DeleteFinishedDeliveries(ctx, time.Now().Add(-time.Hour))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(0), n)
+
+			n, err = s.DeleteFinishedDeliveries(ctx, time.Now().Add(time.Hour))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(1), n)
+
+			due, err := s.GetDueDeliveries(ctx, now.Add(time.Minute), 10)
+			assert.Nil(t, err)
+			assert.Equal(t, 2, len(due))
+		},
+	)
+
+	t.Run(
+		"reschedule digest deliveries", func(t *testing.T) {
+			digest := &model.Delivery{
+				ContentHashID: "c", UserID: 3, SourceID: 2, Status: model.DeliveryStatusPending,
+				Digest: true, NextAttemptAt: now.Add(time.Hour),
+			}
+			assert.Nil(t, s.AddDeliveries(ctx, []*model.Delivery{digest}))
+			before := now.Add(-time.Minute)
+			due, err := s.GetDueDeliveries(ctx, before, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, len(due))
+
+			assert.Nil(t, s.RescheduleDigestDeliveries(ctx, 3, 2, before))
+			due, err = s.GetDueDeliveries(ctx, before, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, 1, len(due))
+			assert.Equal(t, "c", due[0].ContentHashID)
+		},
+	)
+
+	t.Run(
+		"move source deliveries", func(t *testing.T) {
+			assert.Nil(t, s.MoveSourceDeliveries(ctx, 2, 4))
+			due, err := s.GetDueDeliveries(ctx, now.Add(time.Hour), 10)
+			assert.Nil(t, err)
+			for _, delivery := range due {
+				assert.NotEqual(t, uint(2), delivery.SourceID)
+				if delivery.ContentHashID == "c" {
+					assert.Equal(t, uint(4), delivery.SourceID)
+				}
+			}
+		},
+	)
+}
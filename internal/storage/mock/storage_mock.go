@@ -7,6 +7,7 @@ package mock
 import (
 	context "context"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	model "github.com/zintus/flowerss-bot/internal/model"
@@ -73,6 +74,21 @@ func (m *MockUser) EXPECT() *MockUserMockRecorder {
 	return m.recorder
 }
 
+// CountUsers mocks base method.
+func (m *MockUser) CountUsers(ctx context.Context) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountUsers", ctx)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CountUsers indicates an expected call of CountUsers.
+func (mr *MockUserMockRecorder) CountUsers(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountUsers", reflect.TypeOf((*MockUser)(nil).CountUsers), ctx)
+}
+
 // CreateUser mocks base method.
 func (m *MockUser) CreateUser(ctx context.Context, user *model.User) error {
 	m.ctrl.T.Helper()
@@ -116,6 +132,20 @@ func (mr *MockUserMockRecorder) Init(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Init", reflect.TypeOf((*MockUser)(nil).Init), ctx)
 }
 
+// SetUserDigest mocks base method.
+func (m *MockUser) SetUserDigest(ctx context.Context, userID int64, digest string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserDigest", ctx, userID, digest)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserDigest indicates an expected call of SetUserDigest.
+func (mr *MockUserMockRecorder) SetUserDigest(ctx, userID, digest interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserDigest", reflect.TypeOf((*MockUser)(nil).SetUserDigest), ctx, userID, digest)
+}
+
 // SetUserLanguage mocks base method.
 func (m *MockUser) SetUserLanguage(ctx context.Context, userID int64, langCode string) error {
 	m.ctrl.T.Helper()
@@ -130,6 +160,20 @@ func (mr *MockUserMockRecorder) SetUserLanguage(ctx, userID, langCode interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserLanguage", reflect.TypeOf((*MockUser)(nil).SetUserLanguage), ctx, userID, langCode)
 }
 
+// SetUserQuietHours mocks base method.
+func (m *MockUser) SetUserQuietHours(ctx context.Context, user *model.User) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetUserQuietHours", ctx, user)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetUserQuietHours indicates an expected call of SetUserQuietHours.
+func (mr *MockUserMockRecorder) SetUserQuietHours(ctx, user interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetUserQuietHours", reflect.TypeOf((*MockUser)(nil).SetUserQuietHours), ctx, user)
+}
+
 // MockSource is a mock of Source interface.
 type MockSource struct {
 	ctrl     *gomock.Controller
@@ -211,6 +255,21 @@ func (mr *MockSourceMockRecorder) GetSourceByURL(ctx, url interface{}) *gomock.C
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSourceByURL", reflect.TypeOf((*MockSource)(nil).GetSourceByURL), ctx, url)
 }
 
+// GetSourceByURLKey mocks base method.
+func (m *MockSource) GetSourceByURLKey(ctx context.Context, key string) (*model.Source, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSourceByURLKey", ctx, key)
+	ret0, _ := ret[0].(*model.Source)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSourceByURLKey indicates an expected call of GetSourceByURLKey.
+func (mr *MockSourceMockRecorder) GetSourceByURLKey(ctx, key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSourceByURLKey", reflect.TypeOf((*MockSource)(nil).GetSourceByURLKey), ctx, key)
+}
+
 // GetSources mocks base method.
 func (m *MockSource) GetSources(ctx context.Context) ([]*model.Source, error) {
 	m.ctrl.T.Helper()
@@ -336,6 +395,21 @@ func (mr *MockSubscriptionMockRecorder) DeleteSubscription(ctx, userID, sourceID
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubscription", reflect.TypeOf((*MockSubscription)(nil).DeleteSubscription), ctx, userID, sourceID)
 }
 
+// GetSubscriberIDs mocks base method.
+func (m *MockSubscription) GetSubscriberIDs(ctx context.Context) ([]int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubscriberIDs", ctx)
+	ret0, _ := ret[0].([]int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubscriberIDs indicates an expected call of GetSubscriberIDs.
+func (mr *MockSubscriptionMockRecorder) GetSubscriberIDs(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubscriberIDs", reflect.TypeOf((*MockSubscription)(nil).GetSubscriberIDs), ctx)
+}
+
 // GetSubscription mocks base method.
 func (m *MockSubscription) GetSubscription(ctx context.Context, userID int64, sourceID uint) (*model.Subscribe, error) {
 	m.ctrl.T.Helper()
@@ -490,6 +564,51 @@ func (mr *MockContentMockRecorder) DeleteSourceContents(ctx, sourceID interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSourceContents", reflect.TypeOf((*MockContent)(nil).DeleteSourceContents), ctx, sourceID)
 }
 
+// GetContentsByHashIDs mocks base method.
+func (m *MockContent) GetContentsByHashIDs(ctx context.Context, hashIDs []string) ([]*model.Content, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetContentsByHashIDs", ctx, hashIDs)
+	ret0, _ := ret[0].([]*model.Content)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetContentsByHashIDs indicates an expected call of GetContentsByHashIDs.
+func (mr *MockContentMockRecorder) GetContentsByHashIDs(ctx, hashIDs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetContentsByHashIDs", reflect.TypeOf((*MockContent)(nil).GetContentsByHashIDs), ctx, hashIDs)
+}
+
+// GetSourceContentsSince mocks base method.
+func (m *MockContent) GetSourceContentsSince(ctx context.Context, sourceID uint, since time.Time) ([]*model.Content, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSourceContentsSince", ctx, sourceID, since)
+	ret0, _ := ret[0].([]*model.Content)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSourceContentsSince indicates an expected call of GetSourceContentsSince.
+func (mr *MockContentMockRecorder) GetSourceContentsSince(ctx, sourceID, since interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSourceContentsSince", reflect.TypeOf((*MockContent)(nil).GetSourceContentsSince), ctx, sourceID, since)
+}
+
+// GetSourceLatestContents mocks base method.
+func (m *MockContent) GetSourceLatestContents(ctx context.Context, sourceID uint, limit int) ([]*model.Content, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSourceLatestContents", ctx, sourceID, limit)
+	ret0, _ := ret[0].([]*model.Content)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSourceLatestContents indicates an expected call of GetSourceLatestContents.
+func (mr *MockContentMockRecorder) GetSourceLatestContents(ctx, sourceID, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSourceLatestContents", reflect.TypeOf((*MockContent)(nil).GetSourceLatestContents), ctx, sourceID, limit)
+}
+
 // HashIDExist mocks base method.
 func (m *MockContent) HashIDExist(ctx context.Context, hashID string) (bool, error) {
 	m.ctrl.T.Helper()
@@ -518,3 +637,209 @@ func (mr *MockContentMockRecorder) Init(ctx interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Init", reflect.TypeOf((*MockContent)(nil).Init), ctx)
 }
+
+// MoveSourceContents mocks base method.
+func (m *MockContent) MoveSourceContents(ctx context.Context, fromSourceID, toSourceID uint) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MoveSourceContents", ctx, fromSourceID, toSourceID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MoveSourceContents indicates an expected call of MoveSourceContents.
+func (mr *MockContentMockRecorder) MoveSourceContents(ctx, fromSourceID, toSourceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MoveSourceContents", reflect.TypeOf((*MockContent)(nil).MoveSourceContents), ctx, fromSourceID, toSourceID)
+}
+
+// StripContentsBefore mocks base method.
+func (m *MockContent) StripContentsBefore(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StripContentsBefore", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StripContentsBefore indicates an expected call of StripContentsBefore.
+func (mr *MockContentMockRecorder) StripContentsBefore(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StripContentsBefore", reflect.TypeOf((*MockContent)(nil).StripContentsBefore), ctx, before)
+}
+
+// MockContentSearch is a mock of ContentSearch interface.
+type MockContentSearch struct {
+	ctrl     *gomock.Controller
+	recorder *MockContentSearchMockRecorder
+}
+
+// MockContentSearchMockRecorder is the mock recorder for MockContentSearch.
+type MockContentSearchMockRecorder struct {
+	mock *MockContentSearch
+}
+
+// NewMockContentSearch creates a new mock instance.
+func NewMockContentSearch(ctrl *gomock.Controller) *MockContentSearch {
+	mock := &MockContentSearch{ctrl: ctrl}
+	mock.recorder = &MockContentSearchMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockContentSearch) EXPECT() *MockContentSearchMockRecorder {
+	return m.recorder
+}
+
+// Init mocks base method.
+func (m *MockContentSearch) Init(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Init", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Init indicates an expected call of Init.
+func (mr *MockContentSearchMockRecorder) Init(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Init", reflect.TypeOf((*MockContentSearch)(nil).Init), ctx)
+}
+
+// SearchContents mocks base method.
+func (m *MockContentSearch) SearchContents(ctx context.Context, sourceIDs []uint, query string, offset, limit int) ([]*model.Content, int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SearchContents", ctx, sourceIDs, query, offset, limit)
+	ret0, _ := ret[0].([]*model.Content)
+	ret1, _ := ret[1].(int64)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// SearchContents indicates an expected call of SearchContents.
+func (mr *MockContentSearchMockRecorder) SearchContents(ctx, sourceIDs, query, offset, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SearchContents", reflect.TypeOf((*MockContentSearch)(nil).SearchContents), ctx, sourceIDs, query, offset, limit)
+}
+
+// MockDelivery is a mock of Delivery interface.
+type MockDelivery struct {
+	ctrl     *gomock.Controller
+	recorder *MockDeliveryMockRecorder
+}
+
+// MockDeliveryMockRecorder is the mock recorder for MockDelivery.
+type MockDeliveryMockRecorder struct {
+	mock *MockDelivery
+}
+
+// NewMockDelivery creates a new mock instance.
+func NewMockDelivery(ctrl *gomock.Controller) *MockDelivery {
+	mock := &MockDelivery{ctrl: ctrl}
+	mock.recorder = &MockDeliveryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDelivery) EXPECT() *MockDeliveryMockRecorder {
+	return m.recorder
+}
+
+// AddDeliveries mocks base method.
+func (m *MockDelivery) AddDeliveries(ctx context.Context, deliveries []*model.Delivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddDeliveries", ctx, deliveries)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddDeliveries indicates an expected call of AddDeliveries.
+func (mr *MockDeliveryMockRecorder) AddDeliveries(ctx, deliveries interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDeliveries", reflect.TypeOf((*MockDelivery)(nil).AddDeliveries), ctx, deliveries)
+}
+
+// DeleteFinishedDeliveries mocks base method.
+func (m *MockDelivery) DeleteFinishedDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteFinishedDeliveries", ctx, before)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteFinishedDeliveries indicates an expected call of DeleteFinishedDeliveries.
+func (mr *MockDeliveryMockRecorder) DeleteFinishedDeliveries(ctx, before interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteFinishedDeliveries", reflect.TypeOf((*MockDelivery)(nil).DeleteFinishedDeliveries), ctx, before)
+}
+
+// GetDueDeliveries mocks base method.
+func (m *MockDelivery) GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*model.Delivery, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDueDeliveries", ctx, now, limit)
+	ret0, _ := ret[0].([]*model.Delivery)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDueDeliveries indicates an expected call of GetDueDeliveries.
+func (mr *MockDeliveryMockRecorder) GetDueDeliveries(ctx, now, limit interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDueDeliveries", reflect.TypeOf((*MockDelivery)(nil).GetDueDeliveries), ctx, now, limit)
+}
+
+// Init mocks base method.
+func (m *MockDelivery) Init(ctx context.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Init", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Init indicates an expected call of Init.
+func (mr *MockDeliveryMockRecorder) Init(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Init", reflect.TypeOf((*MockDelivery)(nil).Init), ctx)
+}
+
+// MoveSourceDeliveries mocks base method.
+func (m *MockDelivery) MoveSourceDeliveries(ctx context.Context, fromSourceID, toSourceID uint) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MoveSourceDeliveries", ctx, fromSourceID, toSourceID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MoveSourceDeliveries indicates an expected call of MoveSourceDeliveries.
+func (mr *MockDeliveryMockRecorder) MoveSourceDeliveries(ctx, fromSourceID, toSourceID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MoveSourceDeliveries", reflect.TypeOf((*MockDelivery)(nil).MoveSourceDeliveries), ctx, fromSourceID, toSourceID)
+}
+
+// RescheduleDigestDeliveries mocks base method.
+func (m *MockDelivery) RescheduleDigestDeliveries(ctx context.Context, userID int64, sourceID uint, nextAttemptAt time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RescheduleDigestDeliveries", ctx, userID, sourceID, nextAttemptAt)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RescheduleDigestDeliveries indicates an expected call of RescheduleDigestDeliveries.
+func (mr *MockDeliveryMockRecorder) RescheduleDigestDeliveries(ctx, userID, sourceID, nextAttemptAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RescheduleDigestDeliveries", reflect.TypeOf((*MockDelivery)(nil).RescheduleDigestDeliveries), ctx, userID, sourceID, nextAttemptAt)
+}
+
+// UpdateDelivery mocks base method.
+func (m *MockDelivery) UpdateDelivery(ctx context.Context, delivery *model.Delivery) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateDelivery", ctx, delivery)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateDelivery indicates an expected call of UpdateDelivery.
+func (mr *MockDeliveryMockRecorder) UpdateDelivery(ctx, delivery interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateDelivery", reflect.TypeOf((*MockDelivery)(nil).UpdateDelivery), ctx, delivery)
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+
+	"github.com/zintus/flowerss-bot/internal/log"
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+const (
+	// maxSearchTerms 搜索语句中最多使用的关键词数量
+	maxSearchTerms = 8
+	// ftsMinTermLen trigram 索引能匹配的最短关键词长度，更短的关键词使用 LIKE
+	ftsMinTermLen = 3
+)
+
+// sqliteFTSTriggers 保持 content_fts 与 contents 同步的触发器
+var sqliteFTSTriggers = map[string]string{
+	"content_fts_ai": `CREATE TRIGGER IF NOT EXISTS content_fts_ai AFTER INSERT ON contents BEGIN
+	INSERT INTO content_fts(rowid, title, description, summary) VALUES (new.rowid, new.title, new.description, new.summary);
+END`,
+	"content_fts_ad": `CREATE TRIGGER IF NOT EXISTS content_fts_ad AFTER DELETE ON contents BEGIN
+	INSERT INTO content_fts(content_fts, rowid, title, description, summary) VALUES ('delete', old.rowid, old.title, old.description, old.summary);
+END`,
+	"content_fts_au": `CREATE TRIGGER IF NOT EXISTS content_fts_au AFTER UPDATE ON contents BEGIN
+	INSERT INTO content_fts(content_fts, rowid, title, description, summary) VALUES ('delete', old.rowid, old.title, old.description, old.summary);
+	INSERT INTO content_fts(rowid, title, description, summary) VALUES (new.rowid, new.title, new.description, new.summary);
+END`,
+}
+
+// SQLiteContentSearchImpl 基于 SQLite FTS5 的文章搜索，sqlite 未编译 FTS5 时（需要 sqlite_fts5 build tag）使用 LIKE 搜索
+type SQLiteContentSearchImpl struct {
+	db  *gorm.DB
+	fts bool
+}
+
+func NewSQLiteContentSearchImpl(db *gorm.DB) *SQLiteContentSearchImpl {
+	return &SQLiteContentSearchImpl{db: db}
+}
+
+func (s *SQLiteContentSearchImpl) Init(ctx context.Context) error {
+	db := s.db.WithContext(ctx)
+	err := db.Exec(
+		`CREATE VIRTUAL TABLE IF NOT EXISTS content_fts USING fts5(` +
+			`title, description, summary, content='contents', content_rowid='rowid', tokenize='trigram')`,
+	).Error
+	if err != nil {
+		if !strings.Contains(err.Error(), "no such module") {
+			return err
+		}
+		log.Warnf("sqlite fts5 is not available, falling back to LIKE search: %v", err)
+		// 触发器在没有 FTS5 时会导致写入文章失败，删除后由支持 FTS5 的版本重建索引
+		for name := range sqliteFTSTriggers {
+			if err := db.Exec("DROP TRIGGER IF EXISTS " + name).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var triggers int64
+	if err := db.Raw(
+		"SELECT count(*) FROM sqlite_master WHERE type = 'trigger' AND name LIKE 'content_fts_%'",
+	).Scan(&triggers).Error; err != nil {
+		return err
+	}
+	if triggers < int64(len(sqliteFTSTriggers)) {
+		for _, trigger := range sqliteFTSTriggers {
+			if err := db.Exec(trigger).Error; err != nil {
+				return err
+			}
+		}
+		// 触发器缺失期间写入的文章不在索引中，重建索引
+		if err := db.Exec("INSERT INTO content_fts(content_fts) VALUES ('rebuild')").Error; err != nil {
+			return err
+		}
+	}
+	s.fts = true
+	return nil
+}
+
+func (s *SQLiteContentSearchImpl) SearchContents(
+	ctx context.Context, sourceIDs []uint, query string, offset, limit int,
+) ([]*model.Content, int64, error) {
+	terms := searchTerms(query)
+	if len(sourceIDs) == 0 || len(terms) == 0 {
+		return nil, 0, nil
+	}
+
+	tx := s.db.WithContext(ctx).Model(&model.Content{}).Where("contents.source_id IN ?", sourceIDs)
+	if s.fts {
+		tx = tx.Joins("JOIN content_fts ON content_fts.rowid = contents.rowid")
+		var match []string
+		for _, term := range terms {
+			if utf8.RuneCountInString(term) >= ftsMinTermLen {
+				match = append(match, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+				continue
+			}
+			tx = whereTermLike(tx, "content_fts", term)
+		}
+		if len(match) > 0 {
+			tx = tx.Where("content_fts MATCH ?", strings.Join(match, " "))
+		}
+	} else {
+		for _, term := range terms {
+			tx = whereTermLike(tx, "contents", term)
+		}
+	}
+	return findSearchPage(tx, offset, limit)
+}
+
+// searchTerms 将搜索语句按空白拆分为去重后的关键词
+func searchTerms(query string) []string {
+	var terms []string
+	seen := map[string]bool{}
+	for _, term := range strings.Fields(query) {
+		if seen[strings.ToLower(term)] {
+			continue
+		}
+		seen[strings.ToLower(term)] = true
+		terms = append(terms, term)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
+
+// whereTermLike 要求标题、正文或摘要中包含关键词
+func whereTermLike(tx *gorm.DB, table string, term string) *gorm.DB {
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term) + "%"
+	return tx.Where(
+		`(`+table+`.title LIKE ? ESCAPE '\' OR `+table+`.description LIKE ? ESCAPE '\' OR `+
+			table+`.summary LIKE ? ESCAPE '\')`,
+		pattern, pattern, pattern,
+	)
+}
+
+// findSearchPage 按入库时间倒序获取一页搜索结果和匹配总数
+func findSearchPage(tx *gorm.DB, offset, limit int) ([]*model.Content, int64, error) {
+	var total int64
+	if err := tx.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	var contents []*model.Content
+	result := tx.Select("contents.*").Order("contents.created_at desc").Order("contents.published_at desc").
+		Offset(offset).Limit(limit).Find(&contents)
+	if result.Error != nil {
+		return nil, 0, result.Error
+	}
+	return contents, total, nil
+}
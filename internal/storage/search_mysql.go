@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"gorm.io/gorm"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+// mysqlFulltextIndex 文章全文索引名
+const mysqlFulltextIndex = "idx_content_fulltext"
+
+// MysqlContentSearchImpl 基于 MySQL FULLTEXT 索引的文章搜索，使用 ngram 分词以支持中文
+type MysqlContentSearchImpl struct {
+	db *gorm.DB
+}
+
+func NewMysqlContentSearchImpl(db *gorm.DB) *MysqlContentSearchImpl {
+	return &MysqlContentSearchImpl{db: db}
+}
+
+func (s *MysqlContentSearchImpl) Init(ctx context.Context) error {
+	if s.db.Migrator().HasIndex(&model.Content{}, mysqlFulltextIndex) {
+		return nil
+	}
+	return s.db.WithContext(ctx).Exec(
+		"CREATE FULLTEXT INDEX " + mysqlFulltextIndex + " ON contents (title, description, summary) WITH PARSER ngram",
+	).Error
+}
+
+func (s *MysqlContentSearchImpl) SearchContents(
+	ctx context.Context, sourceIDs []uint, query string, offset, limit int,
+) ([]*model.Content, int64, error) {
+	terms := searchTerms(query)
+	if len(sourceIDs) == 0 || len(terms) == 0 {
+		return nil, 0, nil
+	}
+
+	// 布尔模式下每个关键词都必须出现，关键词按短语匹配以避免解析其中的运算符
+	match := make([]string, 0, len(terms))
+	for _, term := range terms {
+		match = append(match, `+"`+strings.ReplaceAll(term, `"`, ``)+`"`)
+	}
+	tx := s.db.WithContext(ctx).Model(&model.Content{}).
+		Where("contents.source_id IN ?", sourceIDs).
+		Where("MATCH (title, description, summary) AGAINST (? IN BOOLEAN MODE)", strings.Join(match, " "))
+	return findSearchPage(tx, offset, limit)
+}
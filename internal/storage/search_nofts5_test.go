@@ -0,0 +1,6 @@
+//go:build !sqlite_fts5
+
+package storage
+
+// sqliteFTS5Enabled go-sqlite3 是否编译了 FTS5
+const sqliteFTS5Enabled = false
@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/zintus/flowerss-bot/internal/model"
+)
+
+func TestSQLiteContentSearchImpl(t *testing.T) {
+	db := GetTestDB(t)
+	contentStorage := NewContentStorageImpl(db)
+	ctx := context.Background()
+	if err := contentStorage.Init(ctx); err != nil {
+		t.Fatalf("init storage failed: %v", err)
+	}
+	s := NewSQLiteContentSearchImpl(db)
+	if err := s.Init(ctx); err != nil {
+		t.Fatalf("init search failed: %v", err)
+	}
+	// 重复初始化不会出错
+	if err := s.Init(ctx); err != nil {
+		t.Fatalf("init search again failed: %v", err)
+	}
+	assert.Equal(t, sqliteFTS5Enabled, s.fts, "fts5 is only available with the sqlite_fts5 build tag")
+	// 未启用 FTS5 时使用的 LIKE 搜索，两种构建下都要测试
+	likeSearch := &SQLiteContentSearchImpl{db: db}
+
+	now := time.Now()
+	contents := []*model.Content{
+		{SourceID: 10, HashID: "s1", Title: "Go 1.22 released", Description: "<p>Range over integers</p>"},
+		{SourceID: 10, HashID: "s2", Title: "Rust news", Summary: "Golang comparison"},
+		{SourceID: 11, HashID: "s3", Title: "Go generics", Description: "100% type safe"},
+		{SourceID: 12, HashID: "s4", Title: "Go in another feed"},
+		{SourceID: 11, HashID: "s5", Title: "今天的新闻", Description: "全文搜索测试"},
+	}
+	for i, content := range contents {
+		content.CreatedAt = now.Add(time.Duration(i) * time.Second)
+		assert.Nil(t, contentStorage.AddContent(ctx, content))
+	}
+
+	tests := []struct {
+		name      string
+		sourceIDs []uint
+		query     string
+		want      []string
+	}{
+		{name: "title", sourceIDs: []uint{10, 11}, query: "released", want: []string{"s1"}},
+		{name: "body", sourceIDs: []uint{10, 11}, query: "integers", want: []string{"s1"}},
+		{name: "summary", sourceIDs: []uint{10, 11}, query: "golang", want: []string{"s2"}},
+		{name: "short term", sourceIDs: []uint{10, 11}, query: "go", want: []string{"s3", "s2", "s1"}},
+		{name: "all terms", sourceIDs: []uint{10, 11}, query: "go generics", want: []string{"s3"}},
+		{name: "scoped", sourceIDs: []uint{12}, query: "go", want: []string{"s4"}},
+		{name: "chinese", sourceIDs: []uint{11}, query: "全文搜索", want: []string{"s5"}},
+		{name: "like wildcard", sourceIDs: []uint{10, 11}, query: "100%", want: []string{"s3"}},
+		{name: "fts syntax", sourceIDs: []uint{10, 11}, query: `"go" OR*`, want: nil},
+		{name: "no subscriptions", sourceIDs: nil, query: "go", want: nil},
+		{name: "empty query", sourceIDs: []uint{10}, query: "  ", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(
+			tt.name, func(t *testing.T) {
+				for _, searcher := range []*SQLiteContentSearchImpl{s, likeSearch} {
+					got, total, err := searcher.SearchContents(ctx, tt.sourceIDs, tt.query, 0, 10)
+					assert.Nil(t, err)
+					assert.Equal(t, int64(len(tt.want)), total, "fts: %v", searcher.fts)
+					var hashIDs []string
+					for _, content := range got {
+						hashIDs = append(hashIDs, content.HashID)
+					}
+					assert.Equal(t, tt.want, hashIDs, "fts: %v", searcher.fts)
+				}
+			},
+		)
+	}
+
+	t.Run(
+		"paginate", func(t *testing.T) {
+			got, total, err := s.SearchContents(ctx, []uint{10, 11}, "go", 1, 1)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(3), total)
+			assert.Equal(t, 1, len(got))
+			assert.Equal(t, "s2", got[0].HashID)
+		},
+	)
+
+	t.Run(
+		"index follows updates", func(t *testing.T) {
+			n, err := contentStorage.StripContentsBefore(ctx, now.Add(time.Hour))
+			assert.Nil(t, err)
+			assert.Equal(t, int64(4), n)
+			_, total, err := s.SearchContents(ctx, []uint{10, 11}, "integers", 0, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(0), total)
+
+			_, err = contentStorage.DeleteSourceContents(ctx, 12)
+			assert.Nil(t, err)
+			_, total, err = s.SearchContents(ctx, []uint{12}, "another", 0, 10)
+			assert.Nil(t, err)
+			assert.Equal(t, int64(0), total)
+		},
+	)
+}
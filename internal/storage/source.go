@@ -6,6 +6,7 @@ import (
 
 	"gorm.io/gorm"
 
+	"github.com/zintus/flowerss-bot/internal/config"
 	"github.com/zintus/flowerss-bot/internal/log"
 	"github.com/zintus/flowerss-bot/internal/model"
 )
@@ -19,7 +20,17 @@ func NewSourceStorageImpl(db *gorm.DB) *SourceStorageImpl {
 }
 
 func (s *SourceStorageImpl) Init(ctx context.Context) error {
-	return s.db.Migrator().AutoMigrate(&model.Source{})
+	hasPaused := s.db.Migrator().HasColumn(&model.Source{}, "Paused")
+	if err := s.db.Migrator().AutoMigrate(&model.Source{}); err != nil {
+		return err
+	}
+	if hasPaused {
+		return nil
+	}
+	// 旧版本通过错误计数超过阈值表示暂停更新，迁移为 Paused
+	return s.db.WithContext(ctx).Model(&model.Source{}).
+		Where("error_count >= ?", config.ErrorThreshold).
+		Update("paused", true).Error
 }
 
 func (s *SourceStorageImpl) AddSource(ctx context.Context, source *model.Source) error {
@@ -57,7 +68,20 @@ func (s *SourceStorageImpl) GetSources(ctx context.Context) ([]*model.Source, er
 
 func (s *SourceStorageImpl) GetSourceByURL(ctx context.Context, url string) (*model.Source, error) {
 	var source = &model.Source{}
-	result := s.db.WithContext(ctx).Where(&model.Source{Link: url}).First(source)
+	// 有自定义请求设置的订阅源只属于设置它的订阅者，不参与按链接查找
+	result := s.db.WithContext(ctx).Where("link = ? AND request_settings IS NULL", url).First(source)
+	if result.Error != nil {
+		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, result.Error
+	}
+	return source, nil
+}
+
+func (s *SourceStorageImpl) GetSourceByURLKey(ctx context.Context, key string) (*model.Source, error) {
+	var source = &model.Source{}
+	result := s.db.WithContext(ctx).Where("url_key = ? AND request_settings IS NULL", key).First(source)
 	if result.Error != nil {
 		if errors.Is(result.Error, gorm.ErrRecordNotFound) {
 			return nil, ErrRecordNotFound
@@ -90,14 +114,33 @@ func (s *SourceStorageImpl) UpsertSource(ctx context.Context, sourceID uint, new
 	} else {
 		// Source found, update fields
 		oldSource.Link = newSource.Link
+		oldSource.URLKey = newSource.URLKey
+		oldSource.OriginLink = newSource.OriginLink
+		oldSource.RedirectURL = newSource.RedirectURL
+		oldSource.RedirectCount = newSource.RedirectCount
 		oldSource.Title = newSource.Title
 		oldSource.ErrorCount = newSource.ErrorCount
+		oldSource.Paused = newSource.Paused
+		oldSource.LastError = newSource.LastError
+		oldSource.LastErrorAt = newSource.LastErrorAt
 		oldSource.LastPublishedAt = newSource.LastPublishedAt
+		oldSource.LastContentAt = newSource.LastContentAt
+		oldSource.LastFetchedAt = newSource.LastFetchedAt
+		oldSource.NextFetchAt = newSource.NextFetchAt
+		oldSource.ETag = newSource.ETag
+		oldSource.LastModified = newSource.LastModified
+		oldSource.RequestSettings = newSource.RequestSettings
+		oldSource.HubURL = newSource.HubURL
+		oldSource.HubTopic = newSource.HubTopic
+		oldSource.HubSecret = newSource.HubSecret
+		oldSource.HubLeaseUntil = newSource.HubLeaseUntil
+		oldSource.FeedHints = newSource.FeedHints
+		oldSource.PublishHistory = newSource.PublishHistory
 		result = s.db.WithContext(ctx).Save(&oldSource)
 		if result.Error != nil {
 			return result.Error
 		}
-		log.Debugf("update %d row, sourceID %d new %#v", result.RowsAffected, sourceID, oldSource)
+		log.Debugf("update %d row, source [%d]%s", result.RowsAffected, sourceID, oldSource.DisplayLink())
 		return nil
 	}
 }
@@ -8,6 +8,7 @@ import (
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
+	"github.com/zintus/flowerss-bot/internal/config"
 	"github.com/zintus/flowerss-bot/internal/model"
 )
 
@@ -175,4 +176,145 @@ func TestSourceStorage_NewInstallation_HasLastPublishedAt(t *testing.T) {
 	if !retrieved.LastPublishedAt.Equal(now) {
 		t.Errorf("Expected LastPublishedAt to be %v, got %v", now, *retrieved.LastPublishedAt)
 	}
-}
\ No newline at end of file
+}
+
+func TestSourceStorage_Migration_PausesSourcesOverThreshold(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get sql.DB: %v", err)
+	}
+
+	// Old schema paused a source by pushing error_count over the threshold
+	_, err = sqlDB.Exec(`
+		CREATE TABLE sources (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			link TEXT,
+			title TEXT,
+			error_count INTEGER,
+			created_at DATETIME,
+			updated_at DATETIME
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create old schema: %v", err)
+	}
+	now := time.Now()
+	_, err = sqlDB.Exec(`
+		INSERT INTO sources (link, title, error_count, created_at, updated_at)
+		VALUES
+			('http://example1.com/feed', 'Active', 0, ?, ?),
+			('http://example2.com/feed', 'Failing', 3, ?, ?),
+			('http://example3.com/feed', 'Paused', ?, ?, ?)
+	`, now, now, now, now, config.ErrorThreshold+1, now, now)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	storage := NewSourceStorageImpl(db)
+	if err := storage.Init(context.Background()); err != nil {
+		t.Fatalf("Failed to run migration: %v", err)
+	}
+
+	var sources []*model.Source
+	if err := db.Order("id").Find(&sources).Error; err != nil {
+		t.Fatalf("Failed to query sources after migration: %v", err)
+	}
+	if len(sources) != 3 {
+		t.Fatalf("Expected 3 sources, got %d", len(sources))
+	}
+	if sources[0].Paused || sources[1].Paused {
+		t.Error("Sources under the error threshold should not be paused")
+	}
+	if !sources[2].Paused {
+		t.Error("Source over the error threshold should be paused")
+	}
+
+	// Running Init again must not pause sources that fail later
+	if err := db.Model(sources[1]).Update("error_count", config.ErrorThreshold).Error; err != nil {
+		t.Fatalf("Failed to update error count: %v", err)
+	}
+	if err := storage.Init(context.Background()); err != nil {
+		t.Fatalf("Failed to run migration again: %v", err)
+	}
+	retrieved, err := storage.GetSource(context.Background(), sources[1].ID)
+	if err != nil {
+		t.Fatalf("Failed to get source: %v", err)
+	}
+	if retrieved.Paused {
+		t.Error("Source should not be paused by a repeated migration")
+	}
+}
+
+func TestSubscriptionStorage_Migration_MarksIntervalSet(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to create test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get sql.DB: %v", err)
+	}
+
+	_, err = sqlDB.Exec(`
+		CREATE TABLE subscribes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id INTEGER,
+			source_id INTEGER,
+			interval INTEGER,
+			created_at DATETIME,
+			updated_at DATETIME
+		)
+	`)
+	if err != nil {
+		t.Fatalf("Failed to create old schema: %v", err)
+	}
+	now := time.Now()
+	_, err = sqlDB.Exec(`
+		INSERT INTO subscribes (user_id, source_id, interval, created_at, updated_at)
+		VALUES (1, 1, 0, ?, ?), (2, 1, 30, ?, ?), (4, 1, ?, ?, ?)
+	`, now, now, now, now, config.UpdateInterval, now, now)
+	if err != nil {
+		t.Fatalf("Failed to insert test data: %v", err)
+	}
+
+	storage := NewSubscriptionStorageImpl(db)
+	if err := storage.Init(context.Background()); err != nil {
+		t.Fatalf("Failed to run migration: %v", err)
+	}
+
+	var subs []*model.Subscribe
+	if err := db.Order("id").Find(&subs).Error; err != nil {
+		t.Fatalf("Failed to query subscriptions after migration: %v", err)
+	}
+	if len(subs) != 3 {
+		t.Fatalf("Expected 3 subscriptions, got %d", len(subs))
+	}
+	if subs[0].IntervalSet {
+		t.Error("Subscription without interval should not be marked as set")
+	}
+	if !subs[1].IntervalSet {
+		t.Error("Subscription with interval should be marked as set")
+	}
+	if subs[2].IntervalSet {
+		t.Error("Subscription with the default interval should not be marked as set")
+	}
+
+	// Running Init again must not mark default intervals of new subscriptions
+	if err := db.Create(&model.Subscribe{UserID: 3, SourceID: 1, Interval: 10}).Error; err != nil {
+		t.Fatalf("Failed to add subscription: %v", err)
+	}
+	if err := storage.Init(context.Background()); err != nil {
+		t.Fatalf("Failed to run migration again: %v", err)
+	}
+	var added model.Subscribe
+	if err := db.Where("user_id = ?", 3).First(&added).Error; err != nil {
+		t.Fatalf("Failed to query subscription: %v", err)
+	}
+	if added.IntervalSet {
+		t.Error("Default interval of a new subscription should not be marked as set")
+	}
+}
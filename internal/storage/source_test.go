@@ -64,4 +64,51 @@ func TestSourceStorageImpl(t *testing.T) {
 			assert.Equal(t, source.Title, got.Title)
 		},
 	)
+	t.Run(
+		"request settings", func(t *testing.T) {
+			private := &model.Source{
+				Link: "http://example.com/private",
+				RequestSettings: model.RequestSettings{
+					Headers:       map[string]string{"Cookie": "session=1"},
+					BasicAuthUser: "user",
+				},
+			}
+			assert.Nil(t, s.AddSource(ctx, private))
+
+			got, err := s.GetSource(ctx, private.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, private.RequestSettings, got.RequestSettings)
+
+			// 有自定义设置的订阅源不会按链接被找到
+			_, err = s.GetSourceByURL(ctx, private.Link)
+			assert.Equal(t, ErrRecordNotFound, err)
+
+			got.RequestSettings = model.RequestSettings{}
+			assert.Nil(t, s.UpsertSource(ctx, got.ID, got))
+			got, err = s.GetSourceByURL(ctx, private.Link)
+			assert.Nil(t, err)
+			assert.Equal(t, private.ID, got.ID)
+			assert.True(t, got.RequestSettings.IsEmpty())
+		},
+	)
+
+	t.Run(
+		"url key", func(t *testing.T) {
+			keyed := &model.Source{Link: "https://www.example.com/feed/", URLKey: "//example.com/feed"}
+			assert.Nil(t, s.AddSource(ctx, keyed))
+			private := &model.Source{
+				Link:            keyed.Link,
+				URLKey:          keyed.URLKey,
+				RequestSettings: model.RequestSettings{UserAgent: "reader"},
+			}
+			assert.Nil(t, s.AddSource(ctx, private))
+
+			got, err := s.GetSourceByURLKey(ctx, keyed.URLKey)
+			assert.Nil(t, err)
+			assert.Equal(t, keyed.ID, got.ID)
+
+			_, err = s.GetSourceByURLKey(ctx, "//example.com/other")
+			assert.Equal(t, ErrRecordNotFound, err)
+		},
+	)
 }
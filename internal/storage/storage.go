@@ -5,6 +5,7 @@ package storage
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/zintus/flowerss-bot/internal/model"
 )
@@ -24,6 +25,9 @@ type User interface {
 	CreateUser(ctx context.Context, user *model.User) error
 	GetUser(ctx context.Context, id int64) (*model.User, error)
 	SetUserLanguage(ctx context.Context, userID int64, langCode string) error
+	SetUserQuietHours(ctx context.Context, user *model.User) error
+	SetUserDigest(ctx context.Context, userID int64, digest string) error
+	CountUsers(ctx context.Context) (int64, error)
 }
 
 // Source 订阅源存储接口
@@ -33,6 +37,8 @@ type Source interface {
 	GetSource(ctx context.Context, id uint) (*model.Source, error)
 	GetSources(ctx context.Context) ([]*model.Source, error)
 	GetSourceByURL(ctx context.Context, url string) (*model.Source, error)
+	// GetSourceByURLKey 按链接的规范形式查找没有自定义请求设置的订阅源
+	GetSourceByURLKey(ctx context.Context, key string) (*model.Source, error)
 	Delete(ctx context.Context, id uint) error
 	UpsertSource(ctx context.Context, sourceID uint, newSource *model.Source) error
 }
@@ -66,6 +72,7 @@ type Subscription interface {
 		ctx context.Context, sourceID uint, opts *GetSubscriptionsOptions,
 	) (*GetSubscriptionsResult, error)
 	CountSubscriptions(ctx context.Context) (int64, error)
+	GetSubscriberIDs(ctx context.Context) ([]int64, error)
 	DeleteSubscription(ctx context.Context, userID int64, sourceID uint) (int64, error)
 	CountSourceSubscriptions(ctx context.Context, sourceID uint) (int64, error)
 	UpdateSubscription(
@@ -82,6 +89,41 @@ type Content interface {
 	AddContent(ctx context.Context, content *model.Content) error
 	// DeleteSourceContents 删除订阅源的所有文章，返回被删除的文章数
 	DeleteSourceContents(ctx context.Context, sourceID uint) (int64, error)
+	// MoveSourceContents 将订阅源的所有文章移到另一个订阅源，hash id 不变，返回移动的文章数
+	MoveSourceContents(ctx context.Context, fromSourceID, toSourceID uint) (int64, error)
 	// HashIDExist hash id 对应的文章是否已存在
 	HashIDExist(ctx context.Context, hashID string) (bool, error)
+	// GetSourceContentsSince 获取订阅源在 since 之后入库的文章，按入库时间升序
+	GetSourceContentsSince(ctx context.Context, sourceID uint, since time.Time) ([]*model.Content, error)
+	// GetContentsByHashIDs 按 hash id 批量获取文章，不存在的 hash id 会被忽略
+	GetContentsByHashIDs(ctx context.Context, hashIDs []string) ([]*model.Content, error)
+	// GetSourceLatestContents 获取订阅源最新入库的 limit 篇文章，同一次入库的按发布时间降序
+	GetSourceLatestContents(ctx context.Context, sourceID uint, limit int) ([]*model.Content, error)
+	// StripContentsBefore 清空 before 之前入库文章的正文、摘要和附件，保留 hash id 用于去重，返回清理的文章数
+	StripContentsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// ContentSearch 文章全文搜索接口
+type ContentSearch interface {
+	Storage
+	// SearchContents 在 sourceIDs 订阅源的文章标题、正文和摘要中搜索包含所有关键词的文章，
+	// 按入库时间倒序返回 offset 开始的 limit 篇文章和匹配总数
+	SearchContents(ctx context.Context, sourceIDs []uint, query string, offset, limit int) ([]*model.Content, int64, error)
+}
+
+// Delivery 推送记录（outbox）存储接口
+type Delivery interface {
+	Storage
+	// AddDeliveries 批量添加推送记录，同一文章同一订阅者已存在的记录会被忽略
+	AddDeliveries(ctx context.Context, deliveries []*model.Delivery) error
+	// GetDueDeliveries 获取已到发送时间的待发送记录，按 ID 升序
+	GetDueDeliveries(ctx context.Context, now time.Time, limit int) ([]*model.Delivery, error)
+	// UpdateDelivery 保存推送记录
+	UpdateDelivery(ctx context.Context, delivery *model.Delivery) error
+	// RescheduleDigestDeliveries 将用户订阅源待发送的摘要记录改到 nextAttemptAt 发送
+	RescheduleDigestDeliveries(ctx context.Context, userID int64, sourceID uint, nextAttemptAt time.Time) error
+	// MoveSourceDeliveries 将订阅源的所有推送记录移到另一个订阅源
+	MoveSourceDeliveries(ctx context.Context, fromSourceID, toSourceID uint) error
+	// DeleteFinishedDeliveries 删除 before 之前已结束（已发送或放弃）的记录，返回被删除的记录数
+	DeleteFinishedDeliveries(ctx context.Context, before time.Time) (int64, error)
 }
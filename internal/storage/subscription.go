@@ -5,7 +5,9 @@ import (
 	"errors"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/zintus/flowerss-bot/internal/config"
 	"github.com/zintus/flowerss-bot/internal/log"
 	"github.com/zintus/flowerss-bot/internal/model"
 )
@@ -19,7 +21,20 @@ func NewSubscriptionStorageImpl(db *gorm.DB) *SubscriptionStorageImpl {
 }
 
 func (s *SubscriptionStorageImpl) Init(ctx context.Context) error {
-	return s.db.Migrator().AutoMigrate(&model.Subscribe{})
+	hasIntervalSet := s.db.Migrator().HasColumn(&model.Subscribe{}, "IntervalSet")
+	if err := s.db.Migrator().AutoMigrate(&model.Subscribe{}); err != nil {
+		return err
+	}
+	if hasIntervalSet {
+		return nil
+	}
+	// 旧版本新订阅都保存默认更新间隔，与默认值不同的间隔才视为订阅者设置的
+	// interval 是 MySQL 保留字，使用 clause 让 gorm 加上引号
+	column := clause.Column{Name: "interval"}
+	return s.db.WithContext(ctx).
+		Where(clause.Gt{Column: column, Value: 0}).
+		Where(clause.Neq{Column: column, Value: config.UpdateInterval}).
+		Update("interval_set", true).Error
 }
 
 func (s *SubscriptionStorageImpl) AddSubscription(ctx context.Context, subscription *model.Subscribe) error {
@@ -123,6 +138,16 @@ func (s *SubscriptionStorageImpl) getSubscriptionsOrderBy(opts *GetSubscriptions
 	return ""
 }
 
+// GetSubscriberIDs 有订阅的所有用户 ID，按 ID 排序
+func (s *SubscriptionStorageImpl) GetSubscriberIDs(ctx context.Context) ([]int64, error) {
+	var userIDs []int64
+	result := s.db.WithContext(ctx).Distinct("user_id").Order("user_id").Pluck("user_id", &userIDs)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	return userIDs, nil
+}
+
 func (s *SubscriptionStorageImpl) CountSubscriptions(ctx context.Context) (int64, error) {
 	var count int64
 	dbResult := s.db.WithContext(ctx).Count(&count)
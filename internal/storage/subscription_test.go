@@ -113,6 +113,10 @@ func TestSubscriptionStorageImpl(t *testing.T) {
 			assert.Nil(t, err)
 			assert.Equal(t, int64(4), got)
 
+			userIDs, err := s.GetSubscriberIDs(ctx)
+			assert.Nil(t, err)
+			assert.Equal(t, []int64{100, 101}, userIDs)
+
 			got, err = s.CountSourceSubscriptions(ctx, 2)
 			assert.Nil(t, err)
 			assert.Equal(t, int64(2), got)
@@ -53,3 +53,40 @@ func (s *UserStorageImpl) SetUserLanguage(ctx context.Context, userID int64, lan
 	}
 	return nil
 }
+
+// SetUserQuietHours 保存用户的时区和免打扰设置，用户不存在时创建
+func (s *UserStorageImpl) SetUserQuietHours(ctx context.Context, user *model.User) error {
+	result := s.db.WithContext(ctx).Where(&model.User{ID: user.ID}).Assign(
+		map[string]interface{}{
+			"timezone":    user.Timezone,
+			"quiet_start": user.QuietStart,
+			"quiet_end":   user.QuietEnd,
+			"quiet_mode":  user.QuietMode,
+		},
+	).FirstOrCreate(&model.User{})
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// SetUserDigest 保存用户所有订阅的摘要计划，用户不存在时创建
+func (s *UserStorageImpl) SetUserDigest(ctx context.Context, userID int64, digest string) error {
+	result := s.db.WithContext(ctx).Where(&model.User{ID: userID}).
+		Assign(map[string]interface{}{"digest": digest}).
+		FirstOrCreate(&model.User{})
+	if result.Error != nil {
+		return result.Error
+	}
+	return nil
+}
+
+// CountUsers 用户数量
+func (s *UserStorageImpl) CountUsers(ctx context.Context) (int64, error) {
+	var count int64
+	result := s.db.WithContext(ctx).Model(&model.User{}).Count(&count)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	return count, nil
+}
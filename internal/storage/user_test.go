@@ -44,6 +44,52 @@ func TestUserStorageImpl(t *testing.T) {
 			assert.Nil(t, err)
 			assert.NotNil(t, got)
 			assert.Equal(t, user.ID, got.ID)
+
+			count, err := s.CountUsers(ctx)
+			assert.Nil(t, err)
+			assert.GreaterOrEqual(t, count, int64(1))
+		},
+	)
+
+	t.Run(
+		"set user quiet hours", func(t *testing.T) {
+			quiet := &model.User{ID: user.ID, Timezone: "Europe/Berlin", QuietStart: "23:00", QuietEnd: "07:00", QuietMode: "hold"}
+			assert.Nil(t, s.SetUserQuietHours(ctx, quiet))
+			got, err := s.GetUser(ctx, user.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, "Europe/Berlin", got.Timezone)
+			assert.Equal(t, "23:00", got.QuietStart)
+			assert.Equal(t, "hold", got.QuietMode)
+			assert.Equal(t, "en", got.LanguageCode)
+
+			// 清除免打扰时间
+			quiet.QuietStart, quiet.QuietEnd, quiet.QuietMode = "", "", ""
+			assert.Nil(t, s.SetUserQuietHours(ctx, quiet))
+			got, err = s.GetUser(ctx, user.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, "", got.QuietStart)
+			assert.Equal(t, "Europe/Berlin", got.Timezone)
+
+			// 不存在的用户会被创建
+			assert.Nil(t, s.SetUserQuietHours(ctx, &model.User{ID: 456, Timezone: "Asia/Shanghai"}))
+			got, err = s.GetUser(ctx, 456)
+			assert.Nil(t, err)
+			assert.Equal(t, "Asia/Shanghai", got.Timezone)
+		},
+	)
+
+	t.Run(
+		"set user digest", func(t *testing.T) {
+			assert.Nil(t, s.SetUserDigest(ctx, user.ID, "daily 09:00"))
+			got, err := s.GetUser(ctx, user.ID)
+			assert.Nil(t, err)
+			assert.Equal(t, "daily 09:00", got.Digest)
+			assert.Equal(t, "Europe/Berlin", got.Timezone)
+
+			assert.Nil(t, s.SetUserDigest(ctx, user.ID, ""))
+			got, err = s.GetUser(ctx, user.ID)
+			assert.Nil(t, err)
+			assert.Empty(t, got.Digest)
 		},
 	)
 }
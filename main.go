@@ -1,23 +1,49 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/zintus/flowerss-bot/internal/bot"
+	"github.com/zintus/flowerss-bot/internal/config"
 	"github.com/zintus/flowerss-bot/internal/core"
 	"github.com/zintus/flowerss-bot/internal/i18n"
 	"github.com/zintus/flowerss-bot/internal/log"
 	"github.com/zintus/flowerss-bot/internal/scheduler"
+	"github.com/zintus/flowerss-bot/internal/server"
 )
 
+// shutdownTimeout 退出时等待进行中的抓取和推送完成的最长时间
+const shutdownTimeout = 30 * time.Second
+
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGQUIT, syscall.SIGTERM)
+	defer stop()
+
 	appCore := core.NewCoreFormConfig()
-	if err := appCore.Init(); err != nil {
+	if err := appCore.Init(ctx); err != nil {
 		log.Fatal(err)
 	}
+	if config.MergeDuplicateSources {
+		merged, err := appCore.MergeDuplicateSources(ctx)
+		if err != nil {
+			log.Fatalf("merge duplicate sources failed: %v", err)
+		}
+		log.Infof("merged %d duplicate sources", merged)
+		waitCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := appCore.Wait(waitCtx); err != nil {
+			log.Errorf("wait for hub requests failed, %v", err)
+		}
+		cancel()
+		if err := appCore.Close(); err != nil {
+			log.Errorf("close db failed, %v", err)
+		}
+		return
+	}
 
 	// --- debug info for translation loading ---
 	cwd, err := os.Getwd()
@@ -51,22 +77,66 @@ func main() {
 	}
 	log.Infof("Translations loaded.")
 
-	go handleSignal()
 	b := bot.NewBot(appCore)
 
 	task := scheduler.NewRssTask(appCore)
 	task.Register(b)
-	task.Start()
-	if err := b.Run(); err != nil {
+	task.Start(ctx)
+
+	var srv *server.Server
+	if config.MetricsListen != "" {
+		srv = server.NewServer(config.MetricsListen, appCore, task)
+		if err := srv.Start(); err != nil {
+			log.Fatalf("Failed to start metrics server: %v", err)
+		}
+	}
+
+	var hubSrv *server.Server
+	if config.WebSubCallbackURL != "" {
+		hubSrv = server.NewWebSubServer(
+			config.WebSubListen, appCore, task, int64(config.FetchMaxBodySize)<<20,
+		)
+		if err := hubSrv.Start(); err != nil {
+			log.Fatalf("Failed to start websub server: %v", err)
+		}
+	}
+
+	if err := b.Run(ctx); err != nil {
 		log.Fatalf("Failed to run bot: %v", err)
 	}
+	// 恢复默认的信号处理，等待期间再次收到信号时直接退出
+	stop()
+	shutdown(appCore, task, b, srv, hubSrv)
 }
 
-func handleSignal() {
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+// shutdown 等待调度器、hub 推送、outbox 和 hub 订阅请求完成进行中的工作，最多等待 shutdownTimeout，然后关闭数据库
+func shutdown(appCore *core.Core, task *scheduler.RssUpdateTask, b *bot.Bot, srv, hubSrv *server.Server) {
+	log.Infof("shutting down, waiting up to %s for in-flight work", shutdownTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
 
-	<-c
-
-	os.Exit(0)
+	if hubSrv != nil {
+		// 先停止接收推送，进行中的推送写入 outbox 后再等待 outbox
+		if err := hubSrv.Shutdown(ctx); err != nil {
+			log.Errorf("shutdown websub server failed, %v", err)
+		}
+	}
+	if err := task.Wait(ctx); err != nil {
+		log.Errorf("wait for rss update task failed, %v", err)
+	}
+	if err := b.Wait(ctx); err != nil {
+		log.Errorf("wait for outbox failed, %v", err)
+	}
+	if err := appCore.Wait(ctx); err != nil {
+		log.Errorf("wait for hub requests failed, %v", err)
+	}
+	if srv != nil {
+		if err := srv.Shutdown(ctx); err != nil {
+			log.Errorf("shutdown metrics server failed, %v", err)
+		}
+	}
+	if err := appCore.Close(); err != nil {
+		log.Errorf("close db failed, %v", err)
+	}
+	log.Info("shutdown complete")
 }
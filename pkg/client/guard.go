@@ -0,0 +1,194 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+)
+
+// ErrBlockedAddress the request target is a loopback, link-local, private or otherwise internal address
+var ErrBlockedAddress = errors.New("address is not allowed")
+
+// blockedNets special purpose ranges not covered by the net.IP helpers
+var blockedNets = mustParseCIDRs(
+	"0.0.0.0/8",      // "this" network
+	"100.64.0.0/10",  // carrier-grade NAT
+	"192.0.0.0/24",   // IETF protocol assignments
+	"198.18.0.0/15",  // benchmarking
+	"240.0.0.0/4",    // reserved, includes broadcast
+	"64:ff9b::/96",   // NAT64, may map to internal IPv4 addresses
+	"64:ff9b:1::/48", // local-use NAT64
+	"2002::/16",      // 6to4, may embed internal IPv4 addresses
+	"fec0::/10",      // deprecated site-local
+)
+
+// AddressGuard blocks requests to internal addresses, it checks the address of every connection
+// after DNS resolution, so redirects and DNS rebinding are covered as well
+type AddressGuard struct {
+	allowedHosts map[string]bool
+	allowedNets  []*net.IPNet
+}
+
+// NewAddressGuard creates a guard, allowlist entries are host names, IP addresses or CIDR ranges
+// that may be requested even if they are internal
+func NewAddressGuard(allowlist []string) (*AddressGuard, error) {
+	g := &AddressGuard{allowedHosts: make(map[string]bool)}
+	for _, entry := range allowlist {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "" {
+			continue
+		}
+		if strings.Contains(entry, "/") {
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid allowlist entry %q: %w", entry, err)
+			}
+			g.allowedNets = append(g.allowedNets, ipNet)
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			if ip4 := ip.To4(); ip4 != nil {
+				ip = ip4
+			}
+			g.allowedNets = append(g.allowedNets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+		g.allowedHosts[strings.TrimSuffix(entry, ".")] = true
+	}
+	return g, nil
+}
+
+// allowHost allows a host regardless of its address, used for the configured proxy
+func (g *AddressGuard) allowHost(host string) {
+	g.allowedHosts[strings.ToLower(host)] = true
+}
+
+func (g *AddressGuard) hostAllowed(host string) bool {
+	return g.allowedHosts[strings.TrimSuffix(strings.ToLower(host), ".")]
+}
+
+// ipAllowed reports whether a connection to ip may be made
+func (g *AddressGuard) ipAllowed(ip net.IP) bool {
+	for _, ipNet := range g.allowedNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return !IsInternalIP(ip)
+}
+
+// IsInternalIP reports whether ip is a loopback, link-local, private, multicast or otherwise non-public address
+func IsInternalIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsPrivate() || ip.IsUnspecified() {
+		return true
+	}
+	for _, ipNet := range blockedNets {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkHost rejects request URLs whose host is an internal IP literal or a localhost name, so the
+// error is returned before any connection is made, including when requests go through a proxy
+func (g *AddressGuard) checkHost(host string) error {
+	if g.hostAllowed(host) {
+		return nil
+	}
+	name := strings.TrimSuffix(strings.ToLower(host), ".")
+	if name == "localhost" || strings.HasSuffix(name, ".localhost") {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	if ip := net.ParseIP(strings.Trim(host, "[]")); ip != nil && !g.ipAllowed(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	return nil
+}
+
+// CheckURL rejects links that are not http(s) or whose host is or resolves to an internal address,
+// for links handed to another service that fetches them on our behalf
+func (g *AddressGuard) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrBlockedAddress, u.Scheme)
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("%w: missing host", ErrBlockedAddress)
+	}
+	if err := g.checkHost(host); err != nil || g.hostAllowed(host) || net.ParseIP(host) != nil {
+		return err
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !g.ipAllowed(addr.IP) {
+			return fmt.Errorf("%w: %s resolves to %s", ErrBlockedAddress, host, addr.IP)
+		}
+	}
+	return nil
+}
+
+// control checks the resolved address right before connecting
+func (g *AddressGuard) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !g.ipAllowed(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+	return nil
+}
+
+// dialContext dials with the address check, allowlisted host names are dialed without it
+func (g *AddressGuard) dialContext(dialer *net.Dialer) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	guarded := *dialer
+	guarded.Control = g.control
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if g.hostAllowed(host) {
+			return dialer.DialContext(ctx, network, addr)
+		}
+		return guarded.DialContext(ctx, network, addr)
+	}
+}
+
+// checkRedirect validates every redirect target before it is requested
+func (g *AddressGuard) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	return g.checkHost(req.URL.Hostname())
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsInternalIP(t *testing.T) {
+	tests := []struct {
+		ip       string
+		internal bool
+	}{
+		{"127.0.0.1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"100.64.0.1", true},
+		{"0.0.0.0", true},
+		{"224.0.0.1", true},
+		{"255.255.255.255", true},
+		{"::1", true},
+		{"fe80::1", true},
+		{"fd00::1", true},
+		{"::ffff:127.0.0.1", true},
+		{"64:ff9b::a00:1", true},
+		{"8.8.8.8", false},
+		{"1.1.1.1", false},
+		{"2606:4700:4700::1111", false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.internal, IsInternalIP(net.ParseIP(tt.ip)), tt.ip)
+	}
+}
+
+func TestNewAddressGuard(t *testing.T) {
+	_, err := NewAddressGuard([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+
+	g, err := NewAddressGuard([]string{" 192.168.1.0/24 ", "10.0.0.5", "Feeds.Lan.", ""})
+	assert.Nil(t, err)
+	assert.Nil(t, g.checkHost("192.168.1.20"))
+	assert.Nil(t, g.checkHost("10.0.0.5"))
+	assert.Nil(t, g.checkHost("feeds.lan"))
+	assert.ErrorIs(t, g.checkHost("10.0.0.6"), ErrBlockedAddress)
+	assert.ErrorIs(t, g.checkHost("localhost"), ErrBlockedAddress)
+	assert.ErrorIs(t, g.checkHost("a.localhost"), ErrBlockedAddress)
+	assert.ErrorIs(t, g.checkHost("[::1]"), ErrBlockedAddress)
+	assert.Nil(t, g.checkHost("example.com"))
+
+	assert.Nil(t, g.control("tcp4", "192.168.1.20:80", nil))
+	assert.ErrorIs(t, g.control("tcp4", "127.0.0.1:80", nil), ErrBlockedAddress)
+	assert.ErrorIs(t, g.control("tcp6", "[fe80::1]:80", nil), ErrBlockedAddress)
+	assert.Nil(t, g.control("tcp4", "8.8.8.8:443", nil))
+}
+
+func TestAddressGuard_CheckURL(t *testing.T) {
+	ctx := context.Background()
+	g, _ := NewAddressGuard([]string{"feeds.lan", "10.0.0.5"})
+	assert.Nil(t, g.CheckURL(ctx, "https://8.8.8.8/feed"))
+	assert.Nil(t, g.CheckURL(ctx, "http://feeds.lan/feed"))
+	assert.Nil(t, g.CheckURL(ctx, "http://10.0.0.5/feed"))
+	assert.ErrorIs(t, g.CheckURL(ctx, "http://10.0.0.6/feed"), ErrBlockedAddress)
+	assert.ErrorIs(t, g.CheckURL(ctx, "http://localhost/feed"), ErrBlockedAddress)
+	assert.ErrorIs(t, g.CheckURL(ctx, "gopher://8.8.8.8/"), ErrBlockedAddress)
+	assert.ErrorIs(t, g.CheckURL(ctx, "/relative"), ErrBlockedAddress)
+}
+
+func TestHttpClient_AddressGuard(t *testing.T) {
+	ts := httptest.NewServer(
+		http.HandlerFunc(
+			func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/redirect" {
+					_, port, _ := net.SplitHostPort(r.Host)
+					http.Redirect(w, r, "http://127.0.0.1:"+port+"/", http.StatusFound)
+					return
+				}
+				_, _ = w.Write([]byte("ok"))
+			},
+		),
+	)
+	defer ts.Close()
+	tsURL, _ := url.Parse(ts.URL)
+	ctx := context.Background()
+
+	t.Run(
+		"block internal address", func(t *testing.T) {
+			g, _ := NewAddressGuard(nil)
+			c := NewHttpClient(WithAddressGuard(g))
+			_, err := c.GetWithContext(ctx, ts.URL)
+			assert.ErrorIs(t, err, ErrBlockedAddress)
+			_, err = c.GetWithContext(ctx, "http://localhost:"+tsURL.Port())
+			assert.ErrorIs(t, err, ErrBlockedAddress)
+		},
+	)
+
+	t.Run(
+		"allowlisted address", func(t *testing.T) {
+			g, _ := NewAddressGuard([]string{"127.0.0.0/8"})
+			c := NewHttpClient(WithAddressGuard(g))
+			resp, err := c.GetWithContext(ctx, ts.URL)
+			assert.Nil(t, err)
+			_ = resp.Body.Close()
+		},
+	)
+
+	t.Run(
+		"block redirect to internal address", func(t *testing.T) {
+			g, _ := NewAddressGuard([]string{"localhost"})
+			c := NewHttpClient(WithAddressGuard(g))
+			resp, err := c.GetWithContext(ctx, "http://localhost:"+tsURL.Port()+"/")
+			assert.Nil(t, err)
+			_ = resp.Body.Close()
+
+			_, err = c.GetWithContext(ctx, "http://localhost:"+tsURL.Port()+"/redirect")
+			assert.ErrorIs(t, err, ErrBlockedAddress)
+		},
+	)
+}
@@ -2,8 +2,10 @@ package client
 
 import (
 	"context"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
@@ -11,6 +13,12 @@ type HttpClientOptions struct {
 	UserAgent string
 	Timeout   time.Duration
 	ProxyURL  string
+	Header    http.Header
+	// BasicAuthUser and BasicAuthPassword are sent as basic auth when either is set
+	BasicAuthUser     string
+	BasicAuthPassword string
+	// AddressGuard blocks requests to internal addresses when set
+	AddressGuard *AddressGuard
 }
 
 func NewHttpClientOptions() *HttpClientOptions {
@@ -37,9 +45,35 @@ func WithProxyURL(url string) HttpClientOption {
 	}
 }
 
+// WithHeader adds a request header, only used by per-request options
+func WithHeader(key, value string) HttpClientOption {
+	return func(opts *HttpClientOptions) {
+		if opts.Header == nil {
+			opts.Header = http.Header{}
+		}
+		opts.Header.Add(key, value)
+	}
+}
+
+// WithBasicAuth sets basic auth credentials, only used by per-request options
+func WithBasicAuth(user, password string) HttpClientOption {
+	return func(opts *HttpClientOptions) {
+		opts.BasicAuthUser = user
+		opts.BasicAuthPassword = password
+	}
+}
+
+// WithAddressGuard blocks requests to internal addresses, including redirects
+func WithAddressGuard(guard *AddressGuard) HttpClientOption {
+	return func(opts *HttpClientOptions) {
+		opts.AddressGuard = guard
+	}
+}
+
 type HttpClient struct {
 	client    *http.Client
 	userAgent string
+	guard     *AddressGuard
 }
 
 func NewHttpClient(opts ...HttpClientOption) *HttpClient {
@@ -49,8 +83,9 @@ func NewHttpClient(opts ...HttpClientOption) *HttpClient {
 	}
 
 	transport := &http.Transport{}
+	var proxyURL *url.URL
 	if o.ProxyURL != "" {
-		proxyURL, _ := url.Parse(o.ProxyURL)
+		proxyURL, _ = url.Parse(o.ProxyURL)
 		transport.Proxy = http.ProxyURL(proxyURL)
 	}
 
@@ -59,21 +94,52 @@ func NewHttpClient(opts ...HttpClientOption) *HttpClient {
 		Transport: transport,
 	}
 
+	if o.AddressGuard != nil {
+		// 代理由运维配置，连接代理不做检查，目标地址只能检查 URL 中的 IP
+		if proxyURL != nil {
+			o.AddressGuard.allowHost(proxyURL.Hostname())
+		}
+		transport.DialContext = o.AddressGuard.dialContext(&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second})
+		client.CheckRedirect = o.AddressGuard.checkRedirect
+	}
+
 	return &HttpClient{
 		client:    client,
 		userAgent: o.UserAgent,
+		guard:     o.AddressGuard,
 	}
 }
 
 func (c *HttpClient) GetWithContext(ctx context.Context, url string, opts ...HttpClientOption) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req, opts...)
+}
+
+// PostFormWithContext sends data as an application/x-www-form-urlencoded POST request
+func (c *HttpClient) PostFormWithContext(
+	ctx context.Context, url string, data url.Values, opts ...HttpClientOption,
+) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.do(req, opts...)
+}
+
+func (c *HttpClient) do(req *http.Request, opts ...HttpClientOption) (*http.Response, error) {
 	o := NewHttpClientOptions()
 	for _, opt := range opts {
 		opt(o)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
+	if c.guard != nil {
+		if err := c.guard.checkHost(req.URL.Hostname()); err != nil {
+			return nil, err
+		}
 	}
 
 	if c.userAgent != "" {
@@ -82,6 +148,14 @@ func (c *HttpClient) GetWithContext(ctx context.Context, url string, opts ...Htt
 	if o.UserAgent != "" {
 		req.Header.Set("User-Agent", o.UserAgent)
 	}
+	for key, values := range o.Header {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+	if o.BasicAuthUser != "" || o.BasicAuthPassword != "" {
+		req.SetBasicAuth(o.BasicAuthUser, o.BasicAuthPassword)
+	}
 	return c.client.Do(req)
 }
 
@@ -89,6 +163,11 @@ func (c *HttpClient) Get(url string, opts ...HttpClientOption) (*http.Response,
 	return c.GetWithContext(context.Background(), url, opts...)
 }
 
+// AddressGuard returns the guard set with WithAddressGuard, nil when requests are not guarded
+func (c *HttpClient) AddressGuard() *AddressGuard {
+	return c.guard
+}
+
 // Client method returns the current `http.Client` used by HttpClient.
 func (c *HttpClient) Client() *http.Client {
 	return c.client
@@ -25,6 +25,8 @@ func createTestServer(t *testing.T) *httptest.Server {
 				for i := range r.Header["User-Agent"] {
 					_, _ = w.Write([]byte(r.Header["User-Agent"][i]))
 				}
+			case "/header":
+				_, _ = w.Write([]byte(r.Header.Get("X-Test")))
 			case "/timeout":
 				time.Sleep(time.Second)
 			}
@@ -73,6 +75,18 @@ func TestHttpClient_Get(t *testing.T) {
 		assert.Equal(t, userAgent, string(body))
 	})
 
+	t.Run("custom get header", func(t *testing.T) {
+		client := NewHttpClient()
+		url := fmt.Sprintf("%s/header", ts.URL)
+		resp, err := client.Get(url, WithHeader("X-Test", "value"))
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+		body, err := io.ReadAll(resp.Body)
+		assert.Nil(t, err)
+		assert.Equal(t, "value", string(body))
+	})
+
 	t.Run("timeout", func(t *testing.T) {
 		client := NewHttpClient(WithTimeout(time.Millisecond))
 		url := fmt.Sprintf("%s/timeout", ts.URL)